@@ -0,0 +1,101 @@
+package codec
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/pagespb"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+type JSONEncoder struct{}
+
+func (JSONEncoder) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+func (JSONEncoder) Encode(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+type XMLEncoder struct{}
+
+func (XMLEncoder) MediaTypes() []string {
+	return []string{"application/xml", "text/xml"}
+}
+
+func (XMLEncoder) Encode(value interface{}) ([]byte, error) {
+	marshal, err := xml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), marshal...), nil
+}
+
+// MessagePackEncoder writes structs as maps keyed by field names, same as JSONEncoder.
+type MessagePackEncoder struct{}
+
+func (MessagePackEncoder) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+func (MessagePackEncoder) Encode(value interface{}) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+// ProtobufEncoder writes pages, seos and products as messages defined in src/pagespb/page.proto.
+type ProtobufEncoder struct{}
+
+func (ProtobufEncoder) MediaTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf"}
+}
+
+func (ProtobufEncoder) Encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case *model.Page:
+		return proto.Marshal(pagespb.FromPage(v))
+	case *model.SparsePage:
+		return proto.Marshal(pagespb.FromSparsePage(v))
+	case *model.SEO:
+		return proto.Marshal(pagespb.FromSEO(v))
+	case *model.Product:
+		return proto.Marshal(pagespb.FromProduct(v))
+	default:
+		return nil, fmt.Errorf("%w: %T can not be written as protobuf", ErrUnsupportedValue, value)
+	}
+}
+
+// NDJSONEncoder writes lists of products as newline delimited JSON, one product per line,
+// so they can be read by the client before the whole list is received.
+type NDJSONEncoder struct{}
+
+func (NDJSONEncoder) MediaTypes() []string {
+	return []string{"application/x-ndjson"}
+}
+
+func (NDJSONEncoder) Encode(value interface{}) ([]byte, error) {
+	products, ok := value.([]model.Product)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T can not be written as ndjson", ErrUnsupportedValue, value)
+	}
+	var encoded []byte
+	for i := range products {
+		line, err := EncodeNDJSONLine(&products[i])
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, line...)
+	}
+	return encoded, nil
+}
+
+// EncodeNDJSONLine encodes single value of the stream, terminated by a newline.
+func EncodeNDJSONLine(value interface{}) ([]byte, error) {
+	marshal, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return append(marshal, '\n'), nil
+}
@@ -0,0 +1,134 @@
+package codec
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/pagespb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+var samplePage = &model.Page{
+	SEO: model.SEO{PageId: 1, Title: "title", Description: "description", Robots: "robots"},
+	Products: []model.Product{
+		{Id: 1, PageId: 1, Name: "name1", Description: "description1", Price: 2.5},
+		{Id: 2, PageId: 1, Name: "name2", Description: "description2", Price: 19.99},
+	},
+}
+
+func TestEncoders_shouldEncodePageReadableBack(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoder Encoder
+		decode  func(data []byte, page *model.Page) error
+	}{
+		{
+			name:    "json",
+			encoder: JSONEncoder{},
+			decode:  func(data []byte, page *model.Page) error { return json.Unmarshal(data, page) },
+		},
+		{
+			name:    "xml",
+			encoder: XMLEncoder{},
+			decode:  func(data []byte, page *model.Page) error { return xml.Unmarshal(data, page) },
+		},
+		{
+			name:    "msgpack",
+			encoder: MessagePackEncoder{},
+			decode:  func(data []byte, page *model.Page) error { return msgpack.Unmarshal(data, page) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.encoder.Encode(samplePage)
+			require.NoError(t, err)
+
+			decoded := &model.Page{}
+			require.NoError(t, tt.decode(encoded, decoded))
+			assert.Equal(t, samplePage, decoded)
+		})
+	}
+}
+
+func TestXMLEncoder_shouldWrapProducts(t *testing.T) {
+	encoded, err := XMLEncoder{}.Encode(samplePage)
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(encoded), xml.Header+"<Page><SEO><PageId>1</PageId>"))
+	assert.Contains(t, string(encoded), "<Products><Product><Id>1</Id>")
+}
+
+func TestProtobufEncoder_shouldEncodePage(t *testing.T) {
+	encoded, err := ProtobufEncoder{}.Encode(samplePage)
+	require.NoError(t, err)
+
+	decoded := &pagespb.Page{}
+	require.NoError(t, proto.Unmarshal(encoded, decoded))
+	assert.Equal(t, int64(1), decoded.GetSeo().GetPageId())
+	assert.Equal(t, "title", decoded.GetSeo().GetTitle())
+	require.Len(t, decoded.GetProducts(), 2)
+	assert.Equal(t, "name2", decoded.GetProducts()[1].GetName())
+	assert.Equal(t, 19.99, decoded.GetProducts()[1].GetPrice())
+}
+
+func TestProtobufEncoder_shouldReturnErr_whenValueNotSupported(t *testing.T) {
+	encoded, err := ProtobufEncoder{}.Encode(map[string]int{})
+
+	assert.Nil(t, encoded)
+	assert.ErrorIs(t, err, ErrUnsupportedValue)
+}
+
+func TestEncoders_shouldOmitFieldsNotSelected(t *testing.T) {
+	sparsePage := samplePage.Sparse(&model.PageFields{SEO: []string{"Title"}, Products: []string{"Name"}})
+	tests := []struct {
+		name     string
+		encoder  Encoder
+		expected string
+	}{
+		{
+			name:     "json",
+			encoder:  JSONEncoder{},
+			expected: `{"SEO":{"Title":"title"},"Products":[{"Name":"name1"},{"Name":"name2"}]}`,
+		},
+		{
+			name:     "xml",
+			encoder:  XMLEncoder{},
+			expected: xml.Header + "<Page><SEO><Title>title</Title></SEO><Products><Product><Name>name1</Name></Product><Product><Name>name2</Name></Product></Products></Page>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.encoder.Encode(sparsePage)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(encoded))
+		})
+	}
+}
+
+func TestProtobufEncoder_shouldEncodeSparsePage(t *testing.T) {
+	encoded, err := ProtobufEncoder{}.Encode(samplePage.Sparse(&model.PageFields{Products: []string{"Price"}}))
+	require.NoError(t, err)
+
+	decoded := &pagespb.Page{}
+	require.NoError(t, proto.Unmarshal(encoded, decoded))
+	assert.Nil(t, decoded.GetSeo())
+	require.Len(t, decoded.GetProducts(), 2)
+	assert.Equal(t, "", decoded.GetProducts()[1].GetName())
+	assert.Equal(t, 19.99, decoded.GetProducts()[1].GetPrice())
+}
+
+func TestNDJSONEncoder_shouldWriteProductPerLine(t *testing.T) {
+	encoded, err := NDJSONEncoder{}.Encode(samplePage.Products)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"Id":1,"PageId":1,"Name":"name1","Description":"description1","Price":2.5}`+"\n"+
+		`{"Id":2,"PageId":1,"Name":"name2","Description":"description2","Price":19.99}`+"\n", string(encoded))
+	_, err = NDJSONEncoder{}.Encode(samplePage)
+	assert.ErrorIs(t, err, ErrUnsupportedValue)
+}
@@ -0,0 +1,109 @@
+package codec
+
+import (
+	"errors"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedValue is returned by encoders, which can not represent the value.
+var ErrUnsupportedValue = errors.New("unsupported value")
+
+// Encoder writes values in one representation.
+type Encoder interface {
+	// MediaTypes of the representation, the first one is preferred.
+	MediaTypes() []string
+	Encode(value interface{}) ([]byte, error)
+}
+
+// Negotiator selects encoder for Accept header of the request.
+type Negotiator struct {
+	encoders []Encoder
+}
+
+// NewNegotiator returns negotiator preferring encoders in given order, the first one is used
+// when the request has no Accept header.
+func NewNegotiator(encoders ...Encoder) *Negotiator {
+	return &Negotiator{encoders: encoders}
+}
+
+// DefaultNegotiator supports JSON, XML, MessagePack and Protobuf, preferring JSON.
+func DefaultNegotiator() *Negotiator {
+	return NewNegotiator(JSONEncoder{}, XMLEncoder{}, MessagePackEncoder{}, ProtobufEncoder{})
+}
+
+// Negotiate returns encoder and media type with the highest quality in accept, ok is false when no encoder
+// is acceptable. When qualities are equal, order of encoders decides.
+func (n *Negotiator) Negotiate(accept string) (encoder Encoder, mediaType string, ok bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return n.encoders[0], n.encoders[0].MediaTypes()[0], true
+	}
+	bestQuality := 0.0
+	for _, candidate := range n.encoders {
+		for _, candidateType := range candidate.MediaTypes() {
+			if quality := qualityOf(candidateType, ranges); quality > bestQuality {
+				encoder, mediaType, bestQuality = candidate, candidateType, quality
+			}
+		}
+	}
+	return encoder, mediaType, encoder != nil
+}
+
+// MediaTypes returns preferred media types of all encoders.
+func (n *Negotiator) MediaTypes() []string {
+	mediaTypes := make([]string, 0, len(n.encoders))
+	for _, encoder := range n.encoders {
+		mediaTypes = append(mediaTypes, encoder.MediaTypes()[0])
+	}
+	return mediaTypes
+}
+
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept parses media ranges of Accept header, invalid ranges are skipped.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+	return ranges
+}
+
+// qualityOf returns quality of the most specific range matching mediaType, as described in RFC 9110.
+func qualityOf(mediaType string, ranges []mediaRange) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, 0
+	for _, r := range ranges {
+		rangeSpecificity := 0
+		switch r.mediaType {
+		case mediaType:
+			rangeSpecificity = 3
+		case mainType + "/*":
+			rangeSpecificity = 2
+		case "*/*":
+			rangeSpecificity = 1
+		}
+		if rangeSpecificity > specificity {
+			quality, specificity = r.quality, rangeSpecificity
+		}
+	}
+	return quality
+}
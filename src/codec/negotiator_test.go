@@ -0,0 +1,80 @@
+package codec
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNegotiator_Negotiate(t *testing.T) {
+	tests := []struct {
+		name              string
+		accept            string
+		expectedMediaType string
+		expectedOk        bool
+	}{
+		{
+			name:              "should return first encoder, when accept is empty",
+			accept:            "",
+			expectedMediaType: "application/json",
+			expectedOk:        true,
+		},
+		{
+			name:              "should return first encoder, when any type accepted",
+			accept:            "*/*",
+			expectedMediaType: "application/json",
+			expectedOk:        true,
+		},
+		{
+			name:              "should return requested alias of media type",
+			accept:            "text/xml",
+			expectedMediaType: "text/xml",
+			expectedOk:        true,
+		},
+		{
+			name:              "should return media type with highest quality",
+			accept:            "application/json;q=0.5, application/msgpack",
+			expectedMediaType: "application/msgpack",
+			expectedOk:        true,
+		},
+		{
+			name:              "should prefer specific range over wildcard",
+			accept:            "application/*;q=0.5, application/x-protobuf",
+			expectedMediaType: "application/x-protobuf",
+			expectedOk:        true,
+		},
+		{
+			name:              "should skip media type with zero quality",
+			accept:            "application/*, application/json;q=0",
+			expectedMediaType: "application/xml",
+			expectedOk:        true,
+		},
+		{
+			name:              "should skip invalid ranges",
+			accept:            "application/xml;q=x, ;;, application/msgpack",
+			expectedMediaType: "application/msgpack",
+			expectedOk:        true,
+		},
+		{
+			name:       "should return not ok, when no media type is acceptable",
+			accept:     "text/html, application/json;q=0",
+			expectedOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, mediaType, ok := DefaultNegotiator().Negotiate(tt.accept)
+
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedMediaType, mediaType)
+			if ok {
+				assert.Contains(t, encoder.MediaTypes(), mediaType)
+			}
+		})
+	}
+}
+
+func TestNegotiator_MediaTypes(t *testing.T) {
+	assert.Equal(t,
+		[]string{"application/json", "application/xml", "application/msgpack", "application/x-protobuf"},
+		DefaultNegotiator().MediaTypes())
+}
@@ -0,0 +1,64 @@
+package contoller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// etagOf returns strong entity tag of encoded representation. Each representation of the page has its own tag,
+// as required for strong validators, so responses vary on Accept header.
+func etagOf(encoded []byte) string {
+	sum := sha256.Sum256(encoded)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// setValidators sets ETag and Last-Modified headers. Last-Modified is omitted when modification time is not known,
+// e.g. for pages stored before it was recorded.
+func setValidators(header http.Header, etag string, modifiedAt time.Time) {
+	header.Set("ETag", etag)
+	if !modifiedAt.IsZero() {
+		header.Set("Last-Modified", modifiedAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since of GET request, as described by RFC 9110 section 13.2.2.
+// If-Modified-Since is ignored when If-None-Match is present, as entity tags are more accurate.
+func notModified(request *http.Request, etag string, modifiedAt time.Time) bool {
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return matchesEtag(ifNoneMatch, etag, true)
+	}
+	ifModifiedSince := request.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || modifiedAt.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	// Last-Modified has one second precision, so the time is truncated before comparison.
+	return !modifiedAt.Truncate(time.Second).After(since)
+}
+
+// matchesEtag reports whether list of entity tags from If-Match or If-None-Match header contains etag.
+// If-None-Match uses weak comparison, which ignores W/ prefix, If-Match uses strong comparison.
+func matchesEtag(header string, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
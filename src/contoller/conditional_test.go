@@ -0,0 +1,33 @@
+package contoller
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatchesEtag(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		weak     bool
+		expected bool
+	}{
+		{name: "should match any, when header is asterisk", header: " * ", expected: true},
+		{name: "should match, when list contains etag", header: `"a", "b"`, expected: true},
+		{name: "should not match, when list does not contain etag", header: `"a", "c"`},
+		{name: "should match weak etag, when comparison is weak", header: `W/"b"`, weak: true, expected: true},
+		{name: "should not match weak etag, when comparison is strong", header: `W/"b"`},
+		{name: "should not match unquoted etag", header: `b`, weak: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, matchesEtag(tt.header, `"b"`, tt.weak))
+		})
+	}
+}
+
+func TestEtagOf_shouldDependOnRepresentation(t *testing.T) {
+	assert.Equal(t, etagOf([]byte("{}")), etagOf([]byte("{}")))
+	assert.NotEqual(t, etagOf([]byte("{}")), etagOf([]byte("<SEO></SEO>")))
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etagOf([]byte("{}")))
+}
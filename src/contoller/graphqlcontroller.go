@@ -0,0 +1,70 @@
+package contoller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/remikj/pages-ms/src/graphqlapi"
+	"github.com/remikj/pages-ms/src/logging"
+	"log/slog"
+	"net/http"
+)
+
+type GraphQLController interface {
+	HandleGraphQL(writer http.ResponseWriter, request *http.Request)
+}
+
+type GraphQLControllerImpl struct {
+	Executor *graphqlapi.Executor
+	Logger   *slog.Logger
+}
+
+func NewGraphQLController(executor *graphqlapi.Executor, logger *slog.Logger) *GraphQLControllerImpl {
+	return &GraphQLControllerImpl{
+		Executor: executor,
+		Logger:   logger,
+	}
+}
+
+// HandleGraphQL executes GraphQL request sent as JSON body of POST, or as query, operationName and variables
+// query parameters of GET. Requests rejected before execution are answered with 400. Executed requests
+// are answered with 200 even when some fields failed, as the errors are listed in the response.
+func (gc *GraphQLControllerImpl) HandleGraphQL(writer http.ResponseWriter, request *http.Request) {
+	logger := logging.FromContext(request.Context(), gc.Logger)
+	graphQLRequest, err := decodeGraphQLRequest(request)
+	if err != nil {
+		logger.Info("invalid graphql request", "error", err)
+		handleInvalidBody(writer, request, logger, err)
+		return
+	}
+	logger = logger.With("operation_name", graphQLRequest.OperationName)
+
+	response, executed := gc.Executor.Execute(request.Context(), graphQLRequest)
+	if !executed {
+		logger.Info("graphql request rejected", "error", response.Errors[0].Message)
+		writeJson(writer, request, logger, http.StatusBadRequest, response)
+		return
+	}
+	writeJson(writer, request, logger, http.StatusOK, response)
+}
+
+func decodeGraphQLRequest(request *http.Request) (graphqlapi.Request, error) {
+	graphQLRequest := graphqlapi.Request{}
+	if request.Method == http.MethodGet {
+		query := request.URL.Query()
+		graphQLRequest.Query = query.Get("query")
+		graphQLRequest.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &graphQLRequest.Variables); err != nil {
+				return graphQLRequest, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+	} else if err := json.NewDecoder(request.Body).Decode(&graphQLRequest); err != nil {
+		// unknown fields are allowed, as clients may send extensions of the request
+		return graphQLRequest, err
+	}
+	if graphQLRequest.Query == "" {
+		return graphQLRequest, errors.New("query must not be empty")
+	}
+	return graphQLRequest, nil
+}
@@ -0,0 +1,69 @@
+package contoller
+
+import (
+	"github.com/remikj/pages-ms/src/graphqlapi"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLControllerImpl_HandleGraphQL(t *testing.T) {
+	tests := []struct {
+		name         string
+		request      *http.Request
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "should execute query from body of POST",
+			request:      httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query($id: Int!) { page(id: $id) { seo { title } } }", "variables": {"id": 0}}`)),
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":{"page":{"seo":{"title":"Sample page title"}}}}`,
+		},
+		{
+			name: "should execute query from parameters of GET",
+			request: httptest.NewRequest("GET", "/graphql?"+url.Values{
+				"query":     {"query($id: Int!) { page(id: $id) { products(first: 1) { name } } }"},
+				"variables": {`{"id": 0}`},
+			}.Encode(), nil),
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":{"page":{"products":[{"name":"Sample product 0 name"}]}}}`,
+		},
+		{
+			name:         "should return bad request, when query is invalid",
+			request:      httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ page { seo { title } } }"}`)),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"errors":[{"message":"Field \"page\" argument \"id\" of type \"Int!\" is required but not provided.","locations":[{"line":1,"column":3}]}]}`,
+		},
+		{
+			name:         "should return problem, when query is missing",
+			request:      httptest.NewRequest("GET", "/graphql", nil),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidBody, "Invalid request body: query must not be empty"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, err := graphqlapi.NewExecutor(
+				&graphqlapi.Configuration{MaxDepth: 5, MaxComplexity: 100, MaxBatchSize: 10},
+				&pageServiceMock{getPageFn: func(pageId int) (*model.Page, error) { return &sampleModelPage, nil }},
+				logging.Discard(),
+			)
+			require.NoError(t, err)
+			gc := NewGraphQLController(executor, logging.Discard())
+			responseRecorder := httptest.NewRecorder()
+
+			gc.HandleGraphQL(responseRecorder, tt.request)
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package contoller
+
+import (
+	"context"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/logging"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	statusUp           = "up"
+	statusDown         = "down"
+	statusShuttingDown = "shutting_down"
+)
+
+type HealthController interface {
+	HandleLiveness(writer http.ResponseWriter, request *http.Request)
+	HandleReadiness(writer http.ResponseWriter, request *http.Request)
+	SetShuttingDown()
+}
+
+// HealthCheck checks availability of a dependency, e.g. pings database.
+type HealthCheck func(ctx context.Context) error
+
+type HealthConfiguration struct {
+	CheckTimeout time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"1s"`
+}
+
+type HealthControllerImpl struct {
+	Config       *HealthConfiguration
+	Checks       map[string]HealthCheck
+	Logger       *slog.Logger
+	shuttingDown atomic.Bool
+}
+
+type HealthResponse struct {
+	Status string                     `json:"status"`
+	Checks map[string]DependencyState `json:"checks,omitempty"`
+}
+
+type DependencyState struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func NewHealthControllerFromEnv(checks map[string]HealthCheck, logger *slog.Logger) (*HealthControllerImpl, error) {
+	config := &HealthConfiguration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	return NewHealthController(config, checks, logger), nil
+}
+
+func NewHealthController(config *HealthConfiguration, checks map[string]HealthCheck, logger *slog.Logger) *HealthControllerImpl {
+	return &HealthControllerImpl{
+		Config: config,
+		Checks: checks,
+		Logger: logger,
+	}
+}
+
+// HandleLiveness reports that the process is up, it does not check dependencies.
+func (hc *HealthControllerImpl) HandleLiveness(writer http.ResponseWriter, request *http.Request) {
+	writeJson(writer, request, hc.requestLogger(request), http.StatusOK, HealthResponse{Status: statusUp})
+}
+
+// HandleReadiness runs all checks concurrently, each limited by CheckTimeout,
+// and reports service unavailable when any of them fails or when the service is shutting down.
+func (hc *HealthControllerImpl) HandleReadiness(writer http.ResponseWriter, request *http.Request) {
+	logger := hc.requestLogger(request)
+	if hc.shuttingDown.Load() {
+		writeJson(writer, request, logger, http.StatusServiceUnavailable, HealthResponse{Status: statusShuttingDown})
+		return
+	}
+
+	response := HealthResponse{Status: statusUp, Checks: hc.runChecks(request.Context())}
+	status := http.StatusOK
+	for name, state := range response.Checks {
+		if state.Status != statusUp {
+			logger.Warn("readiness check failed", "dependency", name, "error", state.Error)
+			response.Status = statusDown
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeJson(writer, request, logger, status, response)
+}
+
+// SetShuttingDown makes readiness fail, so load balancers stop sending new requests.
+func (hc *HealthControllerImpl) SetShuttingDown() {
+	hc.shuttingDown.Store(true)
+}
+
+func (hc *HealthControllerImpl) runChecks(ctx context.Context) map[string]DependencyState {
+	states := make(map[string]DependencyState, len(hc.Checks))
+	mutex := sync.Mutex{}
+	waitGroup := sync.WaitGroup{}
+	for name, check := range hc.Checks {
+		waitGroup.Add(1)
+		go func(name string, check HealthCheck) {
+			defer waitGroup.Done()
+			state := hc.runCheck(ctx, check)
+			mutex.Lock()
+			defer mutex.Unlock()
+			states[name] = state
+		}(name, check)
+	}
+	waitGroup.Wait()
+	return states
+}
+
+func (hc *HealthControllerImpl) runCheck(ctx context.Context, check HealthCheck) DependencyState {
+	ctx, cancelFunc := context.WithTimeout(ctx, hc.Config.CheckTimeout)
+	defer cancelFunc()
+	start := time.Now()
+	err := check(ctx)
+	state := DependencyState{
+		Status:    statusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		state.Status = statusDown
+		state.Error = err.Error()
+	}
+	return state
+}
+
+func (hc *HealthControllerImpl) requestLogger(request *http.Request) *slog.Logger {
+	return logging.FromContext(request.Context(), hc.Logger)
+}
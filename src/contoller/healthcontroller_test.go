@@ -0,0 +1,85 @@
+package contoller
+
+import (
+	"context"
+	"errors"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestHealthControllerImpl_HandleLiveness(t *testing.T) {
+	hc := NewHealthController(&HealthConfiguration{CheckTimeout: time.Second}, nil, logging.Discard())
+	responseRecorder := httptest.NewRecorder()
+
+	hc.HandleLiveness(responseRecorder, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, `{"status":"up"}`, responseRecorder.Body.String())
+}
+
+func TestHealthControllerImpl_HandleReadiness(t *testing.T) {
+	tests := []struct {
+		name         string
+		checks       map[string]HealthCheck
+		shuttingDown bool
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "should return ok, when all checks pass",
+			checks: map[string]HealthCheck{
+				"mongo": func(ctx context.Context) error { return nil },
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"up","checks":{"mongo":{"status":"up","latency_ms":0}}}`,
+		},
+		{
+			name: "should return service unavailable, when check fails",
+			checks: map[string]HealthCheck{
+				"mongo": func(ctx context.Context) error { return errors.New("connection refused") },
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"status":"down","checks":{"mongo":{"status":"down","latency_ms":0,"error":"connection refused"}}}`,
+		},
+		{
+			name: "should return service unavailable, when check exceeds timeout",
+			checks: map[string]HealthCheck{
+				"mongo": func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"status":"down","checks":{"mongo":{"status":"down","latency_ms":0,"error":"context deadline exceeded"}}}`,
+		},
+		{
+			name: "should return service unavailable without running checks, when shutting down",
+			checks: map[string]HealthCheck{
+				"mongo": func(ctx context.Context) error { panic("check should not run") },
+			},
+			shuttingDown: true,
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"status":"shutting_down"}`,
+		},
+	}
+	latency := regexp.MustCompile(`"latency_ms":[0-9.e-]+`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := NewHealthController(&HealthConfiguration{CheckTimeout: 10 * time.Millisecond}, tt.checks, logging.Discard())
+			if tt.shuttingDown {
+				hc.SetShuttingDown()
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			hc.HandleReadiness(responseRecorder, httptest.NewRequest("GET", "/readyz", nil))
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, latency.ReplaceAllString(responseRecorder.Body.String(), `"latency_ms":0`))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package contoller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/remikj/pages-ms/src/codec"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	pageStatusFound    = "found"
+	pageStatusNotFound = "not_found"
+)
+
+type PagesResponse struct {
+	Pages []PageResult
+}
+
+// PageResult is a result for one of requested ids, Page is set only when Status is found.
+// ProductsNext is link to the rest of products, when the page has more of them than were returned with it.
+type PageResult struct {
+	PageId       int
+	Status       string
+	Page         *model.Page `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+	ProductsNext string      `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+}
+
+// pagesNegotiator selects representation of PagesResponse. Protobuf has no message for it, so it is not supported.
+var pagesNegotiator = codec.NewNegotiator(codec.JSONEncoder{}, codec.XMLEncoder{}, codec.MessagePackEncoder{})
+
+// HandlePagesGet returns pages with comma separated ids from ids query parameter, in requested order.
+// Duplicated ids are returned once. At most MaxBatchSize ids can be requested.
+func (pc *PageControllerImpl) HandlePagesGet(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageIds, err := parsePageIds(request.URL.Query().Get("ids"), pc.Config.MaxBatchSize)
+	if err != nil {
+		logger.Info("invalid page ids", "error", err)
+		problem.Write(writer, request, logger, http.StatusBadRequest, problem.CodeInvalidId, fmt.Sprintf("Invalid ids: %v", err))
+		return
+	}
+	logger = logger.With("page_ids", pageIds)
+	encoder, mediaType, ok := pc.negotiate(writer, request, logger, pagesNegotiator)
+	if !ok {
+		return
+	}
+
+	pages, err := pc.PageService.GetPages(request.Context(), pageIds)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+
+	response := PagesResponse{Pages: make([]PageResult, 0, len(pageIds))}
+	for _, pageId := range pageIds {
+		result := PageResult{PageId: pageId, Status: pageStatusNotFound}
+		if page, ok := pages[pageId]; ok && page != nil {
+			result.Status = pageStatusFound
+			result.Page = page
+			if page.ProductsNext != nil {
+				result.ProductsNext = productsNextLink(pageId, page.ProductsNext)
+			}
+		}
+		response.Pages = append(response.Pages, result)
+	}
+	encoded, err := encoder.Encode(response)
+	if err != nil {
+		logger.Error("failed to encode response", "error", err)
+		handleInternalServerError(writer, request, logger)
+		return
+	}
+	writeBody(writer, logger, mediaType, http.StatusOK, encoded)
+}
+
+func parsePageIds(ids string, maxBatchSize int) ([]int, error) {
+	if ids == "" {
+		return nil, errors.New("expected comma separated numbers")
+	}
+	idStrings := strings.Split(ids, ",")
+	pageIds := make([]int, 0, len(idStrings))
+	seen := make(map[int]bool, len(idStrings))
+	for _, idString := range idStrings {
+		pageId, err := strconv.Atoi(strings.TrimSpace(idString))
+		if err != nil {
+			return nil, errors.New("expected comma separated numbers")
+		}
+		if seen[pageId] {
+			continue
+		}
+		seen[pageId] = true
+		pageIds = append(pageIds, pageId)
+	}
+	if len(pageIds) > maxBatchSize {
+		return nil, fmt.Errorf("too many ids, at most %v can be requested", maxBatchSize)
+	}
+	return pageIds, nil
+}
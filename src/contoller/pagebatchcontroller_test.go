@@ -0,0 +1,176 @@
+package contoller
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
+	"github.com/remikj/pages-ms/src/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPageControllerImpl_HandlePagesGet(t *testing.T) {
+	foundPage := &model.Page{SEO: model.SEO{PageId: 1, Title: "title"}, Products: []model.Product{}}
+	productsNext := model.CursorAfter(model.SortById, &model.Product{Id: 5, PageId: 1})
+	cappedPage := &model.Page{SEO: model.SEO{PageId: 1, Title: "title"}, Products: []model.Product{}, ProductsNext: productsNext}
+	tests := []struct {
+		name         string
+		pageService  service.PageService
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "should return found and not found pages in requested order",
+			pageService: &pageServiceMock{
+				getPagesFn: func(pageIds []int) (map[int]*model.Page, error) {
+					return map[int]*model.Page{1: foundPage}, nil
+				},
+			},
+			query:        "?ids=2,1,2",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"Pages":[` +
+				`{"PageId":2,"Status":"not_found"},` +
+				`{"PageId":1,"Status":"found","Page":{"SEO":{"PageId":1,"Title":"title","Description":"","Robots":""},"Products":[]}}` +
+				`]}`,
+		},
+		{
+			name: "should link to the rest of products, when page has more of them than were returned",
+			pageService: &pageServiceMock{
+				getPagesFn: func(pageIds []int) (map[int]*model.Page, error) {
+					return map[int]*model.Page{1: cappedPage}, nil
+				},
+			},
+			query:        "?ids=1",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"Pages":[` +
+				`{"PageId":1,"Status":"found","Page":{"SEO":{"PageId":1,"Title":"title","Description":"","Robots":""},"Products":[]},` +
+				`"ProductsNext":"/pages/1/products?cursor=` + productsNext.Encode() + `"}` +
+				`]}`,
+		},
+		{
+			name:         "should return bad request, when ids missing",
+			query:        "",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidId, "Invalid ids: expected comma separated numbers"),
+		},
+		{
+			name:         "should return bad request, when id is not a number",
+			query:        "?ids=1,a",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidId, "Invalid ids: expected comma separated numbers"),
+		},
+		{
+			name:         "should return bad request, when too many ids",
+			query:        "?ids=1,2,3,4",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidId, "Invalid ids: too many ids, at most 3 can be requested"),
+		},
+		{
+			name: "should return internal server error, when PageService fails",
+			pageService: &pageServiceMock{
+				getPagesFn: func(pageIds []int) (map[int]*model.Page, error) {
+					return nil, errors.New("PageService failed")
+				},
+			},
+			query:        "?ids=1",
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: problemBody(http.StatusInternalServerError, problem.CodeInternal, "Unexpected error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := PageControllerImpl{
+				Config:      &PageConfiguration{MaxBatchSize: 3},
+				PageService: tt.pageService,
+				Logger:      logging.Discard(),
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandlePagesGet(responseRecorder, httptest.NewRequest("GET", "/pages"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestPageControllerImpl_HandlePagesGet_shouldNegotiateRepresentation(t *testing.T) {
+	foundPage := &model.Page{SEO: model.SEO{PageId: 1, Title: "title"}, Products: []model.Product{{Id: 2, PageId: 1, Name: "name"}}}
+	expectedResponse := PagesResponse{Pages: []PageResult{
+		{PageId: 1, Status: pageStatusFound, Page: foundPage}, {PageId: 2, Status: pageStatusNotFound},
+	}}
+	tests := []struct {
+		name                string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		decode              func(body []byte, response *PagesResponse) error
+		expectedBody        string
+	}{
+		{
+			name:                "should return json, when accept is empty",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json",
+			decode:              func(body []byte, response *PagesResponse) error { return json.Unmarshal(body, response) },
+		},
+		{
+			name:                "should return xml, when xml accepted",
+			accept:              "text/xml",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/xml",
+			decode:              func(body []byte, response *PagesResponse) error { return xml.Unmarshal(body, response) },
+		},
+		{
+			name:                "should return msgpack, when msgpack accepted",
+			accept:              "application/msgpack",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/msgpack",
+			decode:              func(body []byte, response *PagesResponse) error { return msgpack.Unmarshal(body, response) },
+		},
+		{
+			name:                "should return not acceptable, when only protobuf accepted",
+			accept:              "application/x-protobuf",
+			expectedCode:        http.StatusNotAcceptable,
+			expectedContentType: problem.ContentType,
+			expectedBody: problemBody(http.StatusNotAcceptable, problem.CodeNotAcceptable,
+				"Supported media types: application/json, application/xml, application/msgpack"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := PageControllerImpl{
+				Config: &PageConfiguration{MaxBatchSize: 3},
+				PageService: &pageServiceMock{
+					getPagesFn: func(pageIds []int) (map[int]*model.Page, error) {
+						return map[int]*model.Page{1: foundPage}, nil
+					},
+				},
+				Logger: logging.Discard(),
+			}
+			request := httptest.NewRequest("GET", "/pages?ids=1,2", nil)
+			request.Header.Set("Accept", tt.accept)
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandlePagesGet(responseRecorder, request)
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedContentType, responseRecorder.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", responseRecorder.Header().Get("Vary"))
+			if tt.decode == nil {
+				assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+				return
+			}
+			response := PagesResponse{}
+			require.NoError(t, tt.decode(responseRecorder.Body.Bytes(), &response))
+			assert.Equal(t, expectedResponse, response)
+		})
+	}
+}
@@ -1,59 +1,364 @@
 package contoller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/codec"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
 	"github.com/remikj/pages-ms/src/service"
+	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type PageController interface {
 	HandlePageGet(writer http.ResponseWriter, request *http.Request)
+	HandlePagesGet(writer http.ResponseWriter, request *http.Request)
+	HandlePagePost(writer http.ResponseWriter, request *http.Request)
+	HandlePagePut(writer http.ResponseWriter, request *http.Request)
+	HandlePagePatch(writer http.ResponseWriter, request *http.Request)
+	HandlePageDelete(writer http.ResponseWriter, request *http.Request)
+}
+
+type PageConfiguration struct {
+	MaxBatchSize int `envconfig:"PAGE_BATCH_MAX_SIZE" default:"50"`
+	// CacheControl is sent with pages returned by GET, empty value omits the header.
+	CacheControl string `envconfig:"PAGE_CACHE_CONTROL" default:"no-cache"`
 }
 
 type PageControllerImpl struct {
+	Config      *PageConfiguration
 	PageService service.PageService
+	// Negotiator selects representation of returned pages from Accept header.
+	Negotiator *codec.Negotiator
+	Logger     *slog.Logger
+}
+
+func NewPageControllerFromEnv(pageService service.PageService, logger *slog.Logger) (*PageControllerImpl, error) {
+	config := &PageConfiguration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	return NewPageController(config, pageService, logger), nil
 }
 
-func NewPageController(pageService service.PageService) *PageControllerImpl {
-	return &PageControllerImpl{pageService}
+func NewPageController(config *PageConfiguration, pageService service.PageService, logger *slog.Logger) *PageControllerImpl {
+	return &PageControllerImpl{
+		Config:      config,
+		PageService: pageService,
+		Negotiator:  codec.DefaultNegotiator(),
+		Logger:      logger,
+	}
 }
 
 func (pc *PageControllerImpl) HandlePageGet(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
 	pageId, err := getPageIdFromRequest(request)
 	if err != nil {
-		fmt.Println(err)
-		handleBadRequest(writer)
+		logger.Info("invalid page id", "error", err)
+		handleBadRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId)
+	fields, err := getFieldsFromRequest(request)
+	if err != nil {
+		logger.Info("invalid fields", "error", err)
+		problem.Write(writer, request, logger, http.StatusBadRequest, problem.CodeInvalidFields, err.Error())
+		return
+	}
+	encoder, mediaType, ok := pc.negotiate(writer, request, logger, pc.Negotiator)
+	if !ok {
 		return
 	}
 
-	page, err := pc.PageService.GetPage(pageId)
+	var page *model.Page
+	if fields == nil {
+		page, err = pc.PageService.GetPage(request.Context(), pageId)
+	} else {
+		page, err = pc.PageService.GetPageFields(request.Context(), pageId, fields)
+	}
 	if err != nil {
-		fmt.Println(err)
-		handleInternalServerError(writer)
+		handleServiceError(writer, request, logger, err)
 		return
 	}
 
 	if page == nil {
-		handleNotFoundServerError(writer)
+		logger.Info("page not found")
+		handleNotFoundServerError(writer, request, logger)
+		return
+	}
+
+	if logger.Enabled(request.Context(), slog.LevelDebug) {
+		marshal, _ := json.Marshal(page)
+		logger.Debug("found page", "page", string(marshal))
+	}
+	var representation interface{} = page
+	if fields != nil {
+		representation = page.Sparse(fields)
+	}
+	if page.Partial {
+		writePartialPage(writer, request, logger, encoder, mediaType, representation)
+		return
+	}
+	if page.ProductsNext != nil {
+		writer.Header().Set("Link", fmt.Sprintf(`<%v>; rel="next"`, productsNextLink(pageId, page.ProductsNext)))
+	}
+	encoded, ok := encodePage(writer, request, logger, encoder, representation, page.SEO.UpdatedAt)
+	if !ok {
+		return
+	}
+	if pc.Config.CacheControl != "" {
+		writer.Header().Set("Cache-Control", pc.Config.CacheControl)
+	}
+	if notModified(request, writer.Header().Get("ETag"), page.SEO.UpdatedAt) {
+		logger.Debug("page not modified")
+		writer.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeBody(writer, logger, mediaType, http.StatusOK, encoded)
+}
+
+func (pc *PageControllerImpl) HandlePagePost(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	encoder, mediaType, ok := pc.negotiate(writer, request, logger, pc.Negotiator)
+	if !ok {
+		return
+	}
+	page := &model.Page{}
+	if err := decodeBody(request, page); err != nil {
+		logger.Info("invalid request body", "error", err)
+		handleInvalidBody(writer, request, logger, err)
+		return
+	}
+
+	createdPage, err := pc.PageService.CreatePage(request.Context(), page)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	writer.Header().Set("Location", fmt.Sprintf("/pages/%v", createdPage.SEO.PageId))
+	writePage(writer, request, logger, encoder, mediaType, http.StatusCreated, createdPage)
+}
+
+func (pc *PageControllerImpl) HandlePagePut(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, err := getPageIdFromRequest(request)
+	if err != nil {
+		logger.Info("invalid page id", "error", err)
+		handleBadRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId)
+	encoder, mediaType, ok := pc.negotiate(writer, request, logger, pc.Negotiator)
+	if !ok {
+		return
+	}
+	page := &model.Page{}
+	if err := decodeBody(request, page); err != nil {
+		logger.Info("invalid request body", "error", err)
+		handleInvalidBody(writer, request, logger, err)
+		return
+	}
+	if page.SEO.PageId == 0 {
+		page.SEO.PageId = pageId
+	}
+	if page.SEO.PageId != pageId {
+		handleInvalidBody(writer, request, logger, fmt.Errorf("SEO.PageId %v does not match pageId %v", page.SEO.PageId, pageId))
+		return
+	}
+	if !pc.checkIfMatch(writer, request, logger, encoder, pageId) {
+		return
+	}
+
+	replacedPage, err := pc.PageService.ReplacePage(request.Context(), page)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	writePage(writer, request, logger, encoder, mediaType, http.StatusOK, replacedPage)
+}
+
+func (pc *PageControllerImpl) HandlePagePatch(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, err := getPageIdFromRequest(request)
+	if err != nil {
+		logger.Info("invalid page id", "error", err)
+		handleBadRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId)
+	encoder, mediaType, ok := pc.negotiate(writer, request, logger, pc.Negotiator)
+	if !ok {
+		return
+	}
+	patch := model.PagePatch{}
+	if err := decodeBody(request, &patch); err != nil {
+		logger.Info("invalid request body", "error", err)
+		handleInvalidBody(writer, request, logger, err)
+		return
+	}
+	if !pc.checkIfMatch(writer, request, logger, encoder, pageId) {
 		return
 	}
 
-	marshal, err := json.Marshal(page)
+	patchedPage, err := pc.PageService.PatchPage(request.Context(), pageId, patch)
 	if err != nil {
-		fmt.Println(err)
-		handleInternalServerError(writer)
+		handleServiceError(writer, request, logger, err)
 		return
 	}
-	fmt.Printf("Found page with id: %v Page: %v\n", pageId, string(marshal))
+	writePage(writer, request, logger, encoder, mediaType, http.StatusOK, patchedPage)
+}
 
-	err = writeResponse(writer, marshal)
+func (pc *PageControllerImpl) HandlePageDelete(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, err := getPageIdFromRequest(request)
 	if err != nil {
-		fmt.Println(err)
+		logger.Info("invalid page id", "error", err)
+		handleBadRequest(writer, request, logger)
 		return
 	}
+	logger = logger.With("page_id", pageId)
+	// Entity tag depends on representation, so If-Match is compared with the one selected by Accept header.
+	if request.Header.Get("If-Match") != "" {
+		encoder, _, ok := pc.negotiate(writer, request, logger, pc.Negotiator)
+		if !ok || !pc.checkIfMatch(writer, request, logger, encoder, pageId) {
+			return
+		}
+	}
+
+	if err := pc.PageService.DeletePage(request.Context(), pageId); err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (pc *PageControllerImpl) requestLogger(request *http.Request) *slog.Logger {
+	return logging.FromContext(request.Context(), pc.Logger)
+}
+
+// negotiate selects encoder of the response from Accept header, responding with 406 when none is acceptable.
+// It is called before the request is handled, so unacceptable requests do not modify pages.
+func (pc *PageControllerImpl) negotiate(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, negotiator *codec.Negotiator) (codec.Encoder, string, bool) {
+	writer.Header().Add("Vary", "Accept")
+	accept := request.Header.Get("Accept")
+	encoder, mediaType, ok := negotiator.Negotiate(accept)
+	if !ok {
+		logger.Info("no acceptable representation", "accept", accept)
+		problem.Write(writer, request, logger, http.StatusNotAcceptable, problem.CodeNotAcceptable,
+			fmt.Sprintf("Supported media types: %v", strings.Join(negotiator.MediaTypes(), ", ")))
+		return nil, "", false
+	}
+	return encoder, mediaType, true
+}
+
+// checkIfMatch evaluates If-Match header of write request against current representation of the page,
+// responding with 412 when it does not match or the page does not exist. The check and the following write
+// are not atomic, so it prevents overwriting changes made since the client read the page,
+// but not concurrent writes racing with each other.
+func (pc *PageControllerImpl) checkIfMatch(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, encoder codec.Encoder, pageId int) bool {
+	ifMatch := request.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+	page, err := pc.currentPage(request.Context(), pageId)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return false
+	}
+	if page != nil && page.Partial {
+		handleServiceError(writer, request, logger, fmt.Errorf("%w: products of the page could not be read to check If-Match", model.ErrBackendUnavailable))
+		return false
+	}
+	if page != nil {
+		encoded, err := encoder.Encode(page)
+		if err != nil {
+			logger.Error("failed to encode page", "error", err)
+			handleInternalServerError(writer, request, logger)
+			return false
+		}
+		if matchesEtag(ifMatch, etagOf(encoded), false) {
+			return true
+		}
+	}
+	logger.Info("precondition failed", "if_match", ifMatch)
+	problem.Write(writer, request, logger, http.StatusPreconditionFailed, problem.CodePreconditionFailed,
+		"If-Match does not match current representation of the page")
+	return false
+}
+
+// currentPage gets page bypassing cache, as cached page could miss writes made by other instances
+// and let a stale entity tag match.
+func (pc *PageControllerImpl) currentPage(ctx context.Context, pageId int) (*model.Page, error) {
+	if getter, ok := pc.PageService.(service.UncachedPageGetter); ok {
+		return getter.GetPageUncached(ctx, pageId)
+	}
+	return pc.PageService.GetPage(ctx, pageId)
+}
+
+func decodeBody(request *http.Request, target interface{}) error {
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// statusClientClosedRequest is non-standard status, used only for logs and metrics,
+// as the client is no longer waiting for response.
+const statusClientClosedRequest = 499
+
+// handleServiceError writes problem for err. Errors caused by request context being done are
+// reported as timeout or client cancellation, even when the repository does not wrap context error.
+func handleServiceError(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, err error) {
+	if ctxErr := request.Context().Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		err = fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn("request timed out", "outcome", "timeout", "error", err)
+		problem.Write(writer, request, logger, http.StatusGatewayTimeout, problem.CodeTimeout, "Request timed out")
+	case errors.Is(err, context.Canceled):
+		logger.Info("request cancelled by client", "outcome", "client_cancelled", "error", err)
+		writer.WriteHeader(statusClientClosedRequest)
+	case errors.Is(err, model.ErrInvalidPage), errors.Is(err, model.ErrInvalidProduct):
+		logger.Info("invalid request", "error", err)
+		problem.Write(writer, request, logger, http.StatusBadRequest, problem.CodeInvalidBody, err.Error())
+	case errors.Is(err, model.ErrPageNotFound), errors.Is(err, model.ErrProductNotFound):
+		logger.Info("result not found", "error", err)
+		problem.Write(writer, request, logger, http.StatusNotFound, problem.CodeNotFound, err.Error())
+	case errors.Is(err, model.ErrPageAlreadyExists), errors.Is(err, model.ErrProductAlreadyExists):
+		logger.Info("conflict", "error", err)
+		problem.Write(writer, request, logger, http.StatusConflict, problem.CodeAlreadyExists, err.Error())
+	case errors.Is(err, model.ErrOverloaded):
+		logger.Warn("service overloaded", "outcome", "overloaded", "error", err)
+		setRetryAfter(writer, err)
+		problem.Write(writer, request, logger, http.StatusServiceUnavailable, problem.CodeOverloaded, "Service is overloaded, retry later")
+	case errors.Is(err, model.ErrBackendUnavailable):
+		logger.Error("backend unavailable", "error", err)
+		problem.Write(writer, request, logger, http.StatusServiceUnavailable, problem.CodeBackendUnavailable, "Backend is unavailable")
+	case errors.Is(err, model.ErrDataIntegrity):
+		logger.Error("data integrity violation", "error", err)
+		problem.Write(writer, request, logger, http.StatusInternalServerError, problem.CodeDataIntegrity, "Stored data is inconsistent")
+	default:
+		logger.Error("unexpected error", "error", err)
+		handleInternalServerError(writer, request, logger)
+	}
+}
+
+// setRetryAfter sets Retry-After header in whole seconds, rounded up, when err carries retry hint.
+func setRetryAfter(writer http.ResponseWriter, err error) {
+	var overloadedErr *model.OverloadedError
+	if errors.As(err, &overloadedErr) && overloadedErr.RetryAfter > 0 {
+		writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(overloadedErr.RetryAfter.Seconds()))))
+	}
 }
 
 func getPageIdFromRequest(request *http.Request) (int, error) {
@@ -65,28 +370,76 @@ func getPageIdFromRequest(request *http.Request) (int, error) {
 	return pageId, nil
 }
 
-func handleNotFoundServerError(writer http.ResponseWriter) {
-	writeStatusAndText(writer, http.StatusNotFound, "result not found")
+// getFieldsFromRequest parses fields query parameter, returns nil when the whole page is requested.
+func getFieldsFromRequest(request *http.Request) (*model.PageFields, error) {
+	value := request.URL.Query().Get("fields")
+	if value == "" {
+		return nil, nil
+	}
+	return model.ParsePageFields(value)
 }
 
-func writeResponse(writer http.ResponseWriter, marshal []byte) error {
-	writer.Header().Set("Content-Type", "application/json")
-	_, err := writer.Write(marshal)
-	return err
+func handleNotFoundServerError(writer http.ResponseWriter, request *http.Request, logger *slog.Logger) {
+	problem.Write(writer, request, logger, http.StatusNotFound, problem.CodeNotFound, model.ErrPageNotFound.Error())
 }
 
-func handleInternalServerError(writer http.ResponseWriter) {
-	writeStatusAndText(writer, http.StatusInternalServerError, "Unexpected error")
+func writePage(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, encoder codec.Encoder, mediaType string, status int, page *model.Page) {
+	if encoded, ok := encodePage(writer, request, logger, encoder, page, page.SEO.UpdatedAt); ok {
+		writeBody(writer, logger, mediaType, status, encoded)
+	}
 }
 
-func handleBadRequest(writer http.ResponseWriter) {
-	writeStatusAndText(writer, http.StatusBadRequest, "Expected pageId to be number")
+// encodePage encodes representation of the page and sets its validators, responding with 500
+// when the page can not be encoded.
+func encodePage(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, encoder codec.Encoder, representation interface{}, modifiedAt time.Time) ([]byte, bool) {
+	encoded, err := encoder.Encode(representation)
+	if err != nil {
+		logger.Error("failed to encode response", "error", err)
+		handleInternalServerError(writer, request, logger)
+		return nil, false
+	}
+	setValidators(writer.Header(), etagOf(encoded), modifiedAt)
+	return encoded, true
 }
 
-func writeStatusAndText(writer http.ResponseWriter, status int, text string) {
-	writer.WriteHeader(status)
-	_, err := writer.Write([]byte(text))
+// productsNextLink is link to products of the page following those returned with the page.
+func productsNextLink(pageId int, next *model.ProductCursor) string {
+	return fmt.Sprintf("/pages/%v/products?cursor=%v", pageId, next.Encode())
+}
+
+// partialPageWarning is Warning header of pages returned without products.
+const partialPageWarning = `199 pages-ms "Products are unavailable, page is returned without them"`
+
+// writePartialPage responds with page returned without products. It has no validators and is not stored
+// by caches, so the client gets the whole page once products can be read again.
+func writePartialPage(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, encoder codec.Encoder, mediaType string, representation interface{}) {
+	encoded, err := encoder.Encode(representation)
 	if err != nil {
-		fmt.Println(err)
+		logger.Error("failed to encode response", "error", err)
+		handleInternalServerError(writer, request, logger)
+		return
+	}
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.Header().Set("Warning", partialPageWarning)
+	writeBody(writer, logger, mediaType, http.StatusOK, encoded)
+}
+
+func writeBody(writer http.ResponseWriter, logger *slog.Logger, mediaType string, status int, encoded []byte) {
+	writer.Header().Set("Content-Type", mediaType)
+	writer.WriteHeader(status)
+	if _, err := writer.Write(encoded); err != nil {
+		logger.Warn("failed to write response", "error", err)
 	}
 }
+
+func handleInternalServerError(writer http.ResponseWriter, request *http.Request, logger *slog.Logger) {
+	problem.Write(writer, request, logger, http.StatusInternalServerError, problem.CodeInternal, "Unexpected error")
+}
+
+func handleBadRequest(writer http.ResponseWriter, request *http.Request, logger *slog.Logger) {
+	problem.Write(writer, request, logger, http.StatusBadRequest, problem.CodeInvalidId, "Expected pageId to be number")
+}
+
+func handleInvalidBody(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, err error) {
+	problem.Write(writer, request, logger, http.StatusBadRequest, problem.CodeInvalidBody, fmt.Sprintf("Invalid request body: %v", err))
+}
@@ -1,18 +1,27 @@
 package contoller
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/remikj/pages-ms/src/codec"
+	"github.com/remikj/pages-ms/src/logging"
 	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
 	"github.com/remikj/pages-ms/src/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -71,7 +80,7 @@ func TestPageControllerImpl_HandlePageGet(t *testing.T) {
 		{
 			name:     "should return correct json when all data valid",
 			request:  requestWithParam("not-a-number"),
-			expected: expectedWrite{code: http.StatusBadRequest, bodyString: "Expected pageId to be number"},
+			expected: expectedWrite{code: http.StatusBadRequest, bodyString: problemBody(http.StatusBadRequest, problem.CodeInvalidId, "Expected pageId to be number")},
 		},
 		{
 			name:    "should return internal server error when PageService fails",
@@ -81,13 +90,56 @@ func TestPageControllerImpl_HandlePageGet(t *testing.T) {
 					return nil, errors.New("PageService failed")
 				},
 			},
-			expected: expectedWrite{code: http.StatusInternalServerError, bodyString: "Unexpected error"},
+			expected: expectedWrite{code: http.StatusInternalServerError, bodyString: problemBody(http.StatusInternalServerError, problem.CodeInternal, "Unexpected error")},
+		},
+		{
+			name:    "should return gateway timeout when PageService exceeds deadline",
+			request: requestWithParam("1"),
+			pageService: &pageServiceMock{
+				getPageFn: func(pageId int) (*model.Page, error) {
+					return nil, fmt.Errorf("failed to find seo: %w", context.DeadlineExceeded)
+				},
+			},
+			expected: expectedWrite{code: http.StatusGatewayTimeout, bodyString: problemBody(http.StatusGatewayTimeout, problem.CodeTimeout, "Request timed out")},
+		},
+		{
+			name:    "should return service unavailable when backend is unavailable",
+			request: requestWithParam("1"),
+			pageService: &pageServiceMock{
+				getPageFn: func(pageId int) (*model.Page, error) {
+					return nil, fmt.Errorf("%w: connection refused", model.ErrBackendUnavailable)
+				},
+			},
+			expected: expectedWrite{code: http.StatusServiceUnavailable, bodyString: problemBody(http.StatusServiceUnavailable, problem.CodeBackendUnavailable, "Backend is unavailable")},
+		},
+		{
+			name:    "should return internal server error with data integrity code when stored data is inconsistent",
+			request: requestWithParam("1"),
+			pageService: &pageServiceMock{
+				getPageFn: func(pageId int) (*model.Page, error) {
+					return nil, fmt.Errorf("%w: too many results", model.ErrDataIntegrity)
+				},
+			},
+			expected: expectedWrite{code: http.StatusInternalServerError, bodyString: problemBody(http.StatusInternalServerError, problem.CodeDataIntegrity, "Stored data is inconsistent")},
+		},
+		{
+			name:    "should return client closed request when request cancelled",
+			request: cancelledRequest(requestWithParam("1")),
+			pageService: &pageServiceMock{
+				getPageFn: func(pageId int) (*model.Page, error) {
+					return nil, errors.New("connection closed")
+				},
+			},
+			expected: expectedWrite{code: statusClientClosedRequest, bodyString: ""},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			pc := PageControllerImpl{
+				Config:      &PageConfiguration{},
+				Negotiator:  codec.DefaultNegotiator(),
 				PageService: tt.pageService,
+				Logger:      logging.Discard(),
 			}
 			responseRecorder := httptest.NewRecorder()
 
@@ -101,8 +153,566 @@ func TestPageControllerImpl_HandlePageGet(t *testing.T) {
 	}
 }
 
+func TestPageControllerImpl_HandlePageGet_shouldReturnRetryAfter_whenOverloaded(t *testing.T) {
+	pc := PageControllerImpl{
+		Config:     &PageConfiguration{},
+		Negotiator: codec.DefaultNegotiator(),
+		Logger:     logging.Discard(),
+		PageService: &pageServiceMock{
+			getPageFn: func(pageId int) (*model.Page, error) {
+				return nil, fmt.Errorf("failed to get seo: %w", &model.OverloadedError{RetryAfter: 1500 * time.Millisecond})
+			},
+		},
+	}
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandlePageGet(responseRecorder, requestWithParam("1"))
+
+	assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Code)
+	assert.Equal(t, "2", responseRecorder.Header().Get("Retry-After"))
+	assert.Equal(t, problemBody(http.StatusServiceUnavailable, problem.CodeOverloaded, "Service is overloaded, retry later"), responseRecorder.Body.String())
+}
+
+func TestPageControllerImpl_HandlePageGet_shouldNegotiateRepresentation(t *testing.T) {
+	tests := []struct {
+		name                string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedBodyPrefix  string
+		expectServiceCall   bool
+	}{
+		{
+			name:                "should return json, when accept is empty",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBodyPrefix:  sampleModelPageString,
+			expectServiceCall:   true,
+		},
+		{
+			name:                "should return xml, when xml accepted",
+			accept:              "application/xml",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/xml",
+			expectedBodyPrefix:  `<?xml version="1.0" encoding="UTF-8"?>` + "\n<Page><SEO>",
+			expectServiceCall:   true,
+		},
+		{
+			name:                "should return not acceptable without getting page, when no representation accepted",
+			accept:              "text/html",
+			expectedCode:        http.StatusNotAcceptable,
+			expectedContentType: problem.ContentType,
+			expectedBodyPrefix: problemBody(http.StatusNotAcceptable, problem.CodeNotAcceptable,
+				"Supported media types: application/json, application/xml, application/msgpack, application/x-protobuf"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceCalled := false
+			pc := NewPageController(&PageConfiguration{}, &pageServiceMock{
+				getPageFn: func(pageId int) (*model.Page, error) {
+					serviceCalled = true
+					return &sampleModelPage, nil
+				},
+			}, logging.Discard())
+			request := requestWithParam("0")
+			request.Header.Set("Accept", tt.accept)
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandlePageGet(responseRecorder, request)
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedContentType, responseRecorder.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", responseRecorder.Header().Get("Vary"))
+			assert.True(t, strings.HasPrefix(responseRecorder.Body.String(), tt.expectedBodyPrefix), responseRecorder.Body.String())
+			assert.Equal(t, tt.expectServiceCall, serviceCalled)
+		})
+	}
+}
+
+func TestPageControllerImpl_HandlePageGet_shouldReturnSelectedFields(t *testing.T) {
+	tests := []struct {
+		name           string
+		fields         string
+		expectedFields *model.PageFields
+		expectedCode   int
+		expectedBody   string
+	}{
+		{
+			name:           "should return only selected fields, when fields valid",
+			fields:         "seo.title,products.name,products.price",
+			expectedFields: &model.PageFields{SEO: []string{"Title"}, Products: []string{"Name", "Price"}},
+			expectedCode:   http.StatusOK,
+			expectedBody: `{"SEO":{"Title":"Sample page title"},"Products":[` +
+				`{"Name":"Sample product 0 name","Price":2.5},{"Name":"Sample product 1 name","Price":19.99}]}`,
+		},
+		{
+			name:           "should omit seo, when only products selected",
+			fields:         "products.id",
+			expectedFields: &model.PageFields{Products: []string{"Id"}},
+			expectedCode:   http.StatusOK,
+			expectedBody:   `{"Products":[{"Id":0},{"Id":1}]}`,
+		},
+		{
+			name:         "should return bad request without getting page, when field unknown",
+			fields:       "seo.title,products.color",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidFields,
+				`invalid fields: unknown field "products.color", products has fields Id, PageId, Name, Description, Price`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestedFields *model.PageFields
+			pc := NewPageController(&PageConfiguration{}, &pageServiceMock{
+				getPageFieldsFn: func(pageId int, fields *model.PageFields) (*model.Page, error) {
+					requestedFields = fields
+					return &sampleModelPage, nil
+				},
+			}, logging.Discard())
+			request := requestWithParam("1")
+			request.URL.RawQuery = url.Values{"fields": {tt.fields}}.Encode()
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandlePageGet(responseRecorder, request)
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+			assert.Equal(t, tt.expectedFields, requestedFields)
+		})
+	}
+}
+
+func TestPageControllerImpl_HandlePageGet_shouldLogPagePayloadOnlyOnDebugLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		level         slog.Level
+		expectPayload bool
+	}{
+		{name: "should log payload, when debug level", level: slog.LevelDebug, expectPayload: true},
+		{name: "should not log payload, when info level", level: slog.LevelInfo, expectPayload: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			pc := PageControllerImpl{
+				Config:     &PageConfiguration{},
+				Negotiator: codec.DefaultNegotiator(),
+				Logger:     slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: tt.level})),
+				PageService: &pageServiceMock{
+					getPageFn: func(pageId int) (*model.Page, error) { return &sampleModelPage, nil },
+				},
+			}
+
+			pc.HandlePageGet(httptest.NewRecorder(), requestWithParam("1"))
+
+			assert.Equal(t, tt.expectPayload, strings.Contains(buffer.String(), "Sample page title"))
+		})
+	}
+}
+
+func TestPageControllerImpl_HandlePagePost(t *testing.T) {
+	tests := []struct {
+		name         string
+		pageService  service.PageService
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "should return created page",
+			pageService: &pageServiceMock{
+				createPageFn: func(page *model.Page) (*model.Page, error) { return page, nil },
+			},
+			body:         sampleModelPageString,
+			expectedCode: http.StatusCreated,
+			expectedBody: sampleModelPageString,
+		},
+		{
+			name:         "should return bad request, when body has unknown fields",
+			body:         `{"Unknown": 1}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidBody, `Invalid request body: json: unknown field "Unknown"`),
+		},
+		{
+			name: "should return conflict, when page already exists",
+			pageService: &pageServiceMock{
+				createPageFn: func(page *model.Page) (*model.Page, error) { return nil, model.ErrPageAlreadyExists },
+			},
+			body:         sampleModelPageString,
+			expectedCode: http.StatusConflict,
+			expectedBody: problemBody(http.StatusConflict, problem.CodeAlreadyExists, "page already exists"),
+		},
+		{
+			name: "should return bad request, when page is invalid",
+			pageService: &pageServiceMock{
+				createPageFn: func(page *model.Page) (*model.Page, error) {
+					return nil, fmt.Errorf("%w: SEO.Title must not be empty", model.ErrInvalidPage)
+				},
+			},
+			body:         sampleModelPageString,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidBody, "invalid page: SEO.Title must not be empty"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := PageControllerImpl{
+				Config:      &PageConfiguration{},
+				Negotiator:  codec.DefaultNegotiator(),
+				PageService: tt.pageService,
+				Logger:      logging.Discard(),
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandlePagePost(responseRecorder, httptest.NewRequest("POST", "/pages", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestPageControllerImpl_HandlePagePut(t *testing.T) {
+	tests := []struct {
+		name         string
+		pageService  service.PageService
+		request      *http.Request
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "should return replaced page",
+			pageService: &pageServiceMock{
+				replacePageFn: func(page *model.Page) (*model.Page, error) { return page, nil },
+			},
+			request:      requestWithParamAndBody("PUT", "0", sampleModelPageString),
+			expectedCode: http.StatusOK,
+			expectedBody: sampleModelPageString,
+		},
+		{
+			name:         "should return bad request, when page id differs from path",
+			request:      requestWithParamAndBody("PUT", "5", `{"SEO": {"PageId": 7, "Title": "title"}}`),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidBody, "Invalid request body: SEO.PageId 7 does not match pageId 5"),
+		},
+		{
+			name: "should return not found, when page does not exist",
+			pageService: &pageServiceMock{
+				replacePageFn: func(page *model.Page) (*model.Page, error) { return nil, model.ErrPageNotFound },
+			},
+			request:      requestWithParamAndBody("PUT", "0", sampleModelPageString),
+			expectedCode: http.StatusNotFound,
+			expectedBody: problemBody(http.StatusNotFound, problem.CodeNotFound, "page not found"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := PageControllerImpl{
+				Config:      &PageConfiguration{},
+				Negotiator:  codec.DefaultNegotiator(),
+				PageService: tt.pageService,
+				Logger:      logging.Discard(),
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandlePagePut(responseRecorder, tt.request)
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestPageControllerImpl_HandlePagePatch(t *testing.T) {
+	var receivedPatch model.PagePatch
+	pc := PageControllerImpl{
+		Config:     &PageConfiguration{},
+		Negotiator: codec.DefaultNegotiator(),
+		Logger:     logging.Discard(),
+		PageService: &pageServiceMock{
+			patchPageFn: func(pageId int, patch model.PagePatch) (*model.Page, error) {
+				receivedPatch = patch
+				return &sampleModelPage, nil
+			},
+		},
+	}
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandlePagePatch(responseRecorder, requestWithParamAndBody("PATCH", "0", `{"SEO": {"Title": "new title"}}`))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, sampleModelPageString, responseRecorder.Body.String())
+	require.NotNil(t, receivedPatch.SEO)
+	assert.Equal(t, "new title", *receivedPatch.SEO.Title)
+	assert.Nil(t, receivedPatch.SEO.Robots)
+	assert.Nil(t, receivedPatch.Products)
+}
+
+func TestPageControllerImpl_HandlePageDelete(t *testing.T) {
+	tests := []struct {
+		name         string
+		deleteErr    error
+		expectedCode int
+	}{
+		{
+			name:         "should return no content, when page deleted",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "should return not found, when page does not exist",
+			deleteErr:    model.ErrPageNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "should return internal server error, when PageService fails",
+			deleteErr:    errors.New("PageService failed"),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := PageControllerImpl{
+				Config:     &PageConfiguration{},
+				Negotiator: codec.DefaultNegotiator(),
+				Logger:     logging.Discard(),
+				PageService: &pageServiceMock{
+					deletePageFn: func(pageId int) error { return tt.deleteErr },
+				},
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandlePageDelete(responseRecorder, requestWithParamAndBody("DELETE", "1", ""))
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+		})
+	}
+}
+
+func TestPageControllerImpl_HandlePageGet_shouldHonorConditionalHeaders(t *testing.T) {
+	modifiedAt := time.Date(2024, 3, 1, 10, 0, 0, 500_000_000, time.UTC)
+	page := sampleModelPage
+	page.SEO.UpdatedAt = modifiedAt
+	etag := etagOf([]byte(sampleModelPageString))
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		expectedCode int
+	}{
+		{
+			name:         "should return page, when request is not conditional",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "should return not modified, when If-None-Match contains etag",
+			headers:      map[string]string{"If-None-Match": `"other", W/` + etag},
+			expectedCode: http.StatusNotModified,
+		},
+		{
+			name:         "should return page, when If-None-Match does not contain etag",
+			headers:      map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": modifiedAt.Format(http.TimeFormat)},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "should return not modified, when page not modified since",
+			headers:      map[string]string{"If-Modified-Since": modifiedAt.Format(http.TimeFormat)},
+			expectedCode: http.StatusNotModified,
+		},
+		{
+			name:         "should return page, when page modified since",
+			headers:      map[string]string{"If-Modified-Since": modifiedAt.Add(-time.Second).Format(http.TimeFormat)},
+			expectedCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := PageControllerImpl{
+				Config:     &PageConfiguration{CacheControl: "no-cache"},
+				Negotiator: codec.DefaultNegotiator(),
+				Logger:     logging.Discard(),
+				PageService: &pageServiceMock{
+					getPageFn: func(pageId int) (*model.Page, error) { return &page, nil },
+				},
+			}
+			request := requestWithParam("0")
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandlePageGet(responseRecorder, request)
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, etag, responseRecorder.Header().Get("ETag"))
+			assert.Equal(t, "Fri, 01 Mar 2024 10:00:00 GMT", responseRecorder.Header().Get("Last-Modified"))
+			assert.Equal(t, "no-cache", responseRecorder.Header().Get("Cache-Control"))
+			if tt.expectedCode == http.StatusNotModified {
+				assert.Empty(t, responseRecorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestPageControllerImpl_HandlePageGet_shouldReturnPartialPageWithoutValidators(t *testing.T) {
+	page := model.Page{SEO: sampleModelPage.SEO, Products: []model.Product{}, Partial: true}
+	pc := PageControllerImpl{
+		Config:     &PageConfiguration{CacheControl: "max-age=60"},
+		Negotiator: codec.DefaultNegotiator(),
+		Logger:     logging.Discard(),
+		PageService: &pageServiceMock{
+			getPageFn: func(pageId int) (*model.Page, error) { return &page, nil },
+		},
+	}
+	request := requestWithParam("0")
+	request.Header.Set("If-None-Match", "*")
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandlePageGet(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.JSONEq(t, `{"SEO":{"PageId":0,"Title":"Sample page title","Description":"Sample page description","Robots":"Sample robots"},"Products":[]}`,
+		responseRecorder.Body.String())
+	assert.Equal(t, partialPageWarning, responseRecorder.Header().Get("Warning"))
+	assert.Equal(t, "no-store", responseRecorder.Header().Get("Cache-Control"))
+	assert.Empty(t, responseRecorder.Header().Get("ETag"))
+	assert.Empty(t, responseRecorder.Header().Get("Last-Modified"))
+}
+
+func TestPageControllerImpl_HandlePageGet_shouldLinkToRestOfProducts_whenProductsCapped(t *testing.T) {
+	next := model.CursorAfter(model.SortById, &sampleModelPage.Products[0])
+	page := model.Page{SEO: sampleModelPage.SEO, Products: sampleModelPage.Products[:1], ProductsNext: next}
+	pc := PageControllerImpl{
+		Config:     &PageConfiguration{},
+		Negotiator: codec.DefaultNegotiator(),
+		Logger:     logging.Discard(),
+		PageService: &pageServiceMock{
+			getPageFn: func(pageId int) (*model.Page, error) { return &page, nil },
+		},
+	}
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandlePageGet(responseRecorder, requestWithParam("0"))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, `</pages/0/products?cursor=`+next.Encode()+`>; rel="next"`, responseRecorder.Header().Get("Link"))
+}
+
+func TestPageControllerImpl_WritePage_shouldCheckIfMatch(t *testing.T) {
+	etag := etagOf([]byte(sampleModelPageString))
+	tests := []struct {
+		name         string
+		ifMatch      string
+		storedPage   *model.Page
+		expectedCode int
+		expectWrite  bool
+	}{
+		{
+			name:         "should write page, when If-Match contains etag",
+			ifMatch:      `"other", ` + etag,
+			storedPage:   &sampleModelPage,
+			expectedCode: http.StatusOK,
+			expectWrite:  true,
+		},
+		{
+			name:         "should write page, when If-Match is any and page exists",
+			ifMatch:      "*",
+			storedPage:   &sampleModelPage,
+			expectedCode: http.StatusOK,
+			expectWrite:  true,
+		},
+		{
+			name:         "should return precondition failed, when etag does not match",
+			ifMatch:      `"other"`,
+			storedPage:   &sampleModelPage,
+			expectedCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:         "should return precondition failed, when weak etag is given",
+			ifMatch:      "W/" + etag,
+			storedPage:   &sampleModelPage,
+			expectedCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:         "should return precondition failed, when page does not exist",
+			ifMatch:      "*",
+			expectedCode: http.StatusPreconditionFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := 0
+			pc := PageControllerImpl{
+				Config:     &PageConfiguration{},
+				Negotiator: codec.DefaultNegotiator(),
+				Logger:     logging.Discard(),
+				PageService: &pageServiceMock{
+					getPageFn:     func(pageId int) (*model.Page, error) { return tt.storedPage, nil },
+					replacePageFn: func(page *model.Page) (*model.Page, error) { written++; return page, nil },
+					patchPageFn: func(pageId int, patch model.PagePatch) (*model.Page, error) {
+						written++
+						return &sampleModelPage, nil
+					},
+					deletePageFn: func(pageId int) error { written++; return nil },
+				},
+			}
+			requests := map[string]func(http.ResponseWriter, *http.Request){
+				"PUT":    pc.HandlePagePut,
+				"PATCH":  pc.HandlePagePatch,
+				"DELETE": pc.HandlePageDelete,
+			}
+			bodies := map[string]string{"PUT": sampleModelPageString, "PATCH": `{"SEO": {"Title": "new title"}}`}
+
+			for method, handle := range requests {
+				request := requestWithParamAndBody(method, "0", bodies[method])
+				request.Header.Set("If-Match", tt.ifMatch)
+				responseRecorder := httptest.NewRecorder()
+
+				handle(responseRecorder, request)
+
+				if tt.expectWrite {
+					assert.Less(t, responseRecorder.Code, 300, method)
+				} else {
+					assert.Equal(t, tt.expectedCode, responseRecorder.Code, method)
+					assert.Equal(t, problemBody(http.StatusPreconditionFailed, problem.CodePreconditionFailed,
+						"If-Match does not match current representation of the page"), responseRecorder.Body.String(), method)
+				}
+			}
+			if tt.expectWrite {
+				assert.Equal(t, len(requests), written)
+			} else {
+				assert.Zero(t, written)
+			}
+		})
+	}
+}
+
+func TestPageControllerImpl_WritePage_shouldReturnPreconditionFailed_whenCachedPageIsStale(t *testing.T) {
+	storedPage := sampleModelPage
+	written := 0
+	pageService := service.NewPageServiceCache(&service.CacheConfiguration{TTL: time.Minute, MaxEntries: 10}, &pageServiceMock{
+		getPageFn:     func(pageId int) (*model.Page, error) { page := storedPage; return &page, nil },
+		replacePageFn: func(page *model.Page) (*model.Page, error) { written++; return page, nil },
+	})
+	pc := NewPageController(&PageConfiguration{}, pageService, logging.Discard())
+	_, err := pageService.GetPage(context.Background(), 0)
+	require.NoError(t, err)
+	storedPage.SEO.Title = "written by other instance"
+	request := requestWithParamAndBody("PUT", "0", sampleModelPageString)
+	request.Header.Set("If-Match", etagOf([]byte(sampleModelPageString)))
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandlePagePut(responseRecorder, request)
+
+	assert.Equal(t, http.StatusPreconditionFailed, responseRecorder.Code)
+	assert.Zero(t, written)
+}
+
 func requestWithParam(s string) *http.Request {
-	request := httptest.NewRequest("GET", "/pages/"+s, nil)
+	return requestWithParamAndBody("GET", s, "")
+}
+
+func requestWithParamAndBody(method string, s string, body string) *http.Request {
+	request := httptest.NewRequest(method, "/pages/"+s, strings.NewReader(body))
 
 	routeContext := chi.NewRouteContext()
 	routeContext.URLParams.Add("id", s)
@@ -110,15 +720,98 @@ func requestWithParam(s string) *http.Request {
 	return request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeContext))
 }
 
+func cancelledRequest(request *http.Request) *http.Request {
+	ctx, cancel := context.WithCancel(request.Context())
+	cancel()
+	return request.WithContext(ctx)
+}
+
+// problemBody is problem written by the controller for request without request id.
+func problemBody(status int, code problem.Code, detail string) string {
+	return fmt.Sprintf(`{"type":"urn:pages-ms:problem:%v","title":%q,"status":%v,"code":"%v","detail":%q}`,
+		code, http.StatusText(status), status, code, detail)
+}
+
 func getSampleModelPageString() string {
 	marshal, _ := json.Marshal(sampleModelPage)
 	return string(marshal)
 }
 
 type pageServiceMock struct {
-	getPageFn func(pageId int) (*model.Page, error)
+	getPageFn       func(pageId int) (*model.Page, error)
+	getPageFieldsFn func(pageId int, fields *model.PageFields) (*model.Page, error)
+	getPagesFn      func(pageIds []int) (map[int]*model.Page, error)
+	createPageFn    func(page *model.Page) (*model.Page, error)
+	replacePageFn   func(page *model.Page) (*model.Page, error)
+	patchPageFn     func(pageId int, patch model.PagePatch) (*model.Page, error)
+	deletePageFn    func(pageId int) error
+
+	getProductsFn    func(pageId int) (*model.ProductList, error)
+	getProductFn     func(pageId int, productId int) (*model.Product, error)
+	queryProductsFn  func(pageId int, query *model.ProductQuery, count bool) (*model.ProductList, error)
+	streamProductsFn func(ctx context.Context, pageId int, yield func(product *model.Product) error) error
+	createProductFn  func(pageId int, product *model.Product) (*model.Product, error)
+	replaceProductFn func(pageId int, product *model.Product) (*model.Product, error)
+	patchProductFn   func(pageId int, productId int, patch model.ProductPatch) (*model.Product, error)
+	deleteProductFn  func(pageId int, productId int) error
 }
 
-func (p pageServiceMock) GetPage(pageId int) (*model.Page, error) {
+func (p pageServiceMock) GetPage(ctx context.Context, pageId int) (*model.Page, error) {
 	return p.getPageFn(pageId)
 }
+
+func (p pageServiceMock) GetPageFields(ctx context.Context, pageId int, fields *model.PageFields) (*model.Page, error) {
+	return p.getPageFieldsFn(pageId, fields)
+}
+
+func (p pageServiceMock) GetPages(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+	return p.getPagesFn(pageIds)
+}
+
+func (p pageServiceMock) CreatePage(ctx context.Context, page *model.Page) (*model.Page, error) {
+	return p.createPageFn(page)
+}
+
+func (p pageServiceMock) ReplacePage(ctx context.Context, page *model.Page) (*model.Page, error) {
+	return p.replacePageFn(page)
+}
+
+func (p pageServiceMock) PatchPage(ctx context.Context, pageId int, patch model.PagePatch) (*model.Page, error) {
+	return p.patchPageFn(pageId, patch)
+}
+
+func (p pageServiceMock) DeletePage(ctx context.Context, pageId int) error {
+	return p.deletePageFn(pageId)
+}
+
+func (p pageServiceMock) GetProducts(ctx context.Context, pageId int) (*model.ProductList, error) {
+	return p.getProductsFn(pageId)
+}
+
+func (p pageServiceMock) GetProduct(ctx context.Context, pageId int, productId int) (*model.Product, error) {
+	return p.getProductFn(pageId, productId)
+}
+
+func (p pageServiceMock) QueryProducts(ctx context.Context, pageId int, query *model.ProductQuery, count bool) (*model.ProductList, error) {
+	return p.queryProductsFn(pageId, query, count)
+}
+
+func (p pageServiceMock) StreamProducts(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+	return p.streamProductsFn(ctx, pageId, yield)
+}
+
+func (p pageServiceMock) CreateProduct(ctx context.Context, pageId int, product *model.Product) (*model.Product, error) {
+	return p.createProductFn(pageId, product)
+}
+
+func (p pageServiceMock) ReplaceProduct(ctx context.Context, pageId int, product *model.Product) (*model.Product, error) {
+	return p.replaceProductFn(pageId, product)
+}
+
+func (p pageServiceMock) PatchProduct(ctx context.Context, pageId int, productId int, patch model.ProductPatch) (*model.Product, error) {
+	return p.patchProductFn(pageId, productId, patch)
+}
+
+func (p pageServiceMock) DeleteProduct(ctx context.Context, pageId int, productId int) error {
+	return p.deleteProductFn(pageId, productId)
+}
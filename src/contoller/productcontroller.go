@@ -0,0 +1,431 @@
+package contoller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/codec"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
+	"github.com/remikj/pages-ms/src/service"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type ProductController interface {
+	HandleProductsGet(writer http.ResponseWriter, request *http.Request)
+	HandleProductsPost(writer http.ResponseWriter, request *http.Request)
+	HandleProductGet(writer http.ResponseWriter, request *http.Request)
+	HandleProductPut(writer http.ResponseWriter, request *http.Request)
+	HandleProductPatch(writer http.ResponseWriter, request *http.Request)
+	HandleProductDelete(writer http.ResponseWriter, request *http.Request)
+}
+
+type ProductConfiguration struct {
+	// DefaultLimit is number of products returned by paginated GET, when limit parameter is not given.
+	DefaultLimit int `envconfig:"PRODUCTS_DEFAULT_LIMIT" default:"20"`
+	MaxLimit     int `envconfig:"PRODUCTS_MAX_LIMIT" default:"100"`
+	// StreamIdleTimeout bounds streams of products instead of request timeout, stream fails when no product
+	// is read and written within it. 0 disables it.
+	StreamIdleTimeout time.Duration `envconfig:"PRODUCTS_STREAM_IDLE_TIMEOUT" default:"10s"`
+}
+
+// errStreamIdle cancels stream, which did not read and write a product within StreamIdleTimeout.
+var errStreamIdle = fmt.Errorf("%w: no product streamed within idle timeout", context.DeadlineExceeded)
+
+type ProductControllerImpl struct {
+	Config      *ProductConfiguration
+	PageService service.PageService
+	Logger      *slog.Logger
+}
+
+func NewProductControllerFromEnv(pageService service.PageService, logger *slog.Logger) (*ProductControllerImpl, error) {
+	config, err := ProductConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewProductController(config, pageService, logger), nil
+}
+
+func ProductConfigurationFromEnv() (*ProductConfiguration, error) {
+	config := &ProductConfiguration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	if config.DefaultLimit < 1 || config.DefaultLimit > config.MaxLimit {
+		return nil, fmt.Errorf("invalid PRODUCTS_DEFAULT_LIMIT %v, expected from 1 to PRODUCTS_MAX_LIMIT %v", config.DefaultLimit, config.MaxLimit)
+	}
+	return config, nil
+}
+
+func NewProductController(config *ProductConfiguration, pageService service.PageService, logger *slog.Logger) *ProductControllerImpl {
+	return &ProductControllerImpl{
+		Config:      config,
+		PageService: pageService,
+		Logger:      logger,
+	}
+}
+
+// productQueryParameters are query parameters of paginated GET of products.
+var productQueryParameters = []string{"limit", "cursor", "sort", "name", "minPrice", "maxPrice", "count"}
+
+// IsProductsStream tells whether HandleProductsGet streams products for the request. Streams last as long as
+// there are products to write, so they are bounded by StreamIdleTimeout instead of request timeout.
+func IsProductsStream(request *http.Request) bool {
+	if hasAnyParameter(request, productQueryParameters) {
+		return false
+	}
+	stream, err := getStreamParameter(request)
+	if err != nil {
+		return false
+	}
+	encoder, _, _ := productsNegotiator.Negotiate(request.Header.Get("Accept"))
+	_, ndjson := encoder.(codec.NDJSONEncoder)
+	return stream || ndjson
+}
+
+// productsNegotiator selects representation of products: JSON array, or NDJSON which is always streamed.
+// Requests accepting neither get JSON array.
+var productsNegotiator = codec.NewNegotiator(codec.JSONEncoder{}, codec.NDJSONEncoder{})
+
+func (pc *ProductControllerImpl) HandleProductsGet(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, err := getPageIdFromRequest(request)
+	if err != nil {
+		logger.Info("invalid page id", "error", err)
+		handleBadRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId)
+	writer.Header().Add("Vary", "Accept")
+	encoder, mediaType, ok := productsNegotiator.Negotiate(request.Header.Get("Accept"))
+	if !ok {
+		encoder, mediaType = codec.JSONEncoder{}, codec.JSONEncoder{}.MediaTypes()[0]
+	}
+	_, ndjson := encoder.(codec.NDJSONEncoder)
+	stream, err := getStreamParameter(request)
+	if err == nil && stream && hasAnyParameter(request, productQueryParameters) {
+		err = fmt.Errorf("%w: stream can not be combined with pagination", model.ErrInvalidProductQuery)
+	}
+	if err != nil {
+		logger.Info("invalid products query", "error", err)
+		problem.Write(writer, request, logger, http.StatusBadRequest, problem.CodeInvalidQuery, err.Error())
+		return
+	}
+	switch {
+	case hasAnyParameter(request, productQueryParameters):
+		pc.handleProductsQuery(writer, request, logger, pageId, encoder, mediaType)
+		return
+	case stream || ndjson:
+		pc.handleProductsStream(writer, request, logger, pageId, ndjson)
+		return
+	}
+
+	list, err := pc.PageService.GetProducts(request.Context(), pageId)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	setNextLink(writer, request, list.Next)
+	writeJson(writer, request, logger, http.StatusOK, list.Products)
+}
+
+// setNextLink sets Link header to the next page of products, when there is one.
+func setNextLink(writer http.ResponseWriter, request *http.Request, next *model.ProductCursor) {
+	if next == nil {
+		return
+	}
+	parameters := request.URL.Query()
+	parameters.Set("cursor", next.Encode())
+	writer.Header().Set("Link", fmt.Sprintf(`<%v?%v>; rel="next"`, request.URL.Path, parameters.Encode()))
+}
+
+// handleProductsQuery responds with a page of products. Link to the next page is sent in Link header,
+// total number of matching products in X-Total-Count header when count parameter is true.
+func (pc *ProductControllerImpl) handleProductsQuery(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, pageId int,
+	encoder codec.Encoder, mediaType string) {
+	query, count, err := pc.getProductQueryFromRequest(request)
+	if err != nil {
+		logger.Info("invalid products query", "error", err)
+		problem.Write(writer, request, logger, http.StatusBadRequest, problem.CodeInvalidQuery, err.Error())
+		return
+	}
+
+	list, err := pc.PageService.QueryProducts(request.Context(), pageId, query, count)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	setNextLink(writer, request, list.Next)
+	if list.Total != nil {
+		writer.Header().Set("X-Total-Count", strconv.Itoa(*list.Total))
+	}
+	encoded, err := encoder.Encode(list.Products)
+	if err != nil {
+		logger.Error("failed to encode response", "error", err)
+		handleInternalServerError(writer, request, logger)
+		return
+	}
+	writeBody(writer, logger, mediaType, http.StatusOK, encoded)
+}
+
+// handleProductsStream writes products as they are read from the repository, so they are never all kept
+// in memory. Failure after the first product is written is reported in Stream-Error trailer, so the client
+// does not take truncated stream for the whole list. Reading stops when the client disconnects, as the request
+// context is cancelled and writes fail, or when no product is streamed within StreamIdleTimeout.
+func (pc *ProductControllerImpl) handleProductsStream(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, pageId int, ndjson bool) {
+	ctx, cancelFunc := context.WithCancelCause(request.Context())
+	defer cancelFunc(nil)
+	stream := newProductStream(writer, ndjson, pc.Config.StreamIdleTimeout)
+	write := stream.write
+	if idleTimeout := pc.Config.StreamIdleTimeout; idleTimeout > 0 {
+		idleTimer := time.AfterFunc(idleTimeout, func() { cancelFunc(errStreamIdle) })
+		defer idleTimer.Stop()
+		write = func(product *model.Product) error {
+			idleTimer.Reset(idleTimeout)
+			return stream.write(product)
+		}
+	}
+	err := pc.PageService.StreamProducts(ctx, pageId, write)
+	if err == nil {
+		err = stream.close()
+	}
+	if err != nil && ctx.Err() != nil && request.Context().Err() == nil {
+		err = context.Cause(ctx)
+	}
+	switch {
+	case err == nil:
+		logger.Debug("products streamed", "products", stream.written)
+	case !stream.started:
+		handleServiceError(writer, request, logger, err)
+	case request.Context().Err() != nil:
+		logger.Info("products stream cancelled by client", "outcome", "client_cancelled", "products", stream.written, "error", err)
+	default:
+		logger.Error("products stream truncated", "outcome", "stream_truncated", "products", stream.written, "error", err)
+		if err := stream.fail(err); err != nil {
+			logger.Info("failed to report truncated stream", "error", err)
+		}
+	}
+}
+
+func (pc *ProductControllerImpl) getProductQueryFromRequest(request *http.Request) (*model.ProductQuery, bool, error) {
+	parameters := request.URL.Query()
+	sort, err := model.ParseProductSort(parameters.Get("sort"))
+	if err != nil {
+		return nil, false, err
+	}
+	query := &model.ProductQuery{Limit: pc.Config.DefaultLimit, Sort: sort, NameContains: parameters.Get("name")}
+	if value := parameters.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit < 1 || query.Limit > pc.Config.MaxLimit {
+			return nil, false, fmt.Errorf("%w: limit must be a number from 1 to %v", model.ErrInvalidProductQuery, pc.Config.MaxLimit)
+		}
+	}
+	if value := parameters.Get("cursor"); value != "" {
+		if query.After, err = model.ParseProductCursor(value, sort); err != nil {
+			return nil, false, err
+		}
+	}
+	if query.MinPrice, err = getPriceParameter(parameters, "minPrice"); err != nil {
+		return nil, false, err
+	}
+	if query.MaxPrice, err = getPriceParameter(parameters, "maxPrice"); err != nil {
+		return nil, false, err
+	}
+	count := false
+	if value := parameters.Get("count"); value != "" {
+		if count, err = strconv.ParseBool(value); err != nil {
+			return nil, false, fmt.Errorf("%w: count must be true or false", model.ErrInvalidProductQuery)
+		}
+	}
+	return query, count, nil
+}
+
+func getStreamParameter(request *http.Request) (bool, error) {
+	value := request.URL.Query().Get("stream")
+	if value == "" {
+		return false, nil
+	}
+	stream, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: stream must be true or false", model.ErrInvalidProductQuery)
+	}
+	return stream, nil
+}
+
+func getPriceParameter(parameters url.Values, name string) (*float64, error) {
+	value := parameters.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(price) {
+		return nil, fmt.Errorf("%w: %v must be a number", model.ErrInvalidProductQuery, name)
+	}
+	return &price, nil
+}
+
+func hasAnyParameter(request *http.Request, names []string) bool {
+	parameters := request.URL.Query()
+	for _, name := range names {
+		if parameters.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pc *ProductControllerImpl) HandleProductsPost(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, err := getPageIdFromRequest(request)
+	if err != nil {
+		logger.Info("invalid page id", "error", err)
+		handleBadRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId)
+	product := &model.Product{}
+	if err := decodeBody(request, product); err != nil {
+		logger.Info("invalid request body", "error", err)
+		handleInvalidBody(writer, request, logger, err)
+		return
+	}
+
+	createdProduct, err := pc.PageService.CreateProduct(request.Context(), pageId, product)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	writer.Header().Set("Location", fmt.Sprintf("/pages/%v/products/%v", pageId, createdProduct.Id))
+	writeJson(writer, request, logger, http.StatusCreated, createdProduct)
+}
+
+func (pc *ProductControllerImpl) HandleProductGet(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, productId, err := getPageIdAndProductIdFromRequest(request)
+	if err != nil {
+		logger.Info("invalid page id or product id", "error", err)
+		handleBadProductRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId, "product_id", productId)
+
+	product, err := pc.PageService.GetProduct(request.Context(), pageId, productId)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	writeJson(writer, request, logger, http.StatusOK, product)
+}
+
+func (pc *ProductControllerImpl) HandleProductPut(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, productId, err := getPageIdAndProductIdFromRequest(request)
+	if err != nil {
+		logger.Info("invalid page id or product id", "error", err)
+		handleBadProductRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId, "product_id", productId)
+	product := &model.Product{}
+	if err := decodeBody(request, product); err != nil {
+		logger.Info("invalid request body", "error", err)
+		handleInvalidBody(writer, request, logger, err)
+		return
+	}
+	if product.Id == 0 {
+		product.Id = productId
+	}
+	if product.Id != productId {
+		handleInvalidBody(writer, request, logger, fmt.Errorf("Id %v does not match productId %v", product.Id, productId))
+		return
+	}
+
+	replacedProduct, err := pc.PageService.ReplaceProduct(request.Context(), pageId, product)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	writeJson(writer, request, logger, http.StatusOK, replacedProduct)
+}
+
+func (pc *ProductControllerImpl) HandleProductPatch(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, productId, err := getPageIdAndProductIdFromRequest(request)
+	if err != nil {
+		logger.Info("invalid page id or product id", "error", err)
+		handleBadProductRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId, "product_id", productId)
+	patch := model.ProductPatch{}
+	if err := decodeBody(request, &patch); err != nil {
+		logger.Info("invalid request body", "error", err)
+		handleInvalidBody(writer, request, logger, err)
+		return
+	}
+
+	patchedProduct, err := pc.PageService.PatchProduct(request.Context(), pageId, productId, patch)
+	if err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	writeJson(writer, request, logger, http.StatusOK, patchedProduct)
+}
+
+func (pc *ProductControllerImpl) HandleProductDelete(writer http.ResponseWriter, request *http.Request) {
+	logger := pc.requestLogger(request)
+	pageId, productId, err := getPageIdAndProductIdFromRequest(request)
+	if err != nil {
+		logger.Info("invalid page id or product id", "error", err)
+		handleBadProductRequest(writer, request, logger)
+		return
+	}
+	logger = logger.With("page_id", pageId, "product_id", productId)
+
+	if err := pc.PageService.DeleteProduct(request.Context(), pageId, productId); err != nil {
+		handleServiceError(writer, request, logger, err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (pc *ProductControllerImpl) requestLogger(request *http.Request) *slog.Logger {
+	return logging.FromContext(request.Context(), pc.Logger)
+}
+
+func getPageIdAndProductIdFromRequest(request *http.Request) (int, int, error) {
+	pageId, err := getPageIdFromRequest(request)
+	if err != nil {
+		return -1, -1, err
+	}
+	productId, err := strconv.Atoi(chi.URLParam(request, "productId"))
+	if err != nil {
+		return -1, -1, err
+	}
+	return pageId, productId, nil
+}
+
+func writeJson(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, status int, value interface{}) {
+	marshal, err := json.Marshal(value)
+	if err != nil {
+		logger.Error("failed to marshal response", "error", err)
+		handleInternalServerError(writer, request, logger)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if _, err := writer.Write(marshal); err != nil {
+		logger.Warn("failed to write response", "error", err)
+	}
+}
+
+func handleBadProductRequest(writer http.ResponseWriter, request *http.Request, logger *slog.Logger) {
+	problem.Write(writer, request, logger, http.StatusBadRequest, problem.CodeInvalidId, "Expected pageId and productId to be numbers")
+}
@@ -0,0 +1,520 @@
+package contoller
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
+	"github.com/remikj/pages-ms/src/service"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	sampleProductString = `{"Id":0,"PageId":0,"Name":"Sample product 0 name","Description":"Sample product 0 description","Price":2.5}`
+)
+
+func TestProductControllerImpl_HandleProductsGet_shouldQueryProducts_whenQueryParametersGiven(t *testing.T) {
+	minPrice := 2.0
+	next := &model.ProductCursor{Sort: model.SortByPrice, Id: 0, Price: 2.5}
+	var requestedQuery *model.ProductQuery
+	var requestedCount bool
+	pc := NewProductController(&ProductConfiguration{DefaultLimit: 20, MaxLimit: 100}, &pageServiceMock{
+		queryProductsFn: func(pageId int, query *model.ProductQuery, count bool) (*model.ProductList, error) {
+			requestedQuery, requestedCount = query, count
+			total := 2
+			return &model.ProductList{Products: sampleModelPage.Products[:1], Next: next, Total: &total}, nil
+		},
+	}, logging.Discard())
+	request := requestWithProductParams("GET", "0", "", "")
+	request.URL.RawQuery = "limit=1&sort=price&minPrice=2&name=Sample&count=true"
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandleProductsGet(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "["+sampleProductString+"]", responseRecorder.Body.String())
+	assert.Equal(t, &model.ProductQuery{Limit: 1, Sort: model.SortByPrice, NameContains: "Sample", MinPrice: &minPrice}, requestedQuery)
+	assert.True(t, requestedCount)
+	assert.Equal(t, "2", responseRecorder.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</pages/0/products/?count=true&cursor=`+next.Encode()+`&limit=1&minPrice=2&name=Sample&sort=price>; rel="next"`,
+		responseRecorder.Header().Get("Link"))
+}
+
+func TestProductControllerImpl_HandleProductsGet_shouldReturnBadRequest_whenQueryInvalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedDetail string
+	}{
+		{
+			name:           "should reject unknown sort",
+			query:          "sort=color",
+			expectedDetail: `invalid product query: unknown sort "color", expected id, price, -price or name`,
+		},
+		{
+			name:           "should reject limit above maximum",
+			query:          "limit=101",
+			expectedDetail: "invalid product query: limit must be a number from 1 to 100",
+		},
+		{
+			name:           "should reject malformed cursor",
+			query:          "cursor=not-a-cursor",
+			expectedDetail: "invalid product query: malformed cursor",
+		},
+		{
+			name:           "should reject cursor of other sort",
+			query:          "sort=name&cursor=" + (&model.ProductCursor{Sort: model.SortByPrice, Id: 1}).Encode(),
+			expectedDetail: `invalid product query: cursor was created for sort "price", not "name"`,
+		},
+		{
+			name:           "should reject price that is not a number",
+			query:          "maxPrice=cheap",
+			expectedDetail: "invalid product query: maxPrice must be a number",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := NewProductController(&ProductConfiguration{DefaultLimit: 20, MaxLimit: 100}, &pageServiceMock{}, logging.Discard())
+			request := requestWithProductParams("GET", "0", "", "")
+			request.URL.RawQuery = tt.query
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandleProductsGet(responseRecorder, request)
+
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+			assert.Equal(t, problemBody(http.StatusBadRequest, problem.CodeInvalidQuery, tt.expectedDetail), responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestProductControllerImpl_HandleProductsGet_shouldStreamProducts(t *testing.T) {
+	product1String := `{"Id":1,"PageId":0,"Name":"Sample product 1 name","Description":"Sample product 1 description","Price":19.99}`
+	tests := []struct {
+		name                string
+		query               string
+		accept              string
+		products            []model.Product
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "should write product per line, when NDJSON accepted",
+			accept:              "application/x-ndjson",
+			products:            sampleModelPage.Products,
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        sampleProductString + "\n" + product1String + "\n",
+		},
+		{
+			name:                "should write JSON array, when stream requested",
+			query:               "stream=true",
+			products:            sampleModelPage.Products,
+			expectedContentType: "application/json",
+			expectedBody:        "[" + sampleProductString + "," + product1String + "]",
+		},
+		{
+			name:                "should write empty JSON array, when page has no products",
+			query:               "stream=true",
+			expectedContentType: "application/json",
+			expectedBody:        "[]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := NewProductController(&ProductConfiguration{}, &pageServiceMock{
+				streamProductsFn: func(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+					for i := range tt.products {
+						if err := yield(&tt.products[i]); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			}, logging.Discard())
+			request := requestWithProductParams("GET", "0", "", "")
+			request.URL.RawQuery = tt.query
+			request.Header.Set("Accept", tt.accept)
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandleProductsGet(responseRecorder, request)
+
+			assert.Equal(t, http.StatusOK, responseRecorder.Code)
+			assert.Equal(t, tt.expectedContentType, responseRecorder.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+			assert.True(t, responseRecorder.Flushed)
+		})
+	}
+}
+
+func TestProductControllerImpl_HandleProductsGet_shouldRespondWithProblem_whenStreamFailsBeforeFirstProduct(t *testing.T) {
+	pc := NewProductController(&ProductConfiguration{}, &pageServiceMock{
+		streamProductsFn: func(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+			return model.ErrPageNotFound
+		},
+	}, logging.Discard())
+	request := requestWithProductParams("GET", "0", "", "")
+	request.URL.RawQuery = "stream=true"
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandleProductsGet(responseRecorder, request)
+
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	assert.Equal(t, "application/problem+json", responseRecorder.Header().Get("Content-Type"))
+}
+
+func TestProductControllerImpl_HandleProductsGet_shouldReportTruncatedStream_whenStreamFailsAfterFirstProduct(t *testing.T) {
+	tests := []struct {
+		name         string
+		accept       string
+		expectedBody string
+	}{
+		{name: "should leave NDJSON as it is", accept: "application/x-ndjson", expectedBody: sampleProductString + "\n"},
+		{name: "should leave JSON array unclosed", accept: "application/json", expectedBody: "[" + sampleProductString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := NewProductController(&ProductConfiguration{}, &pageServiceMock{
+				streamProductsFn: func(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+					if err := yield(&sampleModelPage.Products[0]); err != nil {
+						return err
+					}
+					return model.ErrBackendUnavailable
+				},
+			}, logging.Discard())
+			request := requestWithProductParams("GET", "0", "", "")
+			request.Header.Set("Accept", tt.accept)
+			request.URL.RawQuery = "stream=true"
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandleProductsGet(responseRecorder, request)
+
+			assert.Equal(t, http.StatusOK, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+			assert.Equal(t, string(problem.CodeBackendUnavailable), responseRecorder.Result().Trailer.Get("Stream-Error"))
+		})
+	}
+}
+
+func TestProductControllerImpl_HandleProductsGet_shouldStopStream_whenIdleTimeoutPasses(t *testing.T) {
+	pc := NewProductController(&ProductConfiguration{StreamIdleTimeout: 100 * time.Millisecond}, &pageServiceMock{
+		streamProductsFn: func(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+			for i := 0; i < 3; i++ {
+				time.Sleep(20 * time.Millisecond)
+				if err := yield(&sampleModelPage.Products[0]); err != nil {
+					return err
+				}
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}, logging.Discard())
+	request := requestWithProductParams("GET", "0", "", "")
+	request.Header.Set("Accept", "application/x-ndjson")
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandleProductsGet(responseRecorder, request)
+
+	assert.Equal(t, strings.Repeat(sampleProductString+"\n", 3), responseRecorder.Body.String(), "should not stop stream, which keeps writing")
+	assert.Equal(t, string(problem.CodeTimeout), responseRecorder.Result().Trailer.Get("Stream-Error"))
+}
+
+func TestIsProductsStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		accept   string
+		expected bool
+	}{
+		{name: "should stream, when NDJSON accepted", accept: "application/x-ndjson", expected: true},
+		{name: "should stream, when stream parameter is true", query: "stream=true", expected: true},
+		{name: "should not stream JSON without stream parameter", accept: "application/json", expected: false},
+		{name: "should not stream paginated products", query: "limit=10", accept: "application/x-ndjson", expected: false},
+		{name: "should not stream, when stream parameter is invalid", query: "stream=maybe", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/pages/1/products?"+tt.query, nil)
+			request.Header.Set("Accept", tt.accept)
+
+			assert.Equal(t, tt.expected, IsProductsStream(request))
+		})
+	}
+}
+
+func TestProductControllerImpl_HandleProductsGet_shouldReturnBadRequest_whenStreamCombinedWithPagination(t *testing.T) {
+	pc := NewProductController(&ProductConfiguration{DefaultLimit: 20, MaxLimit: 100}, &pageServiceMock{}, logging.Discard())
+	request := requestWithProductParams("GET", "0", "", "")
+	request.URL.RawQuery = "stream=true&limit=10"
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandleProductsGet(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, problemBody(http.StatusBadRequest, problem.CodeInvalidQuery,
+		"invalid product query: stream can not be combined with pagination"), responseRecorder.Body.String())
+}
+
+func TestProductConfigurationFromEnv_shouldReturnErr_whenDefaultLimitInvalid(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultLimit string
+		maxLimit     string
+	}{
+		{name: "should fail, when default limit is 0", defaultLimit: "0", maxLimit: "100"},
+		{name: "should fail, when max limit is 0", defaultLimit: "20", maxLimit: "0"},
+		{name: "should fail, when default limit exceeds max limit", defaultLimit: "20", maxLimit: "10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PRODUCTS_DEFAULT_LIMIT", tt.defaultLimit)
+			t.Setenv("PRODUCTS_MAX_LIMIT", tt.maxLimit)
+
+			_, err := ProductConfigurationFromEnv()
+
+			assert.ErrorContains(t, err, "invalid PRODUCTS_DEFAULT_LIMIT "+tt.defaultLimit)
+		})
+	}
+}
+
+func TestProductControllerImpl_HandleProductsGet(t *testing.T) {
+	tests := []struct {
+		name         string
+		pageService  service.PageService
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "should return products of page",
+			pageService: &pageServiceMock{
+				getProductsFn: func(pageId int) (*model.ProductList, error) {
+					return &model.ProductList{Products: sampleModelPage.Products[:1]}, nil
+				},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: "[" + sampleProductString + "]",
+		},
+		{
+			name: "should return not found, when page does not exist",
+			pageService: &pageServiceMock{
+				getProductsFn: func(pageId int) (*model.ProductList, error) { return nil, model.ErrPageNotFound },
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: problemBody(http.StatusNotFound, problem.CodeNotFound, "page not found"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := ProductControllerImpl{
+				PageService: tt.pageService,
+				Logger:      logging.Discard(),
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandleProductsGet(responseRecorder, requestWithProductParams("GET", "0", "", ""))
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestProductControllerImpl_HandleProductsGet_shouldLinkToNextProducts_whenPageProductsCapped(t *testing.T) {
+	next := model.CursorAfter(model.SortById, &sampleModelPage.Products[0])
+	pc := ProductControllerImpl{
+		PageService: &pageServiceMock{
+			getProductsFn: func(pageId int) (*model.ProductList, error) {
+				return &model.ProductList{Products: sampleModelPage.Products[:1], Next: next}, nil
+			},
+		},
+		Logger: logging.Discard(),
+	}
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandleProductsGet(responseRecorder, requestWithProductParams("GET", "0", "", ""))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, `</pages/0/products/?cursor=`+next.Encode()+`>; rel="next"`, responseRecorder.Header().Get("Link"))
+}
+
+func TestProductControllerImpl_HandleProductsPost(t *testing.T) {
+	tests := []struct {
+		name         string
+		pageService  service.PageService
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "should return created product",
+			pageService: &pageServiceMock{
+				createProductFn: func(pageId int, product *model.Product) (*model.Product, error) { return product, nil },
+			},
+			body:         sampleProductString,
+			expectedCode: http.StatusCreated,
+			expectedBody: sampleProductString,
+		},
+		{
+			name: "should return conflict, when product id already used in page",
+			pageService: &pageServiceMock{
+				createProductFn: func(pageId int, product *model.Product) (*model.Product, error) {
+					return nil, model.ErrProductAlreadyExists
+				},
+			},
+			body:         sampleProductString,
+			expectedCode: http.StatusConflict,
+			expectedBody: problemBody(http.StatusConflict, problem.CodeAlreadyExists, "product already exists"),
+		},
+		{
+			name:         "should return bad request, when body is not a product",
+			body:         `[]`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidBody, "Invalid request body: json: cannot unmarshal array into Go value of type model.Product"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := ProductControllerImpl{
+				PageService: tt.pageService,
+				Logger:      logging.Discard(),
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandleProductsPost(responseRecorder, requestWithProductParams("POST", "0", "", tt.body))
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestProductControllerImpl_HandleProductGet(t *testing.T) {
+	tests := []struct {
+		name         string
+		pageService  service.PageService
+		productId    string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "should return product",
+			pageService: &pageServiceMock{
+				getProductFn: func(pageId int, productId int) (*model.Product, error) { return &sampleModelPage.Products[0], nil },
+			},
+			productId:    "0",
+			expectedCode: http.StatusOK,
+			expectedBody: sampleProductString,
+		},
+		{
+			name:         "should return bad request, when productId is not a number",
+			productId:    "not-a-number",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: problemBody(http.StatusBadRequest, problem.CodeInvalidId, "Expected pageId and productId to be numbers"),
+		},
+		{
+			name: "should return not found, when product does not exist",
+			pageService: &pageServiceMock{
+				getProductFn: func(pageId int, productId int) (*model.Product, error) { return nil, model.ErrProductNotFound },
+			},
+			productId:    "0",
+			expectedCode: http.StatusNotFound,
+			expectedBody: problemBody(http.StatusNotFound, problem.CodeNotFound, "product not found"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := ProductControllerImpl{
+				PageService: tt.pageService,
+				Logger:      logging.Discard(),
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandleProductGet(responseRecorder, requestWithProductParams("GET", "0", tt.productId, ""))
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+			assert.Equal(t, tt.expectedBody, responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestProductControllerImpl_HandleProductPut_shouldReturnBadRequest_whenIdDiffersFromPath(t *testing.T) {
+	pc := ProductControllerImpl{Logger: logging.Discard()}
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandleProductPut(responseRecorder, requestWithProductParams("PUT", "0", "2", `{"Id": 3, "Name": "name"}`))
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, problemBody(http.StatusBadRequest, problem.CodeInvalidBody, "Invalid request body: Id 3 does not match productId 2"), responseRecorder.Body.String())
+}
+
+func TestProductControllerImpl_HandleProductPatch(t *testing.T) {
+	pc := ProductControllerImpl{
+		Logger: logging.Discard(),
+		PageService: &pageServiceMock{
+			patchProductFn: func(pageId int, productId int, patch model.ProductPatch) (*model.Product, error) {
+				product := sampleModelPage.Products[0]
+				product.ApplyPatch(patch)
+				return &product, nil
+			},
+		},
+	}
+	responseRecorder := httptest.NewRecorder()
+
+	pc.HandleProductPatch(responseRecorder, requestWithProductParams("PATCH", "0", "0", `{"Price": 3.5}`))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, strings.Replace(sampleProductString, "2.5", "3.5", 1), responseRecorder.Body.String())
+}
+
+func TestProductControllerImpl_HandleProductDelete(t *testing.T) {
+	tests := []struct {
+		name         string
+		deleteErr    error
+		expectedCode int
+	}{
+		{
+			name:         "should return no content, when product deleted",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "should return not found, when product does not exist",
+			deleteErr:    model.ErrProductNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "should return internal server error, when PageService fails",
+			deleteErr:    errors.New("PageService failed"),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := ProductControllerImpl{
+				Logger: logging.Discard(),
+				PageService: &pageServiceMock{
+					deleteProductFn: func(pageId int, productId int) error { return tt.deleteErr },
+				},
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			pc.HandleProductDelete(responseRecorder, requestWithProductParams("DELETE", "0", "0", ""))
+
+			assert.Equal(t, tt.expectedCode, responseRecorder.Code)
+		})
+	}
+}
+
+func requestWithProductParams(method string, pageId string, productId string, body string) *http.Request {
+	request := httptest.NewRequest(method, "/pages/"+pageId+"/products/"+productId, strings.NewReader(body))
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", pageId)
+	routeContext.URLParams.Add("productId", productId)
+
+	return request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeContext))
+}
@@ -0,0 +1,130 @@
+package contoller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/remikj/pages-ms/src/codec"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// streamFlushInterval is number of products written between flushes of the stream. The server sends
+	// buffered products once its buffer fills anyway, flushes only bound how long they can wait in it.
+	streamFlushInterval = 100
+	// streamErrorTrailer is trailer with problem code of the error, which truncated the stream.
+	streamErrorTrailer = "Stream-Error"
+)
+
+// productStream writes products to the response as they are read, as NDJSON lines or elements of JSON array.
+// Status and headers are written with the first product, so errors returned before it can still be
+// responded with a problem. Each write has to finish within idleTimeout, so slow clients can not hold the stream.
+type productStream struct {
+	writer      http.ResponseWriter
+	controller  *http.ResponseController
+	ndjson      bool
+	idleTimeout time.Duration
+	started     bool
+	written     int
+}
+
+func newProductStream(writer http.ResponseWriter, ndjson bool, idleTimeout time.Duration) *productStream {
+	return &productStream{writer: writer, controller: http.NewResponseController(writer), ndjson: ndjson, idleTimeout: idleTimeout}
+}
+
+// write sends the product, the first one is flushed right away, so the client gets headers without delay.
+func (s *productStream) write(product *model.Product) error {
+	var encoded []byte
+	var err error
+	if s.ndjson {
+		encoded, err = codec.EncodeNDJSONLine(product)
+	} else {
+		encoded, err = json.Marshal(product)
+		separator := byte(',')
+		if s.written == 0 {
+			separator = '['
+		}
+		encoded = append([]byte{separator}, encoded...)
+	}
+	if err != nil {
+		return err
+	}
+	s.start()
+	if s.idleTimeout > 0 {
+		if err := s.controller.SetWriteDeadline(time.Now().Add(s.idleTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+	if _, err := s.writer.Write(encoded); err != nil {
+		return err
+	}
+	s.written++
+	if s.written == 1 || s.written%streamFlushInterval == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+// close ends the stream, JSON array is closed, or written empty when there were no products.
+func (s *productStream) close() error {
+	s.start()
+	if !s.ndjson {
+		closing := "]"
+		if s.written == 0 {
+			closing = "[]"
+		}
+		if _, err := io.WriteString(s.writer, closing); err != nil {
+			return err
+		}
+	}
+	return s.flush()
+}
+
+// fail ends the stream truncated by err. Stream-Error trailer tells the client the list is not complete,
+// JSON array is also left unclosed.
+func (s *productStream) fail(err error) error {
+	s.writer.Header().Set(streamErrorTrailer, string(streamErrorCode(err)))
+	return s.flush()
+}
+
+func (s *productStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	mediaType := codec.JSONEncoder{}.MediaTypes()[0]
+	if s.ndjson {
+		mediaType = codec.NDJSONEncoder{}.MediaTypes()[0]
+	}
+	s.writer.Header().Set("Content-Type", mediaType)
+	s.writer.Header().Set("Trailer", streamErrorTrailer)
+	s.writer.WriteHeader(http.StatusOK)
+}
+
+// flush sends buffered products to the client, writers not able to flush keep them buffered.
+func (s *productStream) flush() error {
+	if err := s.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// streamErrorCode is code of the problem err would be responded with, when it happened before the stream started.
+func streamErrorCode(err error) problem.Code {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return problem.CodeTimeout
+	case errors.Is(err, model.ErrOverloaded):
+		return problem.CodeOverloaded
+	case errors.Is(err, model.ErrBackendUnavailable):
+		return problem.CodeBackendUnavailable
+	case errors.Is(err, model.ErrDataIntegrity):
+		return problem.CodeDataIntegrity
+	default:
+		return problem.CodeInternal
+	}
+}
@@ -0,0 +1,101 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/problem"
+	"github.com/remikj/pages-ms/src/service"
+	"log/slog"
+)
+
+type Configuration struct {
+	MaxDepth      int `envconfig:"GRAPHQL_MAX_DEPTH" default:"5"`
+	MaxComplexity int `envconfig:"GRAPHQL_MAX_COMPLEXITY" default:"1000"`
+	// MaxBatchSize is shared with HTTP API, so pages query accepts the same number of ids as GET /pages.
+	MaxBatchSize int `envconfig:"PAGE_BATCH_MAX_SIZE" default:"50"`
+}
+
+// Request is GraphQL request, sent as JSON body of POST or as query parameters of GET.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is GraphQL response. Data is omitted when the request could not be executed.
+type Response struct {
+	Data   interface{}                `json:"data,omitempty"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// Executor executes GraphQL requests against schema of pages, rejecting too deep or too complex queries.
+type Executor struct {
+	Config *Configuration
+	schema graphql.Schema
+}
+
+func NewExecutorFromEnv(pageService service.PageService, logger *slog.Logger) (*Executor, error) {
+	config, err := ConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewExecutor(config, pageService, logger)
+}
+
+func ConfigurationFromEnv() (*Configuration, error) {
+	config := &Configuration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func NewExecutor(config *Configuration, pageService service.PageService, logger *slog.Logger) (*Executor, error) {
+	schema, err := newSchema(&resolver{pageService: pageService, maxBatchSize: config.MaxBatchSize, logger: logger})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create graphql schema: %w", err)
+	}
+	return &Executor{Config: config, schema: schema}, nil
+}
+
+// Execute parses, validates and executes request. It returns false when the request was rejected before execution,
+// in which case response has only errors.
+func (e *Executor) Execute(ctx context.Context, request Request) (*Response, bool) {
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &Response{Errors: gqlerrors.FormatErrors(err)}, false
+	}
+	if validation := graphql.ValidateDocument(&e.schema, document, nil); !validation.IsValid {
+		return &Response{Errors: validation.Errors}, false
+	}
+	depth, complexity := measureOperation(&e.schema, document, request.OperationName, request.Variables)
+	if depth > e.Config.MaxDepth {
+		return rejected(fmt.Sprintf("query depth %v exceeds maximum depth %v", depth, e.Config.MaxDepth)), false
+	}
+	if complexity > e.Config.MaxComplexity {
+		return rejected(fmt.Sprintf("query complexity %v exceeds maximum complexity %v", complexity, e.Config.MaxComplexity)), false
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.schema,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       ctx,
+	})
+	return &Response{Data: result.Data, Errors: result.Errors}, result.Data != nil
+}
+
+func rejected(message string) *Response {
+	return &Response{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(&gqlerrors.Error{
+		Message:       message,
+		Locations:     []location.SourceLocation{},
+		OriginalError: newError(problem.CodeInvalidRequest, message),
+	})}}
+}
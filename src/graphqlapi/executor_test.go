@@ -0,0 +1,175 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var samplePage = model.Page{
+	SEO: model.SEO{PageId: 1, Title: "title1", Description: "description1", Robots: "robots1"},
+	Products: []model.Product{
+		{Id: 1, PageId: 1, Name: "Red shoes", Price: 10},
+		{Id: 2, PageId: 1, Name: "Blue shoes", Price: 20},
+		{Id: 3, PageId: 1, Name: "Red hat", Price: 30},
+	},
+}
+
+var cappedPage = model.Page{
+	SEO:          model.SEO{PageId: 2, Title: "title2"},
+	Products:     []model.Product{{Id: 1, PageId: 2, Name: "Green shoes", Price: 10}},
+	ProductsNext: model.CursorAfter(model.SortById, &model.Product{Id: 1, PageId: 2}),
+}
+
+func TestExecutor_Execute(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          Request
+		maxDepth         int
+		expectedResponse string
+		expectedExecuted bool
+	}{
+		{
+			name:             "should return only selected fields",
+			request:          Request{Query: `{ page(id: 1) { seo { title } } }`},
+			expectedResponse: `{"data":{"page":{"seo":{"title":"title1"}}}}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should filter products",
+			request:          Request{Query: `{ page(id: 1) { products(nameContains: "red", maxPrice: 25) { id name price } } }`},
+			expectedResponse: `{"data":{"page":{"products":[{"id":1,"name":"Red shoes","price":10}]}}}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should limit products",
+			request:          Request{Query: `query($first: Int) { page(id: 1) { products(minPrice: 15, first: $first) { id } } }`, Variables: map[string]interface{}{"first": 1.0}},
+			expectedResponse: `{"data":{"page":{"products":[{"id":2}]}}}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should return products with given ids",
+			request:          Request{Query: `{ page(id: 1) { products(ids: [3, 1]) { id } } }`},
+			expectedResponse: `{"data":{"page":{"products":[{"id":1},{"id":3}]}}}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should return cursor of the rest of products, when products are capped",
+			request:          Request{Query: `{ whole: page(id: 1) { productsCursor } capped: page(id: 2) { productsCursor } }`},
+			expectedResponse: `{"data":{"whole":{"productsCursor":null},"capped":{"productsCursor":"` + cappedPage.ProductsNext.Encode() + `"}}}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should return null, when page does not exist",
+			request:          Request{Query: `{ page(id: 9) { seo { title } } }`},
+			expectedResponse: `{"data":{"page":null}}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should return pages in requested order",
+			request:          Request{Query: `{ pages(ids: [9, 1]) { seo { pageId } } }`},
+			expectedResponse: `{"data":{"pages":[null,{"seo":{"pageId":1}}]}}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should return product",
+			request:          Request{Query: `{ product(pageId: 1, id: 2) { name } missing: product(pageId: 1, id: 9) { name } }`},
+			expectedResponse: `{"data":{"missing":null,"product":{"name":"Blue shoes"}}}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should return error with code, when backend is unavailable",
+			request:          Request{Query: `{ page(id: 5) { seo { title } } }`},
+			expectedResponse: `{"data":{"page":null},"errors":[{"message":"Backend is unavailable","locations":[{"line":1,"column":3}],"path":["page"],"extensions":{"code":"backend_unavailable"}}]}`,
+			expectedExecuted: true,
+		},
+		{
+			name:             "should reject too deep query",
+			request:          Request{Query: `{ page(id: 1) { ...seo } } fragment seo on Page { seo { title } }`},
+			maxDepth:         2,
+			expectedResponse: `{"errors":[{"message":"query depth 3 exceeds maximum depth 2","locations":[],"extensions":{"code":"invalid_request"}}]}`,
+		},
+		{
+			name:             "should reject too complex query",
+			request:          Request{Query: `{ pages(ids: [1, 2, 3]) { products { id name } } }`},
+			expectedResponse: `{"errors":[{"message":"query complexity 124 exceeds maximum complexity 100","locations":[],"extensions":{"code":"invalid_request"}}]}`,
+		},
+		{
+			name:             "should reject too complex query, when sibling field has negative list size",
+			request:          Request{Query: `{ page(id: 1) { products(first: -1000) { id } } pages(ids: [1, 2, 3]) { products { id name } } }`},
+			expectedResponse: `{"errors":[{"message":"query complexity 126 exceeds maximum complexity 100","locations":[],"extensions":{"code":"invalid_request"}}]}`,
+		},
+		{
+			name:             "should reject query with unknown field",
+			request:          Request{Query: `{ page(id: 1) { unknown } }`},
+			expectedResponse: `{"errors":[{"message":"Cannot query field \"unknown\" on type \"Page\".","locations":[{"line":1,"column":17}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{MaxDepth: 5, MaxComplexity: 100, MaxBatchSize: 10}
+			if tt.maxDepth != 0 {
+				config.MaxDepth = tt.maxDepth
+			}
+			executor := newTestExecutor(t, config)
+
+			response, executed := executor.Execute(context.Background(), tt.request)
+
+			assert.Equal(t, tt.expectedExecuted, executed)
+			body, err := json.Marshal(response)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expectedResponse, string(body))
+		})
+	}
+}
+
+func newTestExecutor(t *testing.T, config *Configuration) *Executor {
+	executor, err := NewExecutor(config, &pageServiceMock{}, logging.Discard())
+	require.NoError(t, err)
+	return executor
+}
+
+// pageServiceMock returns samplePage for page 1, cappedPage for page 2, fails for page 5 and returns nothing
+// for other pages.
+// Other methods of service.PageService are not used by resolvers and panic.
+type pageServiceMock struct {
+	service.PageService
+}
+
+func (p *pageServiceMock) GetPage(_ context.Context, pageId int) (*model.Page, error) {
+	switch pageId {
+	case 1:
+		return &samplePage, nil
+	case 2:
+		return &cappedPage, nil
+	case 5:
+		return nil, fmt.Errorf("%w: connection refused", model.ErrBackendUnavailable)
+	default:
+		return nil, nil
+	}
+}
+
+func (p *pageServiceMock) GetPages(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+	pages := map[int]*model.Page{}
+	for _, pageId := range pageIds {
+		if page, _ := p.GetPage(ctx, pageId); page != nil {
+			pages[pageId] = page
+		}
+	}
+	return pages, nil
+}
+
+func (p *pageServiceMock) GetProduct(_ context.Context, pageId int, productId int) (*model.Product, error) {
+	for _, product := range samplePage.Products {
+		if product.PageId == pageId && product.Id == productId {
+			return &product, nil
+		}
+	}
+	return nil, model.ErrProductNotFound
+}
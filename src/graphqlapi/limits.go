@@ -0,0 +1,171 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// assumedListSize is number of items assumed for lists without known size, e.g. products without first argument.
+const assumedListSize = 20
+
+// cost measures depth and complexity of an operation, to reject expensive queries before they are executed.
+// Depth is the longest chain of nested fields. Complexity is the number of fields resolved in the worst case:
+// every field costs 1 and fields selected on list items are counted once per item. Introspection fields are free,
+// as they do not touch pages.
+type cost struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// measureOperation returns depth and complexity of the operation with given name, or of the only operation
+// when name is empty. Document has to be validated first, so fragments are known and do not form cycles.
+func measureOperation(schema *graphql.Schema, document *ast.Document, operationName string, variables map[string]interface{}) (int, int) {
+	c := cost{schema: schema, fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return 0, 0
+	}
+	return c.measure(operation.SelectionSet, schema.QueryType())
+}
+
+func (c *cost) measure(selectionSet *ast.SelectionSet, parentType graphql.Type) (depth int, complexity int) {
+	if selectionSet == nil {
+		return 0, 0
+	}
+	for _, selection := range selectionSet.Selections {
+		var selectionDepth, selectionComplexity int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			selectionDepth, selectionComplexity = c.measureField(selection, parentType)
+		case *ast.InlineFragment:
+			selectionDepth, selectionComplexity = c.measure(selection.SelectionSet, c.fragmentType(selection.TypeCondition, parentType))
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[selection.Name.Value]; ok {
+				selectionDepth, selectionComplexity = c.measure(fragment.SelectionSet, c.fragmentType(fragment.TypeCondition, parentType))
+			}
+		}
+		depth = max(depth, selectionDepth)
+		complexity = saturatingAdd(complexity, selectionComplexity)
+	}
+	return depth, complexity
+}
+
+func (c *cost) measureField(field *ast.Field, parentType graphql.Type) (int, int) {
+	if strings.HasPrefix(field.Name.Value, "__") {
+		return 0, 0
+	}
+	object, ok := parentType.(*graphql.Object)
+	if !ok {
+		return 0, 0
+	}
+	definition, ok := object.Fields()[field.Name.Value]
+	if !ok {
+		return 0, 0
+	}
+	childDepth, childComplexity := c.measure(field.SelectionSet, namedType(definition.Type))
+	items := 1
+	if isList(definition.Type) {
+		items = c.listSize(field)
+	}
+	return 1 + childDepth, saturatingAdd(1, saturatingMul(items, childComplexity))
+}
+
+// listSize returns number of items of list field, taken from first or ids argument when given.
+// Negative sizes are counted as no items, so invalid arguments rejected later by resolvers can not lower
+// complexity of sibling fields.
+func (c *cost) listSize(field *ast.Field) int {
+	for _, name := range []string{"first", "ids"} {
+		for _, argument := range field.Arguments {
+			if argument.Name.Value == name {
+				if size, ok := c.valueSize(argument.Value); ok {
+					return max(size, 0)
+				}
+			}
+		}
+	}
+	return assumedListSize
+}
+
+// valueSize returns value of integer or length of list argument, which may be given as variable.
+func (c *cost) valueSize(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		size, err := strconv.Atoi(value.Value)
+		return size, err == nil
+	case *ast.ListValue:
+		return len(value.Values), true
+	case *ast.Variable:
+		switch variable := c.variables[value.Name.Value].(type) {
+		case float64:
+			// JSON numbers are clamped, conversion of floats out of int range is undefined.
+			return int(math.Max(math.Min(variable, math.MaxInt32), math.MinInt32)), true
+		case int:
+			return variable, true
+		case []interface{}:
+			return len(variable), true
+		}
+	}
+	return 0, false
+}
+
+// saturatingAdd and saturatingMul stop at math.MaxInt instead of overflowing, so huge sizes of lists
+// can not wrap complexity around below the limit. Arguments are never negative.
+func saturatingAdd(a int, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func saturatingMul(a int, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
+
+func (c *cost) fragmentType(typeCondition *ast.Named, parentType graphql.Type) graphql.Type {
+	if typeCondition == nil {
+		return parentType
+	}
+	if fragmentType := c.schema.Type(typeCondition.Name.Value); fragmentType != nil {
+		return fragmentType
+	}
+	return parentType
+}
+
+// namedType unwraps non null and list wrappers of fieldType.
+func namedType(fieldType graphql.Type) graphql.Type {
+	for {
+		switch wrapper := fieldType.(type) {
+		case *graphql.NonNull:
+			fieldType = wrapper.OfType
+		case *graphql.List:
+			fieldType = wrapper.OfType
+		default:
+			return fieldType
+		}
+	}
+}
+
+func isList(fieldType graphql.Type) bool {
+	if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+		fieldType = nonNull.OfType
+	}
+	_, ok := fieldType.(*graphql.List)
+	return ok
+}
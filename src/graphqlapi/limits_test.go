@@ -0,0 +1,78 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestMeasureOperation(t *testing.T) {
+	tests := []struct {
+		name               string
+		query              string
+		operationName      string
+		variables          map[string]interface{}
+		expectedDepth      int
+		expectedComplexity int
+	}{
+		{
+			name:               "should count each field once, when there are no lists",
+			query:              `{ page(id: 1) { seo { title robots } } }`,
+			expectedDepth:      3,
+			expectedComplexity: 4,
+		},
+		{
+			name:               "should count fields of list items for assumed number of items",
+			query:              `{ page(id: 1) { products { id } } }`,
+			expectedDepth:      3,
+			expectedComplexity: 2 + assumedListSize,
+		},
+		{
+			name:               "should count fields of list items for number of items given in variables",
+			query:              `query($ids: [Int!]!, $first: Int) { pages(ids: $ids) { products(first: $first) { id } } }`,
+			variables:          map[string]interface{}{"ids": []interface{}{1.0, 2.0}, "first": 3.0},
+			expectedDepth:      3,
+			expectedComplexity: 1 + 2*(1+3),
+		},
+		{
+			name:               "should count no items, when list size is negative",
+			query:              `query($first: Int) { page(id: 1) { products(first: -1000) { id } } pages(ids: [1]) { products(first: $first) { id } } }`,
+			variables:          map[string]interface{}{"first": -1000.0},
+			expectedDepth:      3,
+			expectedComplexity: 4,
+		},
+		{
+			name:               "should stop at maximum int, when list size overflows complexity",
+			query:              `{ page(id: 1) { products(first: 9223372036854775807) { id name } } }`,
+			expectedDepth:      3,
+			expectedComplexity: math.MaxInt,
+		},
+		{
+			name:               "should measure fragments and selected operation",
+			query:              `query A { __typename } query B { page(id: 1) { ... on Page { ...seo } } } fragment seo on Page { seo { title } }`,
+			operationName:      "B",
+			expectedDepth:      3,
+			expectedComplexity: 3,
+		},
+		{
+			name:               "should not count introspection",
+			query:              `{ __schema { types { name fields { name } } } }`,
+			expectedDepth:      0,
+			expectedComplexity: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := newTestExecutor(t, &Configuration{})
+			document, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			require.NoError(t, err)
+
+			depth, complexity := measureOperation(&executor.schema, document, tt.operationName, tt.variables)
+
+			assert.Equal(t, tt.expectedDepth, depth)
+			assert.Equal(t, tt.expectedComplexity, complexity)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
+	"github.com/remikj/pages-ms/src/service"
+	"log/slog"
+	"strings"
+)
+
+// resolver resolves root fields with service.PageService. Fields of pages, seos and products
+// are resolved by graphql.DefaultResolveFn from fields of model types with the same names.
+type resolver struct {
+	pageService  service.PageService
+	maxBatchSize int
+	logger       *slog.Logger
+}
+
+var seoType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "SEO",
+	Description: "SEO data of the page",
+	Fields: graphql.Fields{
+		"pageId":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"title":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"robots":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var productType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Product",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"pageId":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"price":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var pageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Page",
+	Fields: graphql.Fields{
+		"seo": &graphql.Field{Type: graphql.NewNonNull(seoType)},
+		"products": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productType))),
+			Description: "Products of the page with the lowest ids, at most PAGE_EMBEDDED_PRODUCTS_LIMIT of them, narrowed by all given arguments",
+			Args: graphql.FieldConfigArgument{
+				"ids":          &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int)), Description: "Only products with given ids"},
+				"nameContains": &graphql.ArgumentConfig{Type: graphql.String, Description: "Only products with name containing given text, case insensitive"},
+				"minPrice":     &graphql.ArgumentConfig{Type: graphql.Float, Description: "Only products with price greater or equal"},
+				"maxPrice":     &graphql.ArgumentConfig{Type: graphql.Float, Description: "Only products with price less or equal"},
+				"first":        &graphql.ArgumentConfig{Type: graphql.Int, Description: "At most given number of matching products"},
+			},
+			Resolve: resolveProducts,
+		},
+		"productsCursor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Cursor of the rest of products, for GET /pages/{id}/products?cursor=, null when all products are returned",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if next := p.Source.(*model.Page).ProductsNext; next != nil {
+					return next.Encode(), nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+func newSchema(r *resolver) (graphql.Schema, error) {
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"page": &graphql.Field{
+					Type:        pageType,
+					Description: "Page with given id, null when it does not exist",
+					Args: graphql.FieldConfigArgument{
+						"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					},
+					Resolve: r.resolvePage,
+				},
+				"pages": &graphql.Field{
+					Type:        graphql.NewList(pageType),
+					Description: "Pages with given ids in requested order, null for pages that do not exist",
+					Args: graphql.FieldConfigArgument{
+						"ids": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.Int)))},
+					},
+					Resolve: r.resolvePages,
+				},
+				"product": &graphql.Field{
+					Type:        productType,
+					Description: "Product of the page, null when it does not exist",
+					Args: graphql.FieldConfigArgument{
+						"pageId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+						"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					},
+					Resolve: r.resolveProduct,
+				},
+			},
+		}),
+	})
+}
+
+func (r *resolver) resolvePage(p graphql.ResolveParams) (interface{}, error) {
+	pageId := p.Args["id"].(int)
+	page, err := r.pageService.GetPage(p.Context, pageId)
+	if err != nil {
+		return nil, r.resolveError(p.Context, err, "page_id", pageId)
+	}
+	if page == nil {
+		return nil, nil
+	}
+	return page, nil
+}
+
+func (r *resolver) resolvePages(p graphql.ResolveParams) (interface{}, error) {
+	pageIds := intList(p.Args["ids"])
+	if len(pageIds) > r.maxBatchSize {
+		return nil, newError(problem.CodeInvalidId, fmt.Sprintf("too many ids, at most %v can be requested", r.maxBatchSize))
+	}
+	pages, err := r.pageService.GetPages(p.Context, pageIds)
+	if err != nil {
+		return nil, r.resolveError(p.Context, err, "page_ids", pageIds)
+	}
+	results := make([]interface{}, 0, len(pageIds))
+	for _, pageId := range pageIds {
+		if page := pages[pageId]; page != nil {
+			results = append(results, page)
+		} else {
+			results = append(results, nil)
+		}
+	}
+	return results, nil
+}
+
+func (r *resolver) resolveProduct(p graphql.ResolveParams) (interface{}, error) {
+	pageId, productId := p.Args["pageId"].(int), p.Args["id"].(int)
+	product, err := r.pageService.GetProduct(p.Context, pageId, productId)
+	if errors.Is(err, model.ErrProductNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, r.resolveError(p.Context, err, "page_id", pageId, "product_id", productId)
+	}
+	return product, nil
+}
+
+func resolveProducts(p graphql.ResolveParams) (interface{}, error) {
+	page := p.Source.(*model.Page)
+	if page.Partial {
+		return nil, newError(problem.CodeBackendUnavailable, "Products of the page are unavailable")
+	}
+	ids, filterIds := p.Args["ids"]
+	nameContains, filterName := p.Args["nameContains"].(string)
+	minPrice, filterMinPrice := p.Args["minPrice"].(float64)
+	maxPrice, filterMaxPrice := p.Args["maxPrice"].(float64)
+	first, limited := p.Args["first"].(int)
+	if limited && first < 0 {
+		return nil, newError(problem.CodeInvalidRequest, "first must not be negative")
+	}
+	idSet := make(map[int]bool)
+	for _, id := range intList(ids) {
+		idSet[id] = true
+	}
+
+	products := make([]model.Product, 0, len(page.Products))
+	for _, product := range page.Products {
+		if limited && len(products) == first {
+			break
+		}
+		switch {
+		case filterIds && !idSet[product.Id]:
+		case filterName && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(nameContains)):
+		case filterMinPrice && product.Price < minPrice:
+		case filterMaxPrice && product.Price > maxPrice:
+		default:
+			products = append(products, product)
+		}
+	}
+	return products, nil
+}
+
+func intList(value interface{}) []int {
+	values, _ := value.([]interface{})
+	ints := make([]int, 0, len(values))
+	for _, v := range values {
+		ints = append(ints, v.(int))
+	}
+	return ints
+}
+
+// resolveError logs err of PageService and converts it to error with the same code
+// as the one returned by HTTP API, so clients can handle both the same way.
+func (r *resolver) resolveError(ctx context.Context, err error, attributes ...any) error {
+	logger := logging.FromContext(ctx, r.logger).With(attributes...)
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		err = fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn("request timed out", "outcome", "timeout", "error", err)
+		return newError(problem.CodeTimeout, "Request timed out")
+	case errors.Is(err, context.Canceled):
+		logger.Info("request cancelled by client", "outcome", "client_cancelled", "error", err)
+		return newError(problem.CodeInternal, "Request cancelled")
+	case errors.Is(err, model.ErrOverloaded):
+		logger.Warn("service overloaded", "outcome", "overloaded", "error", err)
+		return newError(problem.CodeOverloaded, "Service is overloaded, retry later")
+	case errors.Is(err, model.ErrBackendUnavailable):
+		logger.Error("backend unavailable", "error", err)
+		return newError(problem.CodeBackendUnavailable, "Backend is unavailable")
+	case errors.Is(err, model.ErrDataIntegrity):
+		logger.Error("data integrity violation", "error", err)
+		return newError(problem.CodeDataIntegrity, "Stored data is inconsistent")
+	default:
+		logger.Error("unexpected error", "error", err)
+		return newError(problem.CodeInternal, "Unexpected error")
+	}
+}
+
+// Error is GraphQL error with code in extensions, codes are the same as codes of problems returned by HTTP API.
+type Error struct {
+	Code    problem.Code
+	Message string
+}
+
+func newError(code problem.Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
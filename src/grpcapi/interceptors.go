@@ -0,0 +1,66 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	grpcRequestsTotal = metrics.NewCounterVec(
+		"grpc_requests_total",
+		"Total number of gRPC requests by method and code.",
+		"method", "code",
+	)
+	grpcRequestDuration = metrics.NewHistogramVec(
+		"grpc_request_duration_seconds",
+		"Duration of gRPC requests in seconds by method and code.",
+		metrics.DefaultBuckets,
+		"method", "code",
+	)
+)
+
+// requestIdKey is metadata key of request id, the same as header used for HTTP requests.
+var requestIdKey = strings.ToLower(middleware.RequestIDHeader)
+
+// loggingInterceptor passes logger with request id to handlers through context and logs every handled request
+// with its code and latency. Request id is taken from metadata of the call, or generated when it is missing.
+func loggingInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		requestId := fmt.Sprintf("grpc-%06d", middleware.NextRequestID())
+		if values := metadata.ValueFromIncomingContext(ctx, requestIdKey); len(values) > 0 && values[0] != "" {
+			requestId = values[0]
+		}
+		requestLogger := logger.With("request_id", requestId)
+
+		response, err := handler(logging.WithLogger(ctx, requestLogger), request)
+
+		requestLogger.Info("request handled",
+			"method", info.FullMethod,
+			"code", status.Code(err).String(),
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+		)
+		return response, err
+	}
+}
+
+// metricsInterceptor records count and duration of requests, labeled with full method name and status code.
+func metricsInterceptor(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	response, err := handler(ctx, request)
+
+	code := status.Code(err).String()
+	grpcRequestsTotal.Inc(info.FullMethod, code)
+	grpcRequestDuration.Observe(time.Since(start).Seconds(), info.FullMethod, code)
+	return response, err
+}
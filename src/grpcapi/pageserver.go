@@ -0,0 +1,144 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/pagespb"
+	"github.com/remikj/pages-ms/src/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+// Pages returned without products are marked with header metadata, the same as Warning header of HTTP API.
+const (
+	partialPageHeader  = "warning"
+	partialPageWarning = `199 pages-ms "Products are unavailable, page is returned without them"`
+	// productsNextHeader is cursor of products following those returned with the page, of GetPages
+	// prefixed with id of the page and a space.
+	productsNextHeader = "products-next"
+)
+
+// PageServer implements pagespb.PageServiceServer with service.PageService, the same way page controller does for HTTP.
+type PageServer struct {
+	pagespb.UnimplementedPageServiceServer
+	PageService  service.PageService
+	MaxBatchSize int
+	Logger       *slog.Logger
+}
+
+func NewPageServer(pageService service.PageService, maxBatchSize int, logger *slog.Logger) *PageServer {
+	return &PageServer{
+		PageService:  pageService,
+		MaxBatchSize: maxBatchSize,
+		Logger:       logger,
+	}
+}
+
+func (s *PageServer) GetPage(ctx context.Context, request *pagespb.GetPageRequest) (*pagespb.Page, error) {
+	logger := logging.FromContext(ctx, s.Logger).With("page_id", request.GetPageId())
+	page, err := s.PageService.GetPage(ctx, int(request.GetPageId()))
+	if err != nil {
+		return nil, statusFromError(ctx, logger, err)
+	}
+	if page == nil {
+		logger.Info("page not found")
+		return nil, status.Error(codes.NotFound, model.ErrPageNotFound.Error())
+	}
+	if page.Partial {
+		// header can not be set outside of a gRPC call, e.g. in tests, the page is returned anyway.
+		_ = grpc.SetHeader(ctx, metadata.Pairs(partialPageHeader, partialPageWarning))
+	}
+	if page.ProductsNext != nil {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(productsNextHeader, page.ProductsNext.Encode()))
+	}
+	return pagespb.FromPage(page), nil
+}
+
+// GetPages returns pages in requested order. Duplicated ids are returned once, at most MaxBatchSize ids can be requested.
+func (s *PageServer) GetPages(ctx context.Context, request *pagespb.GetPagesRequest) (*pagespb.GetPagesResponse, error) {
+	logger := logging.FromContext(ctx, s.Logger)
+	pageIds := uniquePageIds(request.GetPageIds())
+	if len(pageIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "expected at least one page id")
+	}
+	if len(pageIds) > s.MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "too many ids, at most %v can be requested", s.MaxBatchSize)
+	}
+	logger = logger.With("page_ids", pageIds)
+
+	pages, err := s.PageService.GetPages(ctx, pageIds)
+	if err != nil {
+		return nil, statusFromError(ctx, logger, err)
+	}
+
+	response := &pagespb.GetPagesResponse{Pages: make([]*pagespb.PageResult, 0, len(pageIds))}
+	for _, pageId := range pageIds {
+		result := &pagespb.PageResult{PageId: int64(pageId), Status: pagespb.PageResult_NOT_FOUND}
+		if page, ok := pages[pageId]; ok && page != nil {
+			result.Status = pagespb.PageResult_FOUND
+			result.Page = pagespb.FromPage(page)
+			if page.ProductsNext != nil {
+				_ = grpc.SetHeader(ctx, metadata.Pairs(productsNextHeader, fmt.Sprintf("%v %v", pageId, page.ProductsNext.Encode())))
+			}
+		}
+		response.Pages = append(response.Pages, result)
+	}
+	return response, nil
+}
+
+func uniquePageIds(ids []int64) []int {
+	pageIds := make([]int, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		pageIds = append(pageIds, int(id))
+	}
+	return pageIds
+}
+
+// statusFromError maps err of PageService to gRPC status, as handleServiceError of controllers maps it to HTTP status.
+// Errors caused by context being done are reported as deadline exceeded or cancellation,
+// even when the repository does not wrap context error.
+func statusFromError(ctx context.Context, logger *slog.Logger, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		err = fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn("request timed out", "outcome", "timeout", "error", err)
+		return status.Error(codes.DeadlineExceeded, "request timed out")
+	case errors.Is(err, context.Canceled):
+		logger.Info("request cancelled by client", "outcome", "client_cancelled", "error", err)
+		return status.Error(codes.Canceled, "request cancelled")
+	case errors.Is(err, model.ErrInvalidPage), errors.Is(err, model.ErrInvalidProduct):
+		logger.Info("invalid request", "error", err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, model.ErrPageNotFound), errors.Is(err, model.ErrProductNotFound):
+		logger.Info("result not found", "error", err)
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, model.ErrPageAlreadyExists), errors.Is(err, model.ErrProductAlreadyExists):
+		logger.Info("conflict", "error", err)
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, model.ErrOverloaded):
+		logger.Warn("service overloaded", "outcome", "overloaded", "error", err)
+		return status.Error(codes.Unavailable, "service is overloaded, retry later")
+	case errors.Is(err, model.ErrBackendUnavailable):
+		logger.Error("backend unavailable", "error", err)
+		return status.Error(codes.Unavailable, "backend is unavailable")
+	case errors.Is(err, model.ErrDataIntegrity):
+		logger.Error("data integrity violation", "error", err)
+		return status.Error(codes.Internal, "stored data is inconsistent")
+	default:
+		logger.Error("unexpected error", "error", err)
+		return status.Error(codes.Internal, "unexpected error")
+	}
+}
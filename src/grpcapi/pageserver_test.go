@@ -0,0 +1,154 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/pagespb"
+	"github.com/remikj/pages-ms/src/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+var samplePage = model.Page{
+	SEO:      model.SEO{PageId: 1, Title: "title1", Description: "description1", Robots: "robots1"},
+	Products: []model.Product{{Id: 2, PageId: 1, Name: "name2", Description: "description2", Price: 20.99}},
+}
+
+func TestPageServer_GetPage(t *testing.T) {
+	tests := []struct {
+		name         string
+		getPageFn    func(ctx context.Context, pageId int) (*model.Page, error)
+		expectedPage *pagespb.Page
+		expectedCode codes.Code
+	}{
+		{
+			name:         "should return page, when page exists",
+			getPageFn:    func(ctx context.Context, pageId int) (*model.Page, error) { return &samplePage, nil },
+			expectedPage: pagespb.FromPage(&samplePage),
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "should return not found, when page does not exist",
+			getPageFn:    func(ctx context.Context, pageId int) (*model.Page, error) { return nil, nil },
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "should return unavailable, when backend is unavailable",
+			getPageFn: func(ctx context.Context, pageId int) (*model.Page, error) {
+				return nil, fmt.Errorf("%w: connection refused", model.ErrBackendUnavailable)
+			},
+			expectedCode: codes.Unavailable,
+		},
+		{
+			name: "should return unavailable, when service is overloaded",
+			getPageFn: func(ctx context.Context, pageId int) (*model.Page, error) {
+				return nil, &model.OverloadedError{RetryAfter: time.Second}
+			},
+			expectedCode: codes.Unavailable,
+		},
+		{
+			name: "should return deadline exceeded, when PageService exceeds deadline",
+			getPageFn: func(ctx context.Context, pageId int) (*model.Page, error) {
+				return nil, fmt.Errorf("failed to find seo: %w", context.DeadlineExceeded)
+			},
+			expectedCode: codes.DeadlineExceeded,
+		},
+		{
+			name: "should return internal, when stored data is inconsistent",
+			getPageFn: func(ctx context.Context, pageId int) (*model.Page, error) {
+				return nil, fmt.Errorf("%w: too many results", model.ErrDataIntegrity)
+			},
+			expectedCode: codes.Internal,
+		},
+		{
+			name: "should return internal, when PageService fails",
+			getPageFn: func(ctx context.Context, pageId int) (*model.Page, error) {
+				return nil, errors.New("PageService failed")
+			},
+			expectedCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPageServer(&pageServiceMock{getPageFn: tt.getPageFn}, 10, logging.Discard())
+
+			page, err := s.GetPage(context.Background(), &pagespb.GetPageRequest{PageId: 1})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			assert.Equal(t, tt.expectedPage, page)
+		})
+	}
+}
+
+func TestPageServer_GetPage_shouldReturnCanceled_whenContextCancelled(t *testing.T) {
+	s := NewPageServer(&pageServiceMock{
+		getPageFn: func(ctx context.Context, pageId int) (*model.Page, error) {
+			return nil, errors.New("connection closed")
+		},
+	}, 10, logging.Discard())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.GetPage(ctx, &pagespb.GetPageRequest{PageId: 1})
+
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
+
+func TestPageServer_GetPages(t *testing.T) {
+	var requestedIds []int
+	s := NewPageServer(&pageServiceMock{
+		getPagesFn: func(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+			requestedIds = pageIds
+			return map[int]*model.Page{1: &samplePage}, nil
+		},
+	}, 2, logging.Discard())
+
+	response, err := s.GetPages(context.Background(), &pagespb.GetPagesRequest{PageIds: []int64{9, 1, 9}})
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{9, 1}, requestedIds)
+	require.Len(t, response.GetPages(), 2)
+	assert.Equal(t, &pagespb.PageResult{PageId: 9, Status: pagespb.PageResult_NOT_FOUND}, response.GetPages()[0])
+	assert.Equal(t, &pagespb.PageResult{PageId: 1, Status: pagespb.PageResult_FOUND, Page: pagespb.FromPage(&samplePage)}, response.GetPages()[1])
+}
+
+func TestPageServer_GetPages_shouldReturnInvalidArgument_whenIdsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		pageIds []int64
+	}{
+		{name: "should reject empty ids", pageIds: nil},
+		{name: "should reject too many ids", pageIds: []int64{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPageServer(&pageServiceMock{}, 2, logging.Discard())
+
+			_, err := s.GetPages(context.Background(), &pagespb.GetPagesRequest{PageIds: tt.pageIds})
+
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+// pageServiceMock implements methods used by PageServer, other methods of service.PageService panic.
+type pageServiceMock struct {
+	service.PageService
+	getPageFn  func(ctx context.Context, pageId int) (*model.Page, error)
+	getPagesFn func(ctx context.Context, pageIds []int) (map[int]*model.Page, error)
+}
+
+func (p *pageServiceMock) GetPage(ctx context.Context, pageId int) (*model.Page, error) {
+	return p.getPageFn(ctx, pageId)
+}
+
+func (p *pageServiceMock) GetPages(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+	return p.getPagesFn(ctx, pageIds)
+}
@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/pagespb"
+	"github.com/remikj/pages-ms/src/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"log/slog"
+	"net"
+)
+
+type Configuration struct {
+	Port int `envconfig:"GRPC_PORT" default:"9090"`
+	// MaxBatchSize is shared with HTTP API, so both accept the same number of ids.
+	MaxBatchSize int `envconfig:"PAGE_BATCH_MAX_SIZE" default:"50"`
+}
+
+// Server serves pagespb.PageService and the standard gRPC health service.
+type Server struct {
+	Config     *Configuration
+	Logger     *slog.Logger
+	grpcServer *grpc.Server
+	health     *health.Server
+}
+
+func NewServerFromEnv(pageService service.PageService, logger *slog.Logger) (*Server, error) {
+	config, err := ConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewServer(config, pageService, logger), nil
+}
+
+func ConfigurationFromEnv() (*Configuration, error) {
+	config := &Configuration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func NewServer(config *Configuration, pageService service.PageService, logger *slog.Logger) *Server {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(loggingInterceptor(logger), metricsInterceptor))
+	healthServer := health.NewServer()
+	pagespb.RegisterPageServiceServer(grpcServer, NewPageServer(pageService, config.MaxBatchSize, logger))
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	healthServer.SetServingStatus(pagespb.PageService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return &Server{
+		Config:     config,
+		Logger:     logger,
+		grpcServer: grpcServer,
+		health:     healthServer,
+	}
+}
+
+// Listen opens listener on configured port.
+func (s *Server) Listen() (net.Listener, error) {
+	return net.Listen("tcp", fmt.Sprintf(":%v", s.Config.Port))
+}
+
+// Serve serves requests from listener until Shutdown or Stop is called, then it returns nil.
+func (s *Server) Serve(listener net.Listener) error {
+	return s.grpcServer.Serve(listener)
+}
+
+// Shutdown reports the services as not serving, stops accepting new connections and waits for in-flight requests
+// until ctx is done. Requests still running after that are cancelled.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// Stop closes all connections immediately, cancelling in-flight requests.
+func (s *Server) Stop() {
+	s.grpcServer.Stop()
+}
@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"context"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/pagespb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConfigurationFromEnv_shouldReadPort(t *testing.T) {
+	t.Setenv("GRPC_PORT", "9191")
+
+	config, err := ConfigurationFromEnv()
+
+	require.NoError(t, err)
+	assert.Equal(t, 9191, config.Port)
+	assert.Equal(t, 50, config.MaxBatchSize)
+}
+
+func TestServer_shouldServePagesAndHealth(t *testing.T) {
+	server := NewServer(&Configuration{MaxBatchSize: 10}, &pageServiceMock{
+		getPageFn: func(ctx context.Context, pageId int) (*model.Page, error) { return &samplePage, nil },
+	}, logging.Discard())
+	connection := dialTestServer(t, server)
+	ctx := context.Background()
+
+	page, pageErr := pagespb.NewPageServiceClient(connection).GetPage(ctx, &pagespb.GetPageRequest{PageId: 1})
+	healthClient := healthpb.NewHealthClient(connection)
+	overall, overallErr := healthClient.Check(ctx, &healthpb.HealthCheckRequest{})
+	pages, pagesErr := healthClient.Check(ctx, &healthpb.HealthCheckRequest{Service: "pages.PageService"})
+
+	require.NoError(t, pageErr)
+	assert.Equal(t, "title1", page.GetSeo().GetTitle())
+	require.NoError(t, overallErr)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, overall.GetStatus())
+	require.NoError(t, pagesErr)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, pages.GetStatus())
+}
+
+func TestServer_GetPage_shouldSendProductsNextHeader_whenProductsCapped(t *testing.T) {
+	next := model.CursorAfter(model.SortById, &samplePage.Products[0])
+	cappedPage := samplePage
+	cappedPage.ProductsNext = next
+	server := NewServer(&Configuration{MaxBatchSize: 10}, &pageServiceMock{
+		getPageFn: func(ctx context.Context, pageId int) (*model.Page, error) { return &cappedPage, nil },
+		getPagesFn: func(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+			return map[int]*model.Page{1: &cappedPage, 3: &cappedPage}, nil
+		},
+	}, logging.Discard())
+	client := pagespb.NewPageServiceClient(dialTestServer(t, server))
+	var pageHeader, pagesHeader metadata.MD
+
+	_, pageErr := client.GetPage(context.Background(), &pagespb.GetPageRequest{PageId: 1}, grpc.Header(&pageHeader))
+	_, pagesErr := client.GetPages(context.Background(), &pagespb.GetPagesRequest{PageIds: []int64{1, 3}}, grpc.Header(&pagesHeader))
+
+	require.NoError(t, pageErr)
+	require.NoError(t, pagesErr)
+	assert.Equal(t, []string{next.Encode()}, pageHeader.Get(productsNextHeader))
+	assert.Equal(t, []string{"1 " + next.Encode(), "3 " + next.Encode()}, pagesHeader.Get(productsNextHeader))
+}
+
+func TestServer_Shutdown_shouldReportNotServing_andWaitForInFlightCalls(t *testing.T) {
+	server := NewServer(&Configuration{MaxBatchSize: 10}, &pageServiceMock{}, logging.Discard())
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	watch, err := healthpb.NewHealthClient(dialTestServer(t, server)).
+		Watch(watchCtx, &healthpb.HealthCheckRequest{Service: "pages.PageService"})
+	require.NoError(t, err)
+	serving, err := watch.Recv()
+	require.NoError(t, err)
+
+	shutdownErrChan := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		shutdownErrChan <- server.Shutdown(ctx)
+	}()
+	notServing, err := watch.Recv()
+	cancelWatch()
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, serving.GetStatus())
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, notServing.GetStatus())
+	assert.NoError(t, <-shutdownErrChan)
+}
+
+func dialTestServer(t *testing.T, server *Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	connection, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = connection.Close() })
+	return connection
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type Configuration struct {
+	Level  string `envconfig:"LOG_LEVEL" default:"info"`
+	Format string `envconfig:"LOG_FORMAT" default:"json"`
+}
+
+type contextKey struct{}
+
+func NewLoggerFromEnv() (*slog.Logger, error) {
+	config, err := ConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewLogger(config, os.Stdout)
+}
+
+func ConfigurationFromEnv() (*Configuration, error) {
+	config := &Configuration{}
+	err := envconfig.Process("", config)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// NewLogger creates logger writing to writer in json or logfmt format, with level one of: debug, info, warn, error.
+func NewLogger(config *Configuration, writer io.Writer) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(config.Format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(writer, options)), nil
+	case "logfmt", "text":
+		return slog.New(slog.NewTextHandler(writer, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT: %v, expected json or logfmt", config.Format)
+	}
+}
+
+// Discard returns logger dropping all records, useful in tests.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// WithLogger returns context carrying request scoped logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns logger stored with WithLogger, or fallback when context has no logger.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         *Configuration
+		expectedOutput string
+		expectedLines  int
+		expectedErr    string
+	}{
+		{
+			name:           "should log json above configured level",
+			config:         &Configuration{Level: "info", Format: "json"},
+			expectedOutput: `"level":"INFO","msg":"info message","page_id":1}`,
+			expectedLines:  1,
+		},
+		{
+			name:           "should log logfmt, when format is logfmt",
+			config:         &Configuration{Level: "debug", Format: "logfmt"},
+			expectedOutput: `level=DEBUG msg="debug message" page_id=1`,
+			expectedLines:  2,
+		},
+		{
+			name:        "should fail, when level invalid",
+			config:      &Configuration{Level: "verbose", Format: "json"},
+			expectedErr: `invalid LOG_LEVEL: slog: level string "verbose": unknown name`,
+		},
+		{
+			name:        "should fail, when format invalid",
+			config:      &Configuration{Level: "info", Format: "xml"},
+			expectedErr: "invalid LOG_FORMAT: xml, expected json or logfmt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+
+			logger, err := NewLogger(tt.config, buffer)
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			logger.Debug("debug message", "page_id", 1)
+			logger.Info("info message", "page_id", 1)
+			assert.Contains(t, buffer.String(), tt.expectedOutput)
+			assert.Equal(t, tt.expectedLines, bytes.Count(buffer.Bytes(), []byte("\n")))
+		})
+	}
+}
+
+func TestConfigurationFromEnv_shouldReadLogLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "debug")
+
+	config, err := ConfigurationFromEnv()
+
+	require.NoError(t, err)
+	assert.Equal(t, &Configuration{Level: "debug", Format: "json"}, config)
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := Discard()
+	logger := Discard()
+
+	assert.Same(t, logger, FromContext(WithLogger(context.Background(), logger), fallback))
+	assert.Same(t, fallback, FromContext(context.Background(), fallback))
+}
@@ -1,32 +1,113 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/remikj/pages-ms/src/contoller"
+	"github.com/remikj/pages-ms/src/graphqlapi"
+	"github.com/remikj/pages-ms/src/grpcapi"
+	"github.com/remikj/pages-ms/src/logging"
 	"github.com/remikj/pages-ms/src/repository"
 	"github.com/remikj/pages-ms/src/server"
 	"github.com/remikj/pages-ms/src/service"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	fmt.Println("Starting application")
-	pageRepositoryAsync, err := repository.InitPageRepositoryAsyncFromEnv()
+	os.Exit(run())
+}
+
+// run starts the application and blocks until SIGINT or SIGTERM, returns exit code.
+func run() (exitCode int) {
+	logger, err := logging.NewLoggerFromEnv()
 	if err != nil {
 		fmt.Println(err)
-		return
+		return 1
+	}
+	logger.Info("starting application")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repositoryConfig, err := repository.ConfigurationFromEnv()
+	if err != nil {
+		logger.Error("failed to load repository configuration", "error", err)
+		return 1
+	}
+	pageRepository, err := repository.NewPageRepository(repositoryConfig, logger)
+	if err != nil {
+		logger.Error("failed to initialize repository", "error", err)
+		return 1
 	}
+	defer func() {
+		if err := pageRepository.CloseRepository(); err != nil {
+			logger.Error("failed to close repository", "error", err)
+			exitCode = 1
+		}
+	}()
 
+	pageRepositoryAsync, err := repository.NewPageRepositoryAsyncFromEnv(pageRepository)
+	if err != nil {
+		logger.Error("failed to initialize repository", "error", err)
+		return 1
+	}
+	pageServiceImpl, err := service.NewPageServiceFromEnv(pageRepositoryAsync, logger)
+	if err != nil {
+		logger.Error("failed to initialize page service", "error", err)
+		return 1
+	}
+	pageService, err := service.NewPageServiceCacheFromEnv(pageServiceImpl)
+	if err != nil {
+		logger.Error("failed to initialize page service", "error", err)
+		return 1
+	}
+	healthChecks := map[string]contoller.HealthCheck{repositoryConfig.Backend: pageRepository.Ping}
+	if circuitChecker, ok := pageRepository.(repository.CircuitChecker); ok {
+		healthChecks[repositoryConfig.Backend+"_circuit_breaker"] = circuitChecker.CheckCircuit
+	}
+	healthController, err := contoller.NewHealthControllerFromEnv(healthChecks, logger)
+	if err != nil {
+		logger.Error("failed to initialize health controller", "error", err)
+		return 1
+	}
+	pageController, err := contoller.NewPageControllerFromEnv(pageService, logger)
+	if err != nil {
+		logger.Error("failed to initialize page controller", "error", err)
+		return 1
+	}
+	productController, err := contoller.NewProductControllerFromEnv(pageService, logger)
+	if err != nil {
+		logger.Error("failed to initialize product controller", "error", err)
+		return 1
+	}
+	graphQLExecutor, err := graphqlapi.NewExecutorFromEnv(pageService, logger)
+	if err != nil {
+		logger.Error("failed to initialize graphql executor", "error", err)
+		return 1
+	}
+	grpcServer, err := grpcapi.NewServerFromEnv(pageService, logger)
+	if err != nil {
+		logger.Error("failed to initialize grpc server", "error", err)
+		return 1
+	}
 	serverImpl, err := server.NewServerFromEnv(
-		contoller.NewPageController(
-			service.NewPageService(pageRepositoryAsync),
-		),
+		pageController,
+		productController,
+		healthController,
+		contoller.NewGraphQLController(graphQLExecutor, logger),
+		grpcServer,
+		logger,
 	)
 	if err != nil {
-		fmt.Println(err)
-		return
+		logger.Error("failed to initialize server", "error", err)
+		return 1
 	}
-	err = serverImpl.Run()
-	if err != nil {
-		fmt.Println(err)
+
+	if err := serverImpl.Run(ctx); err != nil {
+		logger.Error("server stopped with error", "error", err)
+		exitCode = 1
 	}
+	logger.Info("application stopped")
+	return exitCode
 }
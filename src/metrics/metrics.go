@@ -0,0 +1,321 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram buckets in seconds, same as default buckets of Prometheus client.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultRegistry is the registry used by metrics created with package level constructors.
+var DefaultRegistry = NewRegistry()
+
+type collector interface {
+	name() string
+	write(w io.Writer) error
+}
+
+// Registry keeps metrics and writes them in Prometheus text exposition format.
+type Registry struct {
+	mutex      sync.Mutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// register adds collector to the registry, collector registered earlier with the same name is replaced.
+func (r *Registry) register(c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.collectors[c.name()] = c
+}
+
+// Write writes all metrics sorted by name.
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.Lock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].name() < collectors[j].name()
+	})
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		// error means that the scraper disconnected, there is nobody to report it to
+		_ = r.Write(writer)
+	})
+}
+
+func Handler() http.Handler {
+	return DefaultRegistry.Handler()
+}
+
+type desc struct {
+	metricName string
+	help       string
+	metricType string
+	labelNames []string
+}
+
+func (d desc) name() string {
+	return d.metricName
+}
+
+func (d desc) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.metricName, escapeHelp(d.help), d.metricName, d.metricType)
+	return err
+}
+
+func (d desc) checkLabelValues(labelValues []string) {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", d.metricName, len(d.labelNames), len(labelValues)))
+	}
+}
+
+// CounterVec is a counter partitioned by label values.
+type CounterVec struct {
+	desc
+	mutex  sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec creates counter registered in DefaultRegistry.
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	c := newCounterVec(name, help, labelNames...)
+	DefaultRegistry.register(c)
+	return c
+}
+
+func newCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{
+		desc:   desc{metricName: name, help: help, metricType: "counter", labelNames: labelNames},
+		values: make(map[string]*counterValue),
+	}
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	c.checkLabelValues(labelValues)
+	key := labelKey(labelValues)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labelValues: labelValues}
+		c.values[key] = v
+	}
+	v.value += value
+}
+
+// Value returns current value of the counter, used mostly in tests.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if v, ok := c.values[labelKey(labelValues)]; ok {
+		return v.value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	if err := c.writeHeader(w); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		if err := writeSample(w, c.metricName, c.labelNames, v.labelValues, nil, v.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HistogramVec is a histogram partitioned by label values.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mutex   sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues  []string
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+// NewHistogramVec creates histogram registered in DefaultRegistry, buckets are upper bounds sorted ascending.
+func NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	h := newHistogramVec(name, help, buckets, labelNames...)
+	DefaultRegistry.register(h)
+	return h
+}
+
+func newHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{
+		desc:    desc{metricName: name, help: help, metricType: "histogram", labelNames: labelNames},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.checkLabelValues(labelValues)
+	key := labelKey(labelValues)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{labelValues: labelValues, bucketCounts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			v.bucketCounts[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	if err := h.writeHeader(w); err != nil {
+		return err
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		for i, upperBound := range h.buckets {
+			le := []string{"le", formatFloat(upperBound)}
+			if err := writeSample(w, h.metricName+"_bucket", h.labelNames, v.labelValues, le, float64(v.bucketCounts[i])); err != nil {
+				return err
+			}
+		}
+		if err := writeSample(w, h.metricName+"_bucket", h.labelNames, v.labelValues, []string{"le", "+Inf"}, float64(v.count)); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.metricName+"_sum", h.labelNames, v.labelValues, nil, v.sum); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.metricName+"_count", h.labelNames, v.labelValues, nil, float64(v.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valueFunc is a metric without labels with value read on every scrape.
+type valueFunc struct {
+	desc
+	valueFunc func() float64
+}
+
+// NewGaugeFunc registers in DefaultRegistry gauge with value returned by value func,
+// replacing metric registered earlier with the same name.
+func NewGaugeFunc(name string, help string, value func() float64) {
+	DefaultRegistry.register(newValueFunc(name, help, "gauge", value))
+}
+
+// NewCounterFunc registers in DefaultRegistry counter with value returned by value func,
+// replacing metric registered earlier with the same name.
+func NewCounterFunc(name string, help string, value func() float64) {
+	DefaultRegistry.register(newValueFunc(name, help, "counter", value))
+}
+
+func newValueFunc(name string, help string, metricType string, value func() float64) *valueFunc {
+	return &valueFunc{desc: desc{metricName: name, help: help, metricType: metricType}, valueFunc: value}
+}
+
+func (f *valueFunc) write(w io.Writer) error {
+	if err := f.writeHeader(w); err != nil {
+		return err
+	}
+	return writeSample(w, f.metricName, nil, nil, nil, f.valueFunc())
+}
+
+func writeSample(w io.Writer, name string, labelNames []string, labelValues []string, extraLabel []string, value float64) error {
+	labels := make([]string, 0, len(labelNames)+1)
+	for i, labelName := range labelNames {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", labelName, escapeLabelValue(labelValues[i])))
+	}
+	if extraLabel != nil {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", extraLabel[0], extraLabel[1]))
+	}
+	var err error
+	if len(labels) == 0 {
+		_, err = fmt.Fprintf(w, "%s %s\n", name, formatFloat(value))
+	} else {
+		_, err = fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(labels, ","), formatFloat(value))
+	}
+	return err
+}
+
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_Write_shouldWriteMetricsInTextExpositionFormat(t *testing.T) {
+	registry := NewRegistry()
+	counter := newCounterVec("requests_total", "Total requests.", "method", "route")
+	histogram := newHistogramVec("request_duration_seconds", "Request duration.", []float64{0.1, 1}, "route")
+	registry.register(counter)
+	registry.register(histogram)
+	registry.register(newValueFunc("in_flight", "In flight\nrequests.", "gauge", func() float64 { return 3 }))
+
+	counter.Inc("GET", "/pages/{id}")
+	counter.Add(2, "POST", "/pages")
+	counter.Inc("GET", `/"quoted"`)
+	histogram.Observe(0.05, "/pages")
+	histogram.Observe(0.5, "/pages")
+	histogram.Observe(5, "/pages")
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, registry.Write(buffer))
+
+	assert.Equal(t, `# HELP in_flight In flight\nrequests.
+# TYPE in_flight gauge
+in_flight 3
+# HELP request_duration_seconds Request duration.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{route="/pages",le="0.1"} 1
+request_duration_seconds_bucket{route="/pages",le="1"} 2
+request_duration_seconds_bucket{route="/pages",le="+Inf"} 3
+request_duration_seconds_sum{route="/pages"} 5.55
+request_duration_seconds_count{route="/pages"} 3
+# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET",route="/\"quoted\""} 1
+requests_total{method="GET",route="/pages/{id}"} 1
+requests_total{method="POST",route="/pages"} 2
+`, buffer.String())
+}
+
+func TestRegistry_register_shouldReplaceMetricWithSameName(t *testing.T) {
+	registry := NewRegistry()
+	registry.register(newValueFunc("value", "Value.", "gauge", func() float64 { return 1 }))
+	registry.register(newValueFunc("value", "Value.", "gauge", func() float64 { return 2 }))
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, registry.Write(buffer))
+
+	assert.Equal(t, "# HELP value Value.\n# TYPE value gauge\nvalue 2\n", buffer.String())
+}
+
+func TestCounterVec_shouldPanic_whenLabelValuesCountInvalid(t *testing.T) {
+	counter := newCounterVec("requests_total", "Total requests.", "method")
+
+	assert.Panics(t, func() { counter.Inc() })
+	assert.Panics(t, func() { counter.Inc("GET", "/pages") })
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.register(newValueFunc("value", "Value.", "gauge", func() float64 { return 1 }))
+	responseRecorder := httptest.NewRecorder()
+
+	registry.Handler().ServeHTTP(responseRecorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP value Value.\n# TYPE value gauge\nvalue 1\n", responseRecorder.Body.String())
+}
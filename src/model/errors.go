@@ -0,0 +1,43 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrPageNotFound      = errors.New("page not found")
+	ErrPageAlreadyExists = errors.New("page already exists")
+	ErrInvalidPage       = errors.New("invalid page")
+
+	ErrProductNotFound      = errors.New("product not found")
+	ErrProductAlreadyExists = errors.New("product already exists")
+	ErrInvalidProduct       = errors.New("invalid product")
+
+	// ErrInvalidFields is returned when selected fields of the page are not fields of its representation.
+	ErrInvalidFields = errors.New("invalid fields")
+	// ErrInvalidProductQuery is returned when sort, filters or cursor of products query are not valid.
+	ErrInvalidProductQuery = errors.New("invalid product query")
+
+	// ErrBackendUnavailable is returned when repository backend can not be reached or fails to run a query.
+	ErrBackendUnavailable = errors.New("backend unavailable")
+	// ErrDataIntegrity is returned when stored data breaks assumptions of the service,
+	// e.g. there is more than one seo of the page.
+	ErrDataIntegrity = errors.New("data integrity violation")
+	// ErrOverloaded is returned when too many queries are running and waiting, so the query was not run.
+	ErrOverloaded = errors.New("overloaded")
+)
+
+// OverloadedError is ErrOverloaded with hint how long the client should wait before it retries.
+type OverloadedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *OverloadedError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrOverloaded, e.RetryAfter)
+}
+
+func (e *OverloadedError) Is(target error) bool {
+	return target == ErrOverloaded
+}
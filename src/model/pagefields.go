@@ -0,0 +1,141 @@
+package model
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+var (
+	seoFieldNames     = []string{"PageId", "Title", "Description", "Robots"}
+	productFieldNames = []string{"Id", "PageId", "Name", "Description", "Price"}
+)
+
+// PageFields selects fields of the page returned to the client. SEO and Products hold names of selected
+// fields of SEO and Product, in the order of their declaration. Nil slice means that none is selected.
+type PageFields struct {
+	SEO      []string
+	Products []string
+}
+
+// ParsePageFields parses comma separated field paths, e.g. "seo.title,products.name,products.price".
+// Path without field name, e.g. "products", selects all fields. Names are matched ignoring case,
+// so they can be written the same as in JSON representation.
+func ParsePageFields(value string) (*PageFields, error) {
+	seoFields, productFields := map[string]bool{}, map[string]bool{}
+	for _, path := range strings.Split(value, ",") {
+		object, field, hasField := strings.Cut(strings.TrimSpace(path), ".")
+		var fields map[string]bool
+		var names []string
+		switch {
+		case strings.EqualFold(object, "seo"):
+			fields, names = seoFields, seoFieldNames
+		case strings.EqualFold(object, "products"):
+			fields, names = productFields, productFieldNames
+		default:
+			return nil, fmt.Errorf("%w: unknown field %q, expected seo or products", ErrInvalidFields, path)
+		}
+		if !hasField {
+			for _, name := range names {
+				fields[name] = true
+			}
+			continue
+		}
+		name, ok := findFieldName(names, field)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q, %v has fields %v", ErrInvalidFields, path, object, strings.Join(names, ", "))
+		}
+		fields[name] = true
+	}
+	return &PageFields{
+		SEO:      selectedFieldNames(seoFieldNames, seoFields),
+		Products: selectedFieldNames(productFieldNames, productFields),
+	}, nil
+}
+
+func findFieldName(names []string, field string) (string, bool) {
+	for _, name := range names {
+		if strings.EqualFold(name, field) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func selectedFieldNames(names []string, selected map[string]bool) []string {
+	if len(selected) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(selected))
+	for _, name := range names {
+		if selected[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// SparsePage is representation of the page with only selected fields, the others are nil and omitted.
+type SparsePage struct {
+	XMLName  xml.Name         `xml:"Page" json:"-" msgpack:"-"`
+	SEO      *SparseSEO       `json:",omitempty" msgpack:",omitempty"`
+	Products *[]SparseProduct `json:",omitempty" xml:"Products>Product,omitempty" msgpack:",omitempty"`
+}
+
+type SparseSEO struct {
+	PageId      *int    `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+	Title       *string `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+	Description *string `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+	Robots      *string `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+}
+
+type SparseProduct struct {
+	Id          *int     `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+	PageId      *int     `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+	Name        *string  `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+	Description *string  `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+	Price       *float64 `json:",omitempty" xml:",omitempty" msgpack:",omitempty"`
+}
+
+// Sparse returns representation of the page with fields selected by f.
+// Products are omitted when none of their fields is selected, but page without products has empty list of them.
+func (p *Page) Sparse(f *PageFields) *SparsePage {
+	sparse := &SparsePage{}
+	if f.SEO != nil {
+		sparse.SEO = &SparseSEO{}
+		for _, name := range f.SEO {
+			switch name {
+			case "PageId":
+				sparse.SEO.PageId = &p.SEO.PageId
+			case "Title":
+				sparse.SEO.Title = &p.SEO.Title
+			case "Description":
+				sparse.SEO.Description = &p.SEO.Description
+			case "Robots":
+				sparse.SEO.Robots = &p.SEO.Robots
+			}
+		}
+	}
+	if f.Products != nil {
+		products := make([]SparseProduct, len(p.Products))
+		sparse.Products = &products
+		for i := range p.Products {
+			product, sparseProduct := &p.Products[i], &products[i]
+			for _, name := range f.Products {
+				switch name {
+				case "Id":
+					sparseProduct.Id = &product.Id
+				case "PageId":
+					sparseProduct.PageId = &product.PageId
+				case "Name":
+					sparseProduct.Name = &product.Name
+				case "Description":
+					sparseProduct.Description = &product.Description
+				case "Price":
+					sparseProduct.Price = &product.Price
+				}
+			}
+		}
+	}
+	return sparse
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParsePageFields(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		expectedFields *PageFields
+		expectedErr    string
+	}{
+		{
+			name:           "should select fields, when paths valid",
+			value:          "seo.title,products.name,products.price",
+			expectedFields: &PageFields{SEO: []string{"Title"}, Products: []string{"Name", "Price"}},
+		},
+		{
+			name:           "should select fields in declaration order ignoring case and duplicates",
+			value:          "products.Price, PRODUCTS.id,products.price",
+			expectedFields: &PageFields{Products: []string{"Id", "Price"}},
+		},
+		{
+			name:           "should select all fields, when path has no field name",
+			value:          "seo,products.name",
+			expectedFields: &PageFields{SEO: []string{"PageId", "Title", "Description", "Robots"}, Products: []string{"Name"}},
+		},
+		{
+			name:        "should fail, when object unknown",
+			value:       "seo.title,page.title",
+			expectedErr: `invalid fields: unknown field "page.title", expected seo or products`,
+		},
+		{
+			name:        "should fail, when field unknown",
+			value:       "seo.price",
+			expectedErr: `invalid fields: unknown field "seo.price", seo has fields PageId, Title, Description, Robots`,
+		},
+		{
+			name:        "should fail, when path empty",
+			value:       "seo.title,",
+			expectedErr: `invalid fields: unknown field "", expected seo or products`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParsePageFields(tt.value)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				assert.ErrorIs(t, err, ErrInvalidFields)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestPage_Sparse(t *testing.T) {
+	title, name, price := "title", "name", 1.5
+	page := &Page{
+		SEO:      SEO{PageId: 1, Title: title},
+		Products: []Product{{Id: 2, PageId: 1, Name: name, Price: price}},
+	}
+
+	assert.Equal(t, &SparsePage{SEO: &SparseSEO{Title: &title}}, page.Sparse(&PageFields{SEO: []string{"Title"}}))
+	assert.Equal(t, &SparsePage{Products: &[]SparseProduct{{Name: &name, Price: &price}}},
+		page.Sparse(&PageFields{Products: []string{"Name", "Price"}}))
+	assert.Equal(t, &SparsePage{Products: &[]SparseProduct{}}, (&Page{}).Sparse(&PageFields{Products: []string{"Name"}}))
+}
@@ -1,8 +1,26 @@
 package model
 
+import "time"
+
 type Page struct {
 	SEO      SEO
-	Products []Product
+	Products []Product `xml:"Products>Product"`
+	// Partial is set when products could not be read and the page is returned without them.
+	// It is sent in Warning header instead of being part of representations of the page.
+	Partial bool `bson:"-" json:"-" xml:"-" msgpack:"-"`
+	// ProductsNext is set when the page has more products than were read with it, it is position of
+	// the last of them, from which the rest can be queried. It is sent in Link header.
+	ProductsNext *ProductCursor `bson:"-" json:"-" xml:"-" msgpack:"-"`
+}
+
+// CapProducts keeps at most limit products of the page, ordered by id, and sets ProductsNext
+// when some were dropped. Limit of 0 or less keeps all of them.
+func (p *Page) CapProducts(limit int) {
+	if limit <= 0 || len(p.Products) <= limit {
+		return
+	}
+	p.Products = p.Products[:limit]
+	p.ProductsNext = CursorAfter(SortById, &p.Products[limit-1])
 }
 
 type SEO struct {
@@ -10,6 +28,14 @@ type SEO struct {
 	Title       string `bson:"title"`
 	Description string `bson:"description"`
 	Robots      string `bson:"robots"`
+	// UpdatedAt is time of the last change of the page or its products, set by repositories.
+	// It is sent in Last-Modified header instead of being part of representations of the page.
+	UpdatedAt time.Time `bson:"updated_at,omitempty" json:"-" xml:"-" msgpack:"-"`
+}
+
+// Touch marks the page as changed at now. Time is truncated to milliseconds, which all repositories can store.
+func (s *SEO) Touch(now time.Time) {
+	s.UpdatedAt = now.UTC().Truncate(time.Millisecond)
 }
 
 type Product struct {
@@ -0,0 +1,50 @@
+package model
+
+// PagePatch describes partial update of a page. Only non-nil fields are applied,
+// Products replace the whole products list of the page when set.
+type PagePatch struct {
+	SEO      *SEOPatch
+	Products *[]Product
+}
+
+type SEOPatch struct {
+	Title       *string
+	Description *string
+	Robots      *string
+}
+
+// ProductPatch describes partial update of a product. Only non-nil fields are applied.
+type ProductPatch struct {
+	Name        *string
+	Description *string
+	Price       *float64
+}
+
+func (p *Page) ApplyPatch(patch PagePatch) {
+	if patch.SEO != nil {
+		if patch.SEO.Title != nil {
+			p.SEO.Title = *patch.SEO.Title
+		}
+		if patch.SEO.Description != nil {
+			p.SEO.Description = *patch.SEO.Description
+		}
+		if patch.SEO.Robots != nil {
+			p.SEO.Robots = *patch.SEO.Robots
+		}
+	}
+	if patch.Products != nil {
+		p.Products = *patch.Products
+	}
+}
+
+func (p *Product) ApplyPatch(patch ProductPatch) {
+	if patch.Name != nil {
+		p.Name = *patch.Name
+	}
+	if patch.Description != nil {
+		p.Description = *patch.Description
+	}
+	if patch.Price != nil {
+		p.Price = *patch.Price
+	}
+}
@@ -0,0 +1,60 @@
+package model
+
+import "fmt"
+
+// Validate checks that the page can be written to the repository.
+// Products with PageId set to 0 are treated as belonging to the page.
+func (p *Page) Validate() error {
+	if p.SEO.PageId <= 0 {
+		return fmt.Errorf("%w: SEO.PageId must be positive", ErrInvalidPage)
+	}
+	if p.SEO.Title == "" {
+		return fmt.Errorf("%w: SEO.Title must not be empty", ErrInvalidPage)
+	}
+	productIds := make(map[int]bool, len(p.Products))
+	for _, product := range p.Products {
+		if product.PageId != 0 && product.PageId != p.SEO.PageId {
+			return fmt.Errorf("%w: product %v has PageId %v, expected %v", ErrInvalidPage, product.Id, product.PageId, p.SEO.PageId)
+		}
+		if err := product.validateFields(); err != nil {
+			return fmt.Errorf("%w: product %v %v", ErrInvalidPage, product.Id, err)
+		}
+		if productIds[product.Id] {
+			return fmt.Errorf("%w: duplicated product Id %v", ErrInvalidPage, product.Id)
+		}
+		productIds[product.Id] = true
+	}
+	return nil
+}
+
+// Validate checks that the product can be written to the page with given id.
+// Product with PageId set to 0 is treated as belonging to the page.
+func (p *Product) Validate(pageId int) error {
+	if p.PageId != 0 && p.PageId != pageId {
+		return fmt.Errorf("%w: PageId %v, expected %v", ErrInvalidProduct, p.PageId, pageId)
+	}
+	if err := p.validateFields(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+	return nil
+}
+
+func (p *Product) validateFields() error {
+	if p.Name == "" {
+		return fmt.Errorf("Name must not be empty")
+	}
+	if p.Price < 0 {
+		return fmt.Errorf("Price must not be negative")
+	}
+	return nil
+}
+
+// Normalize assigns the page id to all products of the page.
+func (p *Page) Normalize() {
+	if p.Products == nil {
+		p.Products = []Product{}
+	}
+	for i := range p.Products {
+		p.Products[i].PageId = p.SEO.PageId
+	}
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPage_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		page        Page
+		expectedErr string
+	}{
+		{
+			name: "should pass, when page valid",
+			page: Page{
+				SEO:      SEO{PageId: 1, Title: "title"},
+				Products: []Product{{Id: 1, Name: "name1"}, {Id: 2, PageId: 1, Name: "name2", Price: 1.5}},
+			},
+		},
+		{
+			name:        "should fail, when page id not positive",
+			page:        Page{SEO: SEO{PageId: 0, Title: "title"}},
+			expectedErr: "invalid page: SEO.PageId must be positive",
+		},
+		{
+			name:        "should fail, when title empty",
+			page:        Page{SEO: SEO{PageId: 1}},
+			expectedErr: "invalid page: SEO.Title must not be empty",
+		},
+		{
+			name: "should fail, when product belongs to other page",
+			page: Page{
+				SEO:      SEO{PageId: 1, Title: "title"},
+				Products: []Product{{Id: 1, PageId: 2, Name: "name1"}},
+			},
+			expectedErr: "invalid page: product 1 has PageId 2, expected 1",
+		},
+		{
+			name: "should fail, when product price negative",
+			page: Page{
+				SEO:      SEO{PageId: 1, Title: "title"},
+				Products: []Product{{Id: 1, Name: "name1", Price: -1}},
+			},
+			expectedErr: "invalid page: product 1 Price must not be negative",
+		},
+		{
+			name: "should fail, when product ids duplicated",
+			page: Page{
+				SEO:      SEO{PageId: 1, Title: "title"},
+				Products: []Product{{Id: 1, Name: "name1"}, {Id: 1, Name: "name2"}},
+			},
+			expectedErr: "invalid page: duplicated product Id 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.page.Validate()
+
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidPage)
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPage_ApplyPatch(t *testing.T) {
+	title := "new title"
+	products := []Product{{Id: 3, Name: "name3"}}
+	page := Page{
+		SEO:      SEO{PageId: 1, Title: "title", Description: "description", Robots: "robots"},
+		Products: []Product{{Id: 1, Name: "name1"}},
+	}
+
+	page.ApplyPatch(PagePatch{SEO: &SEOPatch{Title: &title}, Products: &products})
+
+	assert.Equal(t, Page{
+		SEO:      SEO{PageId: 1, Title: "new title", Description: "description", Robots: "robots"},
+		Products: products,
+	}, page)
+}
+
+func TestPage_CapProducts(t *testing.T) {
+	products := []Product{{Id: 1}, {Id: 2}, {Id: 3}}
+	capped := Page{Products: products}
+	notCapped := Page{Products: products}
+	unlimited := Page{Products: products}
+
+	capped.CapProducts(2)
+	notCapped.CapProducts(3)
+	unlimited.CapProducts(0)
+
+	assert.Equal(t, Page{Products: products[:2], ProductsNext: &ProductCursor{Sort: SortById, Id: 2}}, capped)
+	assert.Equal(t, Page{Products: products}, notCapped)
+	assert.Equal(t, Page{Products: products}, unlimited)
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ProductSort is order of queried products. Products with equal sort key are ordered by Id,
+// so the order is total and products can be paginated with ProductCursor.
+type ProductSort string
+
+const (
+	SortById        ProductSort = "id"
+	SortByPrice     ProductSort = "price"
+	SortByPriceDesc ProductSort = "-price"
+	SortByName      ProductSort = "name"
+)
+
+// ParseProductSort parses sort, empty value sorts by id.
+func ParseProductSort(value string) (ProductSort, error) {
+	switch sort := ProductSort(value); sort {
+	case "":
+		return SortById, nil
+	case SortById, SortByPrice, SortByPriceDesc, SortByName:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("%w: unknown sort %q, expected %v, %v, %v or %v", ErrInvalidProductQuery,
+			value, SortById, SortByPrice, SortByPriceDesc, SortByName)
+	}
+}
+
+// Less reports whether product a goes before product b.
+func (s ProductSort) Less(a, b *Product) bool {
+	switch {
+	case s == SortByPrice && a.Price != b.Price:
+		return a.Price < b.Price
+	case s == SortByPriceDesc && a.Price != b.Price:
+		return a.Price > b.Price
+	case s == SortByName && a.Name != b.Name:
+		return a.Name < b.Name
+	default:
+		return a.Id < b.Id
+	}
+}
+
+// ProductQuery selects at most Limit products of the page matching filters, in Sort order,
+// starting after position given by After cursor.
+type ProductQuery struct {
+	Limit int
+	Sort  ProductSort
+	After *ProductCursor
+	// NameContains filters products with name containing given text, ignoring case.
+	NameContains string
+	MinPrice     *float64
+	MaxPrice     *float64
+}
+
+// Filter reports whether the product matches filters of the query, regardless of its position.
+func (q *ProductQuery) Filter(product *Product) bool {
+	switch {
+	case q.NameContains != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(q.NameContains)):
+		return false
+	case q.MinPrice != nil && product.Price < *q.MinPrice:
+		return false
+	case q.MaxPrice != nil && product.Price > *q.MaxPrice:
+		return false
+	default:
+		return true
+	}
+}
+
+// Matches reports whether the product matches filters of the query and goes after its cursor.
+func (q *ProductQuery) Matches(product *Product) bool {
+	if !q.Filter(product) {
+		return false
+	}
+	if q.After == nil {
+		return true
+	}
+	last := q.After.product()
+	return q.Sort.Less(&last, product)
+}
+
+// ProductCursor is position of the last returned product: its sort key and Id. Products are queried
+// after the position, instead of skipping already returned ones, so pages are not shifted by writes.
+type ProductCursor struct {
+	Sort  ProductSort `json:"s"`
+	Id    int         `json:"i"`
+	Price float64     `json:"p,omitempty"`
+	Name  string      `json:"n,omitempty"`
+}
+
+// CursorAfter returns cursor of the product in given sort order.
+func CursorAfter(sort ProductSort, product *Product) *ProductCursor {
+	cursor := &ProductCursor{Sort: sort, Id: product.Id}
+	switch sort {
+	case SortByPrice, SortByPriceDesc:
+		cursor.Price = product.Price
+	case SortByName:
+		cursor.Name = product.Name
+	}
+	return cursor
+}
+
+// Encode returns opaque token of the cursor, which is safe to use in URL.
+func (c *ProductCursor) Encode() string {
+	marshal, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(marshal)
+}
+
+// ParseProductCursor decodes token returned by Encode, cursor has to be created for the same sort.
+func ParseProductCursor(token string, sort ProductSort) (*ProductCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidProductQuery)
+	}
+	cursor := &ProductCursor{}
+	if err := json.Unmarshal(decoded, cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidProductQuery)
+	}
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor was created for sort %q, not %q", ErrInvalidProductQuery, cursor.Sort, sort)
+	}
+	return cursor, nil
+}
+
+func (c *ProductCursor) product() Product {
+	return Product{Id: c.Id, Price: c.Price, Name: c.Name}
+}
+
+// ProductList is a page of queried products. Next is nil on the last page,
+// Total is number of all products matching filters, when it was requested.
+type ProductList struct {
+	Products []Product
+	Next     *ProductCursor
+	Total    *int
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestParseProductSort(t *testing.T) {
+	sorting, err := ParseProductSort("")
+	assert.NoError(t, err)
+	assert.Equal(t, SortById, sorting)
+
+	sorting, err = ParseProductSort("-price")
+	assert.NoError(t, err)
+	assert.Equal(t, SortByPriceDesc, sorting)
+
+	_, err = ParseProductSort("-name")
+	assert.EqualError(t, err, `invalid product query: unknown sort "-name", expected id, price, -price or name`)
+	assert.ErrorIs(t, err, ErrInvalidProductQuery)
+}
+
+func TestProductSort_Less(t *testing.T) {
+	products := []Product{
+		{Id: 1, Name: "b", Price: 2},
+		{Id: 2, Name: "a", Price: 1},
+		{Id: 3, Name: "c", Price: 2},
+		{Id: 4, Name: "a", Price: 3},
+	}
+	tests := []struct {
+		sort        ProductSort
+		expectedIds []int
+	}{
+		{sort: SortById, expectedIds: []int{1, 2, 3, 4}},
+		{sort: SortByPrice, expectedIds: []int{2, 1, 3, 4}},
+		{sort: SortByPriceDesc, expectedIds: []int{4, 1, 3, 2}},
+		{sort: SortByName, expectedIds: []int{2, 4, 1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.sort), func(t *testing.T) {
+			sorted := append([]Product{}, products...)
+			sort.Slice(sorted, func(i, j int) bool { return tt.sort.Less(&sorted[i], &sorted[j]) })
+			ids := make([]int, len(sorted))
+			for i := range sorted {
+				ids[i] = sorted[i].Id
+			}
+			assert.Equal(t, tt.expectedIds, ids)
+		})
+	}
+}
+
+func TestProductQuery_Matches(t *testing.T) {
+	minPrice, maxPrice := 1.5, 3.0
+	query := &ProductQuery{
+		Sort:         SortByPrice,
+		After:        CursorAfter(SortByPrice, &Product{Id: 2, Price: 2}),
+		NameContains: "SAMPLE",
+		MinPrice:     &minPrice,
+		MaxPrice:     &maxPrice,
+	}
+
+	assert.True(t, query.Matches(&Product{Id: 3, Name: "sample", Price: 2}))
+	assert.True(t, query.Matches(&Product{Id: 1, Name: "a sample", Price: 2.5}))
+	assert.False(t, query.Matches(&Product{Id: 1, Name: "sample", Price: 2}), "should skip product before cursor")
+	assert.False(t, query.Matches(&Product{Id: 2, Name: "sample", Price: 2}), "should skip product at cursor")
+	assert.False(t, query.Matches(&Product{Id: 3, Name: "other", Price: 2}), "should skip product with other name")
+	assert.False(t, query.Matches(&Product{Id: 3, Name: "sample", Price: 3.5}), "should skip product above max price")
+	assert.True(t, query.Filter(&Product{Id: 1, Name: "sample", Price: 2}), "should filter regardless of cursor")
+}
+
+func TestParseProductCursor(t *testing.T) {
+	cursor := CursorAfter(SortByName, &Product{Id: 7, Name: "name", Price: 2})
+
+	parsed, err := ParseProductCursor(cursor.Encode(), SortByName)
+	assert.NoError(t, err)
+	assert.Equal(t, &ProductCursor{Sort: SortByName, Id: 7, Name: "name"}, parsed)
+
+	_, err = ParseProductCursor(cursor.Encode(), SortById)
+	assert.EqualError(t, err, `invalid product query: cursor was created for sort "name", not "id"`)
+
+	_, err = ParseProductCursor("e30", SortById)
+	assert.EqualError(t, err, `invalid product query: cursor was created for sort "", not "id"`)
+
+	_, err = ParseProductCursor("%%%", SortById)
+	assert.EqualError(t, err, "invalid product query: malformed cursor")
+	assert.ErrorIs(t, err, ErrInvalidProductQuery)
+}
@@ -0,0 +1,35 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Handler serves the spec as JSON.
+func Handler() (http.Handler, error) {
+	doc, err := Spec()
+	if err != nil {
+		return nil, err
+	}
+	marshal, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write(marshal)
+	}), nil
+}
+
+// DocsHandler serves documentation page rendering the spec from /openapi.json.
+// The page is self-contained, so it works without access to the internet.
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = writer.Write(docsPage)
+	})
+}
@@ -0,0 +1,74 @@
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/remikj/pages-ms/src/contoller"
+	"github.com/remikj/pages-ms/src/graphqlapi"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/problem"
+	"sync"
+)
+
+// openapi.json describes paths and responses, schemas of request and response bodies
+// are generated from types used by controllers, so they can not get out of sync.
+//
+//go:embed openapi.json
+var pathsDocument []byte
+
+var (
+	specOnce sync.Once
+	spec     *openapi3.T
+	specErr  error
+)
+
+// schemaTypes are types of request and response bodies, by their schema names.
+var schemaTypes = map[string]interface{}{
+	"Page":            model.Page{},
+	"SEO":             model.SEO{},
+	"Product":         model.Product{},
+	"PagePatch":       model.PagePatch{},
+	"SEOPatch":        model.SEOPatch{},
+	"ProductPatch":    model.ProductPatch{},
+	"PagesResponse":   contoller.PagesResponse{},
+	"PageResult":      contoller.PageResult{},
+	"HealthResponse":  contoller.HealthResponse{},
+	"DependencyState": contoller.DependencyState{},
+	"Problem":         problem.Problem{},
+	"GraphQLRequest":  graphqlapi.Request{},
+	"GraphQLResponse": graphqlapi.Response{},
+}
+
+// Spec returns validated OpenAPI document of the service. It is built once and must not be modified.
+func Spec() (*openapi3.T, error) {
+	specOnce.Do(func() {
+		spec, specErr = buildSpec()
+	})
+	return spec, specErr
+}
+
+func buildSpec() (*openapi3.T, error) {
+	doc := &openapi3.T{}
+	if err := json.Unmarshal(pathsDocument, doc); err != nil {
+		return nil, fmt.Errorf("failed to decode openapi document: %w", err)
+	}
+	doc.Components.Schemas = openapi3.Schemas{}
+	for name, value := range schemaTypes {
+		schemaRef, err := openapi3gen.NewSchemaRefForValue(value, nil, openapi3gen.UseAllExportedFields())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate schema %v: %w", name, err)
+		}
+		doc.Components.Schemas[name] = schemaRef
+	}
+	if err := openapi3.NewLoader().ResolveRefsIn(doc, nil); err != nil {
+		return nil, fmt.Errorf("failed to resolve references in openapi document: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,20 @@
+package openapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSpec_shouldBuildValidDocument(t *testing.T) {
+	doc, err := Spec()
+
+	require.NoError(t, err)
+	assert.NoError(t, doc.Validate(context.Background()))
+	for name := range schemaTypes {
+		assert.Contains(t, doc.Components.Schemas, name)
+	}
+	assert.Contains(t, doc.Components.Schemas["Page"].Value.Properties, "SEO")
+	assert.Contains(t, doc.Components.Schemas["Page"].Value.Properties, "Products")
+}
@@ -0,0 +1,123 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/remikj/pages-ms/src/problem"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+func init() {
+	// docs page is served as text/html, which is validated as plain string.
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.RegisteredBodyDecoder("text/plain"))
+	// other representations of pages and products are not decoded, they are only checked to be documented.
+	for _, contentType := range []string{"application/xml", "text/xml", "application/msgpack", "application/x-protobuf", "application/x-ndjson"} {
+		openapi3filter.RegisterBodyDecoder(contentType, openapi3filter.FileBodyDecoder)
+	}
+}
+
+// ValidationMiddleware responds with 400 to requests not matching the spec. When validateResponses is set,
+// responses are buffered and validated too, and the ones not matching the spec are replaced by 500.
+// Validation of responses delays them until handler finishes, so it is meant for tests.
+// Requests to paths missing in the spec are passed to the handler without validation.
+func ValidationMiddleware(doc *openapi3.T, validateResponses bool, logger *slog.Logger) (func(next http.Handler) http.Handler, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openapi router: %w", err)
+	}
+	return func(next http.Handler) http.Handler {
+		return &validationHandler{next: next, router: router, validateResponses: validateResponses, logger: logger}
+	}, nil
+}
+
+type validationHandler struct {
+	next              http.Handler
+	router            routers.Router
+	validateResponses bool
+	logger            *slog.Logger
+}
+
+func (v *validationHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	route, pathParams, err := v.router.FindRoute(request)
+	if err != nil {
+		v.next.ServeHTTP(writer, request)
+		return
+	}
+	requestInput := &openapi3filter.RequestValidationInput{
+		Request:    request,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+	if err := openapi3filter.ValidateRequest(request.Context(), requestInput); err != nil {
+		v.logger.Info("request does not match openapi specification", "error", err)
+		problem.Write(writer, request, v.logger, http.StatusBadRequest, problem.CodeInvalidRequest,
+			fmt.Sprintf("Request does not match API specification: %v", err))
+		return
+	}
+	if !v.validateResponses {
+		v.next.ServeHTTP(writer, request)
+		return
+	}
+
+	buffer := newResponseBuffer()
+	v.next.ServeHTTP(buffer, request)
+	if request.Context().Err() != nil {
+		buffer.writeTo(writer)
+		return
+	}
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 buffer.status,
+		Header:                 buffer.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(buffer.body.Bytes())),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	if err := openapi3filter.ValidateResponse(request.Context(), responseInput); err != nil {
+		v.logger.Error("response does not match openapi specification", "status", buffer.status, "error", err)
+		problem.Write(writer, request, v.logger, http.StatusInternalServerError, problem.CodeInvalidResponse,
+			fmt.Sprintf("Response does not match API specification: %v", err))
+		return
+	}
+	buffer.writeTo(writer)
+}
+
+// responseBuffer keeps response in memory, so it can be validated before it is sent.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: http.Header{}, status: http.StatusOK}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *responseBuffer) Write(data []byte) (int, error) {
+	if b.header.Get("Content-Type") == "" && b.body.Len() == 0 && len(data) > 0 {
+		b.header.Set("Content-Type", http.DetectContentType(data))
+	}
+	return b.body.Write(data)
+}
+
+func (b *responseBuffer) writeTo(writer http.ResponseWriter) {
+	for key, values := range b.header {
+		writer.Header()[key] = values
+	}
+	writer.WriteHeader(b.status)
+	_, _ = writer.Write(b.body.Bytes())
+}
@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidationMiddleware(t *testing.T) {
+	validPage := `{"SEO": {"PageId": 1, "Title": "title"}, "Products": []}`
+	tests := []struct {
+		name              string
+		validateResponses bool
+		method            string
+		path              string
+		contentType       string
+		body              string
+		responseStatus    int
+		responseType      string
+		responseBody      string
+		wantStatus        int
+		wantBody          string
+		wantHandlerCalled bool
+	}{
+		{
+			name:              "should pass valid request",
+			method:            "PUT",
+			path:              "/pages/1",
+			contentType:       "application/json",
+			body:              validPage,
+			responseStatus:    http.StatusOK,
+			responseBody:      "handled",
+			wantStatus:        http.StatusOK,
+			wantBody:          "handled",
+			wantHandlerCalled: true,
+		},
+		{
+			name:       "should reject request with invalid path parameter",
+			method:     "GET",
+			path:       "/pages/abc",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Request does not match API specification",
+		},
+		{
+			name:        "should reject request with invalid body",
+			method:      "PUT",
+			path:        "/pages/1",
+			contentType: "application/json",
+			body:        `{"SEO": {"PageId": "one"}}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "Request does not match API specification",
+		},
+		{
+			name:              "should pass request to path missing in spec",
+			method:            "GET",
+			path:              "/unknown",
+			responseStatus:    http.StatusNotFound,
+			responseBody:      "not found",
+			wantStatus:        http.StatusNotFound,
+			wantBody:          "not found",
+			wantHandlerCalled: true,
+		},
+		{
+			name:              "should pass valid response",
+			validateResponses: true,
+			method:            "GET",
+			path:              "/pages/1",
+			responseStatus:    http.StatusOK,
+			responseType:      "application/json",
+			responseBody:      validPage,
+			wantStatus:        http.StatusOK,
+			wantBody:          validPage,
+			wantHandlerCalled: true,
+		},
+		{
+			name:              "should replace response with invalid body",
+			validateResponses: true,
+			method:            "GET",
+			path:              "/pages/1",
+			responseStatus:    http.StatusOK,
+			responseType:      "application/json",
+			responseBody:      `{"SEO": {"PageId": "one"}}`,
+			wantStatus:        http.StatusInternalServerError,
+			wantBody:          "Response does not match API specification",
+			wantHandlerCalled: true,
+		},
+		{
+			name:              "should replace response with undocumented status",
+			validateResponses: true,
+			method:            "GET",
+			path:              "/pages/1",
+			responseStatus:    http.StatusTeapot,
+			responseBody:      "teapot",
+			wantStatus:        http.StatusInternalServerError,
+			wantBody:          "Response does not match API specification",
+			wantHandlerCalled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Spec()
+			require.NoError(t, err)
+			middleware, err := ValidationMiddleware(doc, tt.validateResponses, logging.Discard())
+			require.NoError(t, err)
+			handlerCalled := false
+			handler := middleware(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				handlerCalled = true
+				if tt.responseType != "" {
+					writer.Header().Set("Content-Type", tt.responseType)
+				}
+				writer.WriteHeader(tt.responseStatus)
+				_, _ = writer.Write([]byte(tt.responseBody))
+			}))
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.wantBody)
+			assert.Equal(t, tt.wantHandlerCalled, handlerCalled)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package pagespb
+
+import "github.com/remikj/pages-ms/src/model"
+
+func FromPage(page *model.Page) *Page {
+	products := make([]*Product, 0, len(page.Products))
+	for i := range page.Products {
+		products = append(products, FromProduct(&page.Products[i]))
+	}
+	return &Page{Seo: FromSEO(&page.SEO), Products: products}
+}
+
+func FromSEO(seo *model.SEO) *SEO {
+	return &SEO{
+		PageId:      int64(seo.PageId),
+		Title:       seo.Title,
+		Description: seo.Description,
+		Robots:      seo.Robots,
+	}
+}
+
+func FromProduct(product *model.Product) *Product {
+	return &Product{
+		Id:          int64(product.Id),
+		PageId:      int64(product.PageId),
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+	}
+}
+
+// FromSparsePage converts page with only selected fields. Fields that are not selected keep default values,
+// which are not written by protobuf encoding.
+func FromSparsePage(page *model.SparsePage) *Page {
+	result := &Page{}
+	if seo := page.SEO; seo != nil {
+		result.Seo = &SEO{}
+		if seo.PageId != nil {
+			result.Seo.PageId = int64(*seo.PageId)
+		}
+		if seo.Title != nil {
+			result.Seo.Title = *seo.Title
+		}
+		if seo.Description != nil {
+			result.Seo.Description = *seo.Description
+		}
+		if seo.Robots != nil {
+			result.Seo.Robots = *seo.Robots
+		}
+	}
+	if page.Products == nil {
+		return result
+	}
+	result.Products = make([]*Product, 0, len(*page.Products))
+	for _, sparseProduct := range *page.Products {
+		product := &Product{}
+		if sparseProduct.Id != nil {
+			product.Id = int64(*sparseProduct.Id)
+		}
+		if sparseProduct.PageId != nil {
+			product.PageId = int64(*sparseProduct.PageId)
+		}
+		if sparseProduct.Name != nil {
+			product.Name = *sparseProduct.Name
+		}
+		if sparseProduct.Description != nil {
+			product.Description = *sparseProduct.Description
+		}
+		if sparseProduct.Price != nil {
+			product.Price = *sparseProduct.Price
+		}
+		result.Products = append(result.Products, product)
+	}
+	return result
+}
@@ -0,0 +1,375 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.2
+// 	protoc        (unknown)
+// source: src/pagespb/page_service.proto
+
+// gRPC API of pages, served on GRPC_PORT next to the HTTP API.
+// Generated code is committed, run `make generate-proto` after changing this file.
+
+package pagespb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PageResult_Status int32
+
+const (
+	PageResult_STATUS_UNSPECIFIED PageResult_Status = 0
+	PageResult_FOUND              PageResult_Status = 1
+	PageResult_NOT_FOUND          PageResult_Status = 2
+)
+
+// Enum value maps for PageResult_Status.
+var (
+	PageResult_Status_name = map[int32]string{
+		0: "STATUS_UNSPECIFIED",
+		1: "FOUND",
+		2: "NOT_FOUND",
+	}
+	PageResult_Status_value = map[string]int32{
+		"STATUS_UNSPECIFIED": 0,
+		"FOUND":              1,
+		"NOT_FOUND":          2,
+	}
+)
+
+func (x PageResult_Status) Enum() *PageResult_Status {
+	p := new(PageResult_Status)
+	*p = x
+	return p
+}
+
+func (x PageResult_Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PageResult_Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_src_pagespb_page_service_proto_enumTypes[0].Descriptor()
+}
+
+func (PageResult_Status) Type() protoreflect.EnumType {
+	return &file_src_pagespb_page_service_proto_enumTypes[0]
+}
+
+func (x PageResult_Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PageResult_Status.Descriptor instead.
+func (PageResult_Status) EnumDescriptor() ([]byte, []int) {
+	return file_src_pagespb_page_service_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type GetPageRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PageId int64 `protobuf:"varint,1,opt,name=page_id,json=pageId,proto3" json:"page_id,omitempty"`
+}
+
+func (x *GetPageRequest) Reset() {
+	*x = GetPageRequest{}
+	mi := &file_src_pagespb_page_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPageRequest) ProtoMessage() {}
+
+func (x *GetPageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pagespb_page_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPageRequest.ProtoReflect.Descriptor instead.
+func (*GetPageRequest) Descriptor() ([]byte, []int) {
+	return file_src_pagespb_page_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetPageRequest) GetPageId() int64 {
+	if x != nil {
+		return x.PageId
+	}
+	return 0
+}
+
+type GetPagesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PageIds []int64 `protobuf:"varint,1,rep,packed,name=page_ids,json=pageIds,proto3" json:"page_ids,omitempty"`
+}
+
+func (x *GetPagesRequest) Reset() {
+	*x = GetPagesRequest{}
+	mi := &file_src_pagespb_page_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPagesRequest) ProtoMessage() {}
+
+func (x *GetPagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pagespb_page_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPagesRequest.ProtoReflect.Descriptor instead.
+func (*GetPagesRequest) Descriptor() ([]byte, []int) {
+	return file_src_pagespb_page_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetPagesRequest) GetPageIds() []int64 {
+	if x != nil {
+		return x.PageIds
+	}
+	return nil
+}
+
+type GetPagesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pages []*PageResult `protobuf:"bytes,1,rep,name=pages,proto3" json:"pages,omitempty"`
+}
+
+func (x *GetPagesResponse) Reset() {
+	*x = GetPagesResponse{}
+	mi := &file_src_pagespb_page_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPagesResponse) ProtoMessage() {}
+
+func (x *GetPagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pagespb_page_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPagesResponse.ProtoReflect.Descriptor instead.
+func (*GetPagesResponse) Descriptor() ([]byte, []int) {
+	return file_src_pagespb_page_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetPagesResponse) GetPages() []*PageResult {
+	if x != nil {
+		return x.Pages
+	}
+	return nil
+}
+
+// PageResult is a result for one of requested ids, page is set only when status is FOUND.
+type PageResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PageId int64             `protobuf:"varint,1,opt,name=page_id,json=pageId,proto3" json:"page_id,omitempty"`
+	Status PageResult_Status `protobuf:"varint,2,opt,name=status,proto3,enum=pages.PageResult_Status" json:"status,omitempty"`
+	Page   *Page             `protobuf:"bytes,3,opt,name=page,proto3" json:"page,omitempty"`
+}
+
+func (x *PageResult) Reset() {
+	*x = PageResult{}
+	mi := &file_src_pagespb_page_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageResult) ProtoMessage() {}
+
+func (x *PageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pagespb_page_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageResult.ProtoReflect.Descriptor instead.
+func (*PageResult) Descriptor() ([]byte, []int) {
+	return file_src_pagespb_page_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PageResult) GetPageId() int64 {
+	if x != nil {
+		return x.PageId
+	}
+	return 0
+}
+
+func (x *PageResult) GetStatus() PageResult_Status {
+	if x != nil {
+		return x.Status
+	}
+	return PageResult_STATUS_UNSPECIFIED
+}
+
+func (x *PageResult) GetPage() *Page {
+	if x != nil {
+		return x.Page
+	}
+	return nil
+}
+
+var File_src_pagespb_page_service_proto protoreflect.FileDescriptor
+
+var file_src_pagespb_page_service_proto_rawDesc = []byte{
+	0x0a, 0x1e, 0x73, 0x72, 0x63, 0x2f, 0x70, 0x61, 0x67, 0x65, 0x73, 0x70, 0x62, 0x2f, 0x70, 0x61,
+	0x67, 0x65, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x05, 0x70, 0x61, 0x67, 0x65, 0x73, 0x1a, 0x16, 0x73, 0x72, 0x63, 0x2f, 0x70, 0x61, 0x67,
+	0x65, 0x73, 0x70, 0x62, 0x2f, 0x70, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
+	0x29, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x50, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x17, 0x0a, 0x07, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x06, 0x70, 0x61, 0x67, 0x65, 0x49, 0x64, 0x22, 0x2c, 0x0a, 0x0f, 0x47, 0x65,
+	0x74, 0x50, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x19, 0x0a,
+	0x08, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x03, 0x52,
+	0x07, 0x70, 0x61, 0x67, 0x65, 0x49, 0x64, 0x73, 0x22, 0x3b, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x50,
+	0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x27, 0x0a, 0x05,
+	0x70, 0x61, 0x67, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x61,
+	0x67, 0x65, 0x73, 0x2e, 0x50, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x05,
+	0x70, 0x61, 0x67, 0x65, 0x73, 0x22, 0xb4, 0x01, 0x0a, 0x0a, 0x50, 0x61, 0x67, 0x65, 0x52, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x70, 0x61, 0x67, 0x65, 0x49, 0x64, 0x12, 0x30, 0x0a,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e,
+	0x70, 0x61, 0x67, 0x65, 0x73, 0x2e, 0x50, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12,
+	0x1f, 0x0a, 0x04, 0x70, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e,
+	0x70, 0x61, 0x67, 0x65, 0x73, 0x2e, 0x50, 0x61, 0x67, 0x65, 0x52, 0x04, 0x70, 0x61, 0x67, 0x65,
+	0x22, 0x3a, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x12, 0x53, 0x54,
+	0x41, 0x54, 0x55, 0x53, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44,
+	0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x46, 0x4f, 0x55, 0x4e, 0x44, 0x10, 0x01, 0x12, 0x0d, 0x0a,
+	0x09, 0x4e, 0x4f, 0x54, 0x5f, 0x46, 0x4f, 0x55, 0x4e, 0x44, 0x10, 0x02, 0x32, 0x79, 0x0a, 0x0b,
+	0x50, 0x61, 0x67, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x2d, 0x0a, 0x07, 0x47,
+	0x65, 0x74, 0x50, 0x61, 0x67, 0x65, 0x12, 0x15, 0x2e, 0x70, 0x61, 0x67, 0x65, 0x73, 0x2e, 0x47,
+	0x65, 0x74, 0x50, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e,
+	0x70, 0x61, 0x67, 0x65, 0x73, 0x2e, 0x50, 0x61, 0x67, 0x65, 0x12, 0x3b, 0x0a, 0x08, 0x47, 0x65,
+	0x74, 0x50, 0x61, 0x67, 0x65, 0x73, 0x12, 0x16, 0x2e, 0x70, 0x61, 0x67, 0x65, 0x73, 0x2e, 0x47,
+	0x65, 0x74, 0x50, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17,
+	0x2e, 0x70, 0x61, 0x67, 0x65, 0x73, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x61, 0x67, 0x65, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x28, 0x5a, 0x26, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x72, 0x65, 0x6d, 0x69, 0x6b, 0x6a, 0x2f, 0x70, 0x61, 0x67,
+	0x65, 0x73, 0x2d, 0x6d, 0x73, 0x2f, 0x73, 0x72, 0x63, 0x2f, 0x70, 0x61, 0x67, 0x65, 0x73, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_src_pagespb_page_service_proto_rawDescOnce sync.Once
+	file_src_pagespb_page_service_proto_rawDescData = file_src_pagespb_page_service_proto_rawDesc
+)
+
+func file_src_pagespb_page_service_proto_rawDescGZIP() []byte {
+	file_src_pagespb_page_service_proto_rawDescOnce.Do(func() {
+		file_src_pagespb_page_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_src_pagespb_page_service_proto_rawDescData)
+	})
+	return file_src_pagespb_page_service_proto_rawDescData
+}
+
+var file_src_pagespb_page_service_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_src_pagespb_page_service_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_src_pagespb_page_service_proto_goTypes = []any{
+	(PageResult_Status)(0),   // 0: pages.PageResult.Status
+	(*GetPageRequest)(nil),   // 1: pages.GetPageRequest
+	(*GetPagesRequest)(nil),  // 2: pages.GetPagesRequest
+	(*GetPagesResponse)(nil), // 3: pages.GetPagesResponse
+	(*PageResult)(nil),       // 4: pages.PageResult
+	(*Page)(nil),             // 5: pages.Page
+}
+var file_src_pagespb_page_service_proto_depIdxs = []int32{
+	4, // 0: pages.GetPagesResponse.pages:type_name -> pages.PageResult
+	0, // 1: pages.PageResult.status:type_name -> pages.PageResult.Status
+	5, // 2: pages.PageResult.page:type_name -> pages.Page
+	1, // 3: pages.PageService.GetPage:input_type -> pages.GetPageRequest
+	2, // 4: pages.PageService.GetPages:input_type -> pages.GetPagesRequest
+	5, // 5: pages.PageService.GetPage:output_type -> pages.Page
+	3, // 6: pages.PageService.GetPages:output_type -> pages.GetPagesResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_src_pagespb_page_service_proto_init() }
+func file_src_pagespb_page_service_proto_init() {
+	if File_src_pagespb_page_service_proto != nil {
+		return
+	}
+	file_src_pagespb_page_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_src_pagespb_page_service_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_src_pagespb_page_service_proto_goTypes,
+		DependencyIndexes: file_src_pagespb_page_service_proto_depIdxs,
+		EnumInfos:         file_src_pagespb_page_service_proto_enumTypes,
+		MessageInfos:      file_src_pagespb_page_service_proto_msgTypes,
+	}.Build()
+	File_src_pagespb_page_service_proto = out.File
+	file_src_pagespb_page_service_proto_rawDesc = nil
+	file_src_pagespb_page_service_proto_goTypes = nil
+	file_src_pagespb_page_service_proto_depIdxs = nil
+}
@@ -0,0 +1,166 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: src/pagespb/page_service.proto
+
+// gRPC API of pages, served on GRPC_PORT next to the HTTP API.
+// Generated code is committed, run `make generate-proto` after changing this file.
+
+package pagespb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PageService_GetPage_FullMethodName  = "/pages.PageService/GetPage"
+	PageService_GetPages_FullMethodName = "/pages.PageService/GetPages"
+)
+
+// PageServiceClient is the client API for PageService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PageServiceClient interface {
+	// GetPage returns page with its products, NOT_FOUND status is returned when the page does not exist.
+	GetPage(ctx context.Context, in *GetPageRequest, opts ...grpc.CallOption) (*Page, error)
+	// GetPages returns pages in requested order, duplicated ids are returned once.
+	GetPages(ctx context.Context, in *GetPagesRequest, opts ...grpc.CallOption) (*GetPagesResponse, error)
+}
+
+type pageServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPageServiceClient(cc grpc.ClientConnInterface) PageServiceClient {
+	return &pageServiceClient{cc}
+}
+
+func (c *pageServiceClient) GetPage(ctx context.Context, in *GetPageRequest, opts ...grpc.CallOption) (*Page, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Page)
+	err := c.cc.Invoke(ctx, PageService_GetPage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pageServiceClient) GetPages(ctx context.Context, in *GetPagesRequest, opts ...grpc.CallOption) (*GetPagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPagesResponse)
+	err := c.cc.Invoke(ctx, PageService_GetPages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PageServiceServer is the server API for PageService service.
+// All implementations must embed UnimplementedPageServiceServer
+// for forward compatibility.
+type PageServiceServer interface {
+	// GetPage returns page with its products, NOT_FOUND status is returned when the page does not exist.
+	GetPage(context.Context, *GetPageRequest) (*Page, error)
+	// GetPages returns pages in requested order, duplicated ids are returned once.
+	GetPages(context.Context, *GetPagesRequest) (*GetPagesResponse, error)
+	mustEmbedUnimplementedPageServiceServer()
+}
+
+// UnimplementedPageServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPageServiceServer struct{}
+
+func (UnimplementedPageServiceServer) GetPage(context.Context, *GetPageRequest) (*Page, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPage not implemented")
+}
+func (UnimplementedPageServiceServer) GetPages(context.Context, *GetPagesRequest) (*GetPagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPages not implemented")
+}
+func (UnimplementedPageServiceServer) mustEmbedUnimplementedPageServiceServer() {}
+func (UnimplementedPageServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafePageServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PageServiceServer will
+// result in compilation errors.
+type UnsafePageServiceServer interface {
+	mustEmbedUnimplementedPageServiceServer()
+}
+
+func RegisterPageServiceServer(s grpc.ServiceRegistrar, srv PageServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPageServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PageService_ServiceDesc, srv)
+}
+
+func _PageService_GetPage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PageServiceServer).GetPage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PageService_GetPage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PageServiceServer).GetPage(ctx, req.(*GetPageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PageService_GetPages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PageServiceServer).GetPages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PageService_GetPages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PageServiceServer).GetPages(ctx, req.(*GetPagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PageService_ServiceDesc is the grpc.ServiceDesc for PageService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PageService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pages.PageService",
+	HandlerType: (*PageServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPage",
+			Handler:    _PageService_GetPage_Handler,
+		},
+		{
+			MethodName: "GetPages",
+			Handler:    _PageService_GetPages_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "src/pagespb/page_service.proto",
+}
@@ -0,0 +1,73 @@
+package problem
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+)
+
+// ContentType of RFC 7807 problem details.
+const ContentType = "application/problem+json"
+
+// Code identifies kind of the problem. Codes are part of the API, so they must not be changed.
+type Code string
+
+const (
+	CodeInvalidId          Code = "invalid_id"
+	CodeInvalidBody        Code = "invalid_body"
+	CodeInvalidRequest     Code = "invalid_request"
+	CodeInvalidFields      Code = "invalid_fields"
+	CodeInvalidQuery       Code = "invalid_query"
+	CodeNotFound           Code = "not_found"
+	CodeAlreadyExists      Code = "already_exists"
+	CodePreconditionFailed Code = "precondition_failed"
+	CodeNotAcceptable      Code = "not_acceptable"
+	CodeRateLimited        Code = "rate_limited"
+	CodeTimeout            Code = "timeout"
+	CodeBackendUnavailable Code = "backend_unavailable"
+	CodeOverloaded         Code = "overloaded"
+	CodeDataIntegrity      Code = "data_integrity"
+	CodeInvalidResponse    Code = "invalid_response"
+	CodeInternal           Code = "internal_error"
+)
+
+// typePrefix makes Type of the problem an URI, as required by RFC 7807.
+const typePrefix = "urn:pages-ms:problem:"
+
+// Problem is RFC 7807 problem details, extended with Code of the problem and id of the request.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Code      Code   `json:"code"`
+	Detail    string `json:"detail,omitempty"`
+	RequestId string `json:"request_id,omitempty"`
+}
+
+func New(status int, code Code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + string(code),
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// Write responds with the problem, id of the request is taken from request context.
+func Write(writer http.ResponseWriter, request *http.Request, logger *slog.Logger, status int, code Code, detail string) {
+	problem := New(status, code, detail)
+	problem.RequestId = middleware.GetReqID(request.Context())
+	marshal, err := json.Marshal(problem)
+	if err != nil {
+		logger.Error("failed to marshal problem", "error", err)
+		writer.WriteHeader(status)
+		return
+	}
+	writer.Header().Set("Content-Type", ContentType)
+	writer.WriteHeader(status)
+	if _, err := writer.Write(marshal); err != nil {
+		logger.Warn("failed to write response", "error", err)
+	}
+}
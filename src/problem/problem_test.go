@@ -0,0 +1,44 @@
+package problem
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestId    string
+		expectedBody string
+	}{
+		{
+			name:         "should write problem with request id, when request has id",
+			requestId:    "request-1",
+			expectedBody: `{"type":"urn:pages-ms:problem:not_found","title":"Not Found","status":404,"code":"not_found","detail":"page not found","request_id":"request-1"}`,
+		},
+		{
+			name:         "should write problem without request id, when request has no id",
+			expectedBody: `{"type":"urn:pages-ms:problem:not_found","title":"Not Found","status":404,"code":"not_found","detail":"page not found"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/pages/1", nil)
+			if tt.requestId != "" {
+				request = request.WithContext(context.WithValue(request.Context(), middleware.RequestIDKey, tt.requestId))
+			}
+			recorder := httptest.NewRecorder()
+
+			Write(recorder, request, logging.Discard(), http.StatusNotFound, CodeNotFound, "page not found")
+
+			assert.Equal(t, http.StatusNotFound, recorder.Code)
+			assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package memoryimpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/model"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Configuration struct {
+	SeedSeosFile     string `envconfig:"MEMORY_SEED_SEOS_FILE"`
+	SeedProductsFile string `envconfig:"MEMORY_SEED_PRODUCTS_FILE"`
+}
+
+// PageRepositoryMemory keeps pages in memory, it is safe for concurrent use.
+// Returned values are copies, so they can be modified by callers.
+type PageRepositoryMemory struct {
+	logger   *slog.Logger
+	mutex    sync.RWMutex
+	seos     map[int]model.SEO
+	products map[int][]model.Product
+}
+
+// seoDocument and productDocument have the same fields as documents in mongo,
+// so the repository can be seeded with files used for mongoimport.
+type seoDocument struct {
+	PageId      int       `json:"page_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Robots      string    `json:"robots"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type productDocument struct {
+	Id          int     `json:"id"`
+	PageId      int     `json:"page_id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+}
+
+// InitPageRepositoryMemoryFromEnv creates empty repository, seeded with seos and products
+// from JSON arrays in MEMORY_SEED_SEOS_FILE and MEMORY_SEED_PRODUCTS_FILE when they are set.
+func InitPageRepositoryMemoryFromEnv(logger *slog.Logger) (*PageRepositoryMemory, error) {
+	config := &Configuration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	repository := NewPageRepositoryMemory(logger)
+	if err := repository.SeedFromFiles(config.SeedSeosFile, config.SeedProductsFile); err != nil {
+		return nil, err
+	}
+	return repository, nil
+}
+
+func NewPageRepositoryMemory(logger *slog.Logger) *PageRepositoryMemory {
+	return &PageRepositoryMemory{
+		logger:   logger,
+		seos:     make(map[int]model.SEO),
+		products: make(map[int][]model.Product),
+	}
+}
+
+// SeedFromFiles adds seos and products from JSON array files, empty file name is skipped.
+func (p *PageRepositoryMemory) SeedFromFiles(seosFile string, productsFile string) error {
+	var seoDocuments []seoDocument
+	if err := readJsonFile(seosFile, &seoDocuments); err != nil {
+		return err
+	}
+	var productDocuments []productDocument
+	if err := readJsonFile(productsFile, &productDocuments); err != nil {
+		return err
+	}
+	seos := make([]model.SEO, 0, len(seoDocuments))
+	for _, document := range seoDocuments {
+		seos = append(seos, model.SEO(document))
+	}
+	products := make([]model.Product, 0, len(productDocuments))
+	for _, document := range productDocuments {
+		products = append(products, model.Product(document))
+	}
+	p.Seed(seos, products)
+	p.logger.Info("seeded in-memory repository", "seos", len(seos), "products", len(products))
+	return nil
+}
+
+// Seed adds seos and products, replacing existing ones with the same ids.
+// Products do not need to belong to existing pages.
+func (p *PageRepositoryMemory) Seed(seos []model.SEO, products []model.Product) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, seo := range seos {
+		p.seos[seo.PageId] = seo
+	}
+	for _, product := range products {
+		if index := p.productIndex(product.PageId, product.Id); index >= 0 {
+			p.products[product.PageId][index] = product
+		} else {
+			p.products[product.PageId] = append(p.products[product.PageId], product)
+		}
+	}
+}
+
+func readJsonFile(fileName string, target interface{}) error {
+	if fileName == "" {
+		return nil
+	}
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to read seed file: %w", err)
+	}
+	if err := json.Unmarshal(content, target); err != nil {
+		return fmt.Errorf("failed to decode seed file %v: %w", fileName, err)
+	}
+	return nil
+}
+
+func (p *PageRepositoryMemory) GetSeoForPage(_ context.Context, pageId int) (*model.SEO, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	seo, ok := p.seos[pageId]
+	if !ok {
+		return nil, nil
+	}
+	return &seo, nil
+}
+
+func (p *PageRepositoryMemory) GetProductsForPage(_ context.Context, pageId int) ([]model.Product, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return append([]model.Product(nil), p.products[pageId]...), nil
+}
+
+func (p *PageRepositoryMemory) GetSeosForPages(_ context.Context, pageIds []int) ([]model.SEO, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	var seos []model.SEO
+	for _, pageId := range pageIds {
+		if seo, ok := p.seos[pageId]; ok {
+			seos = append(seos, seo)
+		}
+	}
+	return seos, nil
+}
+
+func (p *PageRepositoryMemory) GetProductsForPages(_ context.Context, pageIds []int, limit int) ([]model.Product, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	var products []model.Product
+	for _, pageId := range pageIds {
+		pageProducts := p.products[pageId]
+		if limit > 0 {
+			pageProducts = append([]model.Product{}, pageProducts...)
+			sort.Slice(pageProducts, func(i, j int) bool { return pageProducts[i].Id < pageProducts[j].Id })
+			pageProducts = pageProducts[:min(limit, len(pageProducts))]
+		}
+		products = append(products, pageProducts...)
+	}
+	return products, nil
+}
+
+func (p *PageRepositoryMemory) CreatePage(_ context.Context, page *model.Page) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, ok := p.seos[page.SEO.PageId]; ok {
+		return model.ErrPageAlreadyExists
+	}
+	page.SEO.Touch(time.Now())
+	p.seos[page.SEO.PageId] = page.SEO
+	p.products[page.SEO.PageId] = append([]model.Product(nil), page.Products...)
+	return nil
+}
+
+func (p *PageRepositoryMemory) ReplacePage(_ context.Context, page *model.Page) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, ok := p.seos[page.SEO.PageId]; !ok {
+		return model.ErrPageNotFound
+	}
+	page.SEO.Touch(time.Now())
+	p.seos[page.SEO.PageId] = page.SEO
+	p.products[page.SEO.PageId] = append([]model.Product(nil), page.Products...)
+	return nil
+}
+
+func (p *PageRepositoryMemory) DeletePage(_ context.Context, pageId int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.products, pageId)
+	if _, ok := p.seos[pageId]; !ok {
+		return model.ErrPageNotFound
+	}
+	delete(p.seos, pageId)
+	return nil
+}
+
+func (p *PageRepositoryMemory) GetProductForPage(_ context.Context, pageId int, productId int) (*model.Product, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	index := p.productIndex(pageId, productId)
+	if index < 0 {
+		return nil, nil
+	}
+	product := p.products[pageId][index]
+	return &product, nil
+}
+
+func (p *PageRepositoryMemory) QueryProductsForPage(_ context.Context, pageId int, query *model.ProductQuery) ([]model.Product, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	products := make([]model.Product, 0)
+	for _, product := range p.products[pageId] {
+		if query.Matches(&product) {
+			products = append(products, product)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return query.Sort.Less(&products[i], &products[j])
+	})
+	if len(products) > query.Limit {
+		products = products[:query.Limit]
+	}
+	return products, nil
+}
+
+func (p *PageRepositoryMemory) CountProductsForPage(_ context.Context, pageId int, query *model.ProductQuery) (int, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	count := 0
+	for _, product := range p.products[pageId] {
+		if query.Filter(&product) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (p *PageRepositoryMemory) CreateProduct(_ context.Context, product *model.Product) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, ok := p.seos[product.PageId]; !ok {
+		return model.ErrPageNotFound
+	}
+	if p.productIndex(product.PageId, product.Id) >= 0 {
+		return model.ErrProductAlreadyExists
+	}
+	p.products[product.PageId] = append(p.products[product.PageId], *product)
+	p.touchPage(product.PageId)
+	return nil
+}
+
+func (p *PageRepositoryMemory) ReplaceProduct(_ context.Context, product *model.Product) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	index := p.productIndex(product.PageId, product.Id)
+	if index < 0 {
+		return model.ErrProductNotFound
+	}
+	p.products[product.PageId][index] = *product
+	p.touchPage(product.PageId)
+	return nil
+}
+
+func (p *PageRepositoryMemory) DeleteProduct(_ context.Context, pageId int, productId int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	index := p.productIndex(pageId, productId)
+	if index < 0 {
+		return model.ErrProductNotFound
+	}
+	products := p.products[pageId]
+	p.products[pageId] = append(products[:index:index], products[index+1:]...)
+	p.touchPage(pageId)
+	return nil
+}
+
+func (p *PageRepositoryMemory) Ping(_ context.Context) error {
+	return nil
+}
+
+func (p *PageRepositoryMemory) CloseRepository() error {
+	return nil
+}
+
+// touchPage marks existing page as changed now. It has to be called with mutex held.
+func (p *PageRepositoryMemory) touchPage(pageId int) {
+	if seo, ok := p.seos[pageId]; ok {
+		seo.Touch(time.Now())
+		p.seos[pageId] = seo
+	}
+}
+
+// productIndex returns index of the product in products of the page, or -1 when there is no such product.
+// It has to be called with mutex held.
+func (p *PageRepositoryMemory) productIndex(pageId int, productId int) int {
+	for index, product := range p.products[pageId] {
+		if product.Id == productId {
+			return index
+		}
+	}
+	return -1
+}
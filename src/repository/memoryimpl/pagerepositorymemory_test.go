@@ -0,0 +1,223 @@
+package memoryimpl
+
+import (
+	"context"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+var (
+	sampleSeo     = model.SEO{PageId: 1, Title: "title", Description: "description", Robots: "robots"}
+	sampleProduct = model.Product{Id: 2, PageId: 1, Name: "name", Description: "description", Price: 2.5}
+	samplePage    = model.Page{SEO: sampleSeo, Products: []model.Product{sampleProduct}}
+)
+
+func TestPageRepositoryMemory_CreateAndGetPage(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	ctx := context.Background()
+	page := samplePage
+
+	require.NoError(t, p.CreatePage(ctx, &page))
+	seo, seoErr := p.GetSeoForPage(ctx, 1)
+	products, productsErr := p.GetProductsForPage(ctx, 1)
+	missingSeo, missingSeoErr := p.GetSeoForPage(ctx, 2)
+
+	assert.NoError(t, seoErr)
+	assert.NoError(t, productsErr)
+	assert.NoError(t, missingSeoErr)
+	assert.Equal(t, &page.SEO, seo)
+	assert.False(t, seo.UpdatedAt.IsZero())
+	assert.Equal(t, []model.Product{sampleProduct}, products)
+	assert.Nil(t, missingSeo)
+	assert.Equal(t, model.ErrPageAlreadyExists, p.CreatePage(ctx, &samplePage))
+}
+
+func TestPageRepositoryMemory_shouldReturnCopies(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	ctx := context.Background()
+	page := model.Page{SEO: sampleSeo, Products: []model.Product{sampleProduct}}
+	require.NoError(t, p.CreatePage(ctx, &page))
+
+	page.Products[0].Name = "changed after create"
+	products, _ := p.GetProductsForPage(ctx, 1)
+	products[0].Name = "changed after get"
+	seo, _ := p.GetSeoForPage(ctx, 1)
+	seo.Title = "changed after get"
+
+	storedProducts, _ := p.GetProductsForPage(ctx, 1)
+	storedSeo, _ := p.GetSeoForPage(ctx, 1)
+	assert.Equal(t, []model.Product{sampleProduct}, storedProducts)
+	assert.Equal(t, sampleSeo.Title, storedSeo.Title)
+}
+
+func TestPageRepositoryMemory_ReplaceAndDeletePage(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	ctx := context.Background()
+	replacement := &model.Page{SEO: model.SEO{PageId: 1, Title: "new title"}, Products: []model.Product{}}
+
+	assert.Equal(t, model.ErrPageNotFound, p.ReplacePage(ctx, replacement))
+	require.NoError(t, p.CreatePage(ctx, &samplePage))
+	require.NoError(t, p.ReplacePage(ctx, replacement))
+	seo, _ := p.GetSeoForPage(ctx, 1)
+	products, _ := p.GetProductsForPage(ctx, 1)
+	assert.Equal(t, &replacement.SEO, seo)
+	assert.Empty(t, products)
+
+	require.NoError(t, p.DeletePage(ctx, 1))
+	seo, _ = p.GetSeoForPage(ctx, 1)
+	assert.Nil(t, seo)
+	assert.Equal(t, model.ErrPageNotFound, p.DeletePage(ctx, 1))
+}
+
+func TestPageRepositoryMemory_Products(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	ctx := context.Background()
+	otherProduct := model.Product{Id: 3, PageId: 1, Name: "other"}
+
+	assert.Equal(t, model.ErrPageNotFound, p.CreateProduct(ctx, &otherProduct))
+	require.NoError(t, p.CreatePage(ctx, &samplePage))
+	require.NoError(t, p.CreateProduct(ctx, &otherProduct))
+	assert.Equal(t, model.ErrProductAlreadyExists, p.CreateProduct(ctx, &otherProduct))
+
+	replacedProduct := model.Product{Id: 2, PageId: 1, Name: "replaced"}
+	require.NoError(t, p.ReplaceProduct(ctx, &replacedProduct))
+	product, err := p.GetProductForPage(ctx, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &replacedProduct, product)
+
+	require.NoError(t, p.DeleteProduct(ctx, 1, 2))
+	products, _ := p.GetProductsForPage(ctx, 1)
+	assert.Equal(t, []model.Product{otherProduct}, products)
+	missingProduct, err := p.GetProductForPage(ctx, 1, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, missingProduct)
+	assert.Equal(t, model.ErrProductNotFound, p.DeleteProduct(ctx, 1, 2))
+	assert.Equal(t, model.ErrProductNotFound, p.ReplaceProduct(ctx, &replacedProduct))
+}
+
+func TestPageRepositoryMemory_QueryProductsForPage(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	ctx := context.Background()
+	products := []model.Product{
+		{Id: 1, PageId: 1, Name: "Blue shirt", Price: 20},
+		{Id: 2, PageId: 1, Name: "Red shirt", Price: 10},
+		{Id: 3, PageId: 1, Name: "Shoes", Price: 20},
+		{Id: 4, PageId: 1, Name: "Green SHIRT", Price: 15},
+		{Id: 5, PageId: 1, Name: "Hat", Price: 5},
+	}
+	require.NoError(t, p.CreatePage(ctx, &model.Page{SEO: sampleSeo, Products: products}))
+	minPrice := 10.0
+	query := &model.ProductQuery{Limit: 2, Sort: model.SortByPriceDesc, MinPrice: &minPrice}
+
+	firstPage, firstErr := p.QueryProductsForPage(ctx, 1, query)
+	query.After = model.CursorAfter(query.Sort, &firstPage[len(firstPage)-1])
+	secondPage, secondErr := p.QueryProductsForPage(ctx, 1, query)
+	count, countErr := p.CountProductsForPage(ctx, 1, query)
+
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.NoError(t, countErr)
+	assert.Equal(t, []model.Product{products[0], products[2]}, firstPage)
+	assert.Equal(t, []model.Product{products[3], products[1]}, secondPage)
+	assert.Equal(t, 4, count)
+
+	nameQuery := &model.ProductQuery{Limit: 10, Sort: model.SortByName, NameContains: "shirt"}
+	byName, err := p.QueryProductsForPage(ctx, 1, nameQuery)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Product{products[0], products[3], products[1]}, byName)
+	otherPage, err := p.QueryProductsForPage(ctx, 2, nameQuery)
+	assert.NoError(t, err)
+	assert.Empty(t, otherPage)
+}
+
+func TestPageRepositoryMemory_shouldTouchPage_whenProductsChange(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	ctx := context.Background()
+	p.Seed([]model.SEO{sampleSeo}, nil)
+	product := model.Product{Id: 3, PageId: 1, Name: "name"}
+
+	require.NoError(t, p.CreateProduct(ctx, &product))
+	afterCreate, _ := p.GetSeoForPage(ctx, 1)
+	require.NoError(t, p.DeleteProduct(ctx, 1, 3))
+	afterDelete, _ := p.GetSeoForPage(ctx, 1)
+
+	assert.False(t, afterCreate.UpdatedAt.IsZero())
+	assert.False(t, afterDelete.UpdatedAt.Before(afterCreate.UpdatedAt))
+}
+
+func TestPageRepositoryMemory_GetSeosAndProductsForPages(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	otherSeo := model.SEO{PageId: 3, Title: "other"}
+	p.Seed([]model.SEO{sampleSeo, otherSeo}, []model.Product{sampleProduct})
+
+	seos, seosErr := p.GetSeosForPages(context.Background(), []int{3, 1, 2})
+	products, productsErr := p.GetProductsForPages(context.Background(), []int{3, 1, 2}, 0)
+
+	assert.NoError(t, seosErr)
+	assert.NoError(t, productsErr)
+	assert.Equal(t, []model.SEO{otherSeo, sampleSeo}, seos)
+	assert.Equal(t, []model.Product{sampleProduct}, products)
+}
+
+func TestPageRepositoryMemory_GetProductsForPages_shouldLimitProductsOfEachPage(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	otherSeo := model.SEO{PageId: 3, Title: "other"}
+	firstProduct := model.Product{Id: 1, PageId: 1, Name: "first"}
+	otherProduct := model.Product{Id: 1, PageId: 3, Name: "other"}
+	p.Seed([]model.SEO{sampleSeo, otherSeo}, []model.Product{sampleProduct, firstProduct, otherProduct})
+
+	products, err := p.GetProductsForPages(context.Background(), []int{1, 3}, 1)
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []model.Product{firstProduct, otherProduct}, products)
+}
+
+func TestPageRepositoryMemory_SeedFromFiles(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+
+	err := p.SeedFromFiles("../../../resources/mongodb/sample-seos.json", "../../../resources/mongodb/sample-products.json")
+
+	require.NoError(t, err)
+	seo, _ := p.GetSeoForPage(context.Background(), 1)
+	products, _ := p.GetProductsForPage(context.Background(), 1)
+	orphanProducts, _ := p.GetProductsForPage(context.Background(), 100)
+	assert.Equal(t, &model.SEO{PageId: 1, Title: "title1", Description: "description1", Robots: "robots1"}, seo)
+	assert.Len(t, products, 2)
+	assert.Len(t, orphanProducts, 1)
+}
+
+func TestPageRepositoryMemory_SeedFromFiles_shouldReturnErr_whenFileMissing(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+
+	err := p.SeedFromFiles("missing-seos.json", "")
+
+	assert.Error(t, err)
+}
+
+func TestPageRepositoryMemory_shouldBeSafeForConcurrentUse(t *testing.T) {
+	p := NewPageRepositoryMemory(logging.Discard())
+	ctx := context.Background()
+	require.NoError(t, p.CreatePage(ctx, &model.Page{SEO: sampleSeo, Products: []model.Product{}}))
+
+	waitGroup := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		waitGroup.Add(2)
+		go func(productId int) {
+			defer waitGroup.Done()
+			assert.NoError(t, p.CreateProduct(ctx, &model.Product{Id: productId, PageId: 1}))
+		}(i)
+		go func() {
+			defer waitGroup.Done()
+			_, err := p.GetProductsForPage(ctx, 1)
+			assert.NoError(t, err)
+		}()
+	}
+	waitGroup.Wait()
+
+	products, _ := p.GetProductsForPage(ctx, 1)
+	assert.Len(t, products, 50)
+}
@@ -0,0 +1,144 @@
+package mongoimpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling mongo while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("mongo circuit breaker is open")
+
+const (
+	circuitClosed   = "closed"
+	circuitHalfOpen = "half_open"
+	circuitOpen     = "open"
+)
+
+// circuitStateValues are values of mongo_circuit_breaker_state gauge.
+var circuitStateValues = map[string]float64{
+	circuitClosed:   0,
+	circuitHalfOpen: 1,
+	circuitOpen:     2,
+}
+
+// circuitBreaker counts failures of calls in consecutive windows. Once a window has at least minRequests calls
+// and ratio of failed ones reaches failureRatio, the breaker opens and rejects calls for openTimeout. Then it is
+// half open and lets a single probe call through, which closes it when it succeeds, or opens it again.
+type circuitBreaker struct {
+	config *ResilienceConfiguration
+	logger *slog.Logger
+	now    func() time.Time
+
+	mutex       sync.Mutex
+	state       string
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+func newCircuitBreaker(config *ResilienceConfiguration, logger *slog.Logger) *circuitBreaker {
+	return &circuitBreaker{
+		config: config,
+		logger: logger,
+		now:    time.Now,
+		state:  circuitClosed,
+	}
+}
+
+// allow returns ErrCircuitOpen when the call has to be rejected. probe is true for the single call let through
+// by half open breaker, its result has to be recorded with record, as well as result of any other allowed call.
+func (b *circuitBreaker) allow() (probe bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := b.now()
+	switch b.state {
+	case circuitOpen:
+		if retryIn := b.openedAt.Add(b.config.BreakerOpenTimeout).Sub(now); retryIn > 0 {
+			return false, fmt.Errorf("%w, retrying in %v", ErrCircuitOpen, retryIn.Round(time.Millisecond))
+		}
+		b.setState(circuitHalfOpen)
+		fallthrough
+	case circuitHalfOpen:
+		if b.probing {
+			return false, fmt.Errorf("%w, waiting for probe call", ErrCircuitOpen)
+		}
+		b.probing = true
+		return true, nil
+	default:
+		if now.Sub(b.windowStart) >= b.config.BreakerWindow {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		return false, nil
+	}
+}
+
+// record counts result of allowed call made with ctx. Calls cancelled by the caller or past its deadline say nothing
+// about mongo and are not counted, results of calls allowed before the breaker opened are ignored.
+func (b *circuitBreaker) record(ctx context.Context, probe bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	cancelled := errors.Is(err, context.Canceled) || (err != nil && ctx.Err() != nil)
+	failed := !cancelled && isFailure(ctx, err)
+	switch {
+	case probe:
+		b.probing = false
+		if cancelled {
+			return
+		}
+		if failed {
+			b.open()
+			return
+		}
+		b.windowStart, b.requests, b.failures = b.now(), 0, 0
+		b.setState(circuitClosed)
+	case b.state == circuitClosed && !cancelled:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.config.BreakerMinRequests && float64(b.failures) >= b.config.BreakerFailureRatio*float64(b.requests) {
+			b.open()
+		}
+	}
+}
+
+// check returns ErrCircuitOpen while the breaker rejects calls, without letting a probe call through.
+// It passes once openTimeout elapses, so readiness does not keep away calls needed to close the breaker.
+func (b *circuitBreaker) check() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state != circuitOpen || !b.now().Before(b.openedAt.Add(b.config.BreakerOpenTimeout)) {
+		return nil
+	}
+	return fmt.Errorf("%w since %v", ErrCircuitOpen, b.openedAt.UTC().Format(time.RFC3339))
+}
+
+func (b *circuitBreaker) currentState() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state
+}
+
+func (b *circuitBreaker) open() {
+	b.openedAt = b.now()
+	b.setState(circuitOpen)
+}
+
+func (b *circuitBreaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+	if state == circuitOpen {
+		b.logger.Warn("mongo circuit breaker opened", "from", b.state, "requests", b.requests, "failures", b.failures,
+			"open_timeout", b.config.BreakerOpenTimeout)
+	} else {
+		b.logger.Info("mongo circuit breaker state changed", "from", b.state, "to", state)
+	}
+	b.state = state
+}
@@ -0,0 +1,136 @@
+package mongoimpl
+
+import (
+	"context"
+	"errors"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+var (
+	sampleTransientErr    = mongo.CommandError{Code: 189, Name: "PrimarySteppedDown"}
+	sampleNetworkErr      = mongo.CommandError{Labels: []string{"NetworkError"}, Message: "connection reset"}
+	sampleDuplicateKeyErr = mongo.CommandError{Code: 11000, Message: "E11000 duplicate key error"}
+)
+
+func newTestCircuitBreaker(now *time.Time) *circuitBreaker {
+	breaker := newCircuitBreaker(&ResilienceConfiguration{
+		BreakerFailureRatio: 0.5,
+		BreakerMinRequests:  4,
+		BreakerWindow:       10 * time.Second,
+		BreakerOpenTimeout:  5 * time.Second,
+	}, logging.Discard())
+	breaker.now = func() time.Time { return *now }
+	return breaker
+}
+
+func recordCalls(breaker *circuitBreaker, errs ...error) {
+	for _, err := range errs {
+		probe, allowErr := breaker.allow()
+		if allowErr == nil {
+			breaker.record(context.Background(), probe, err)
+		}
+	}
+}
+
+func TestCircuitBreaker_shouldOpen_whenFailureRatioReached(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	breaker := newTestCircuitBreaker(&now)
+
+	recordCalls(breaker, nil, sampleTransientErr, nil)
+	assert.Equal(t, circuitClosed, breaker.currentState(), "should stay closed below min requests")
+
+	recordCalls(breaker, sampleNetworkErr)
+	assert.Equal(t, circuitOpen, breaker.currentState())
+	_, err := breaker.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.EqualError(t, breaker.check(), "mongo circuit breaker is open since 2024-03-01T10:00:00Z")
+}
+
+func TestCircuitBreaker_shouldNotCountErrorsOfValidRequestsOrCancelledCalls(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	breaker := newTestCircuitBreaker(&now)
+
+	recordCalls(breaker, sampleDuplicateKeyErr, context.Canceled, context.Canceled, sampleTransientErr, nil, sampleDuplicateKeyErr)
+
+	assert.Equal(t, circuitClosed, breaker.currentState())
+	assert.Equal(t, 4, breaker.requests)
+	assert.Equal(t, 1, breaker.failures)
+}
+
+func TestCircuitBreaker_shouldNotCountTimeouts_whenCallerDeadlineExceeded(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	breaker := newTestCircuitBreaker(&now)
+	expiredCtx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		probe, err := breaker.allow()
+		assert.NoError(t, err)
+		breaker.record(expiredCtx, probe, context.DeadlineExceeded)
+	}
+	recordCalls(breaker, nil, context.DeadlineExceeded)
+
+	assert.Equal(t, circuitClosed, breaker.currentState())
+	assert.Equal(t, 2, breaker.requests)
+	assert.Equal(t, 1, breaker.failures, "should count timeout of mongo, when caller is still waiting")
+}
+
+func TestCircuitBreaker_shouldStartNewWindow_whenWindowElapsed(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	breaker := newTestCircuitBreaker(&now)
+
+	recordCalls(breaker, sampleTransientErr, sampleTransientErr, sampleTransientErr)
+	now = now.Add(10 * time.Second)
+	recordCalls(breaker, sampleTransientErr, nil, nil)
+
+	assert.Equal(t, circuitClosed, breaker.currentState())
+}
+
+func TestCircuitBreaker_shouldLetSingleProbeThrough_whenOpenTimeoutElapsed(t *testing.T) {
+	tests := []struct {
+		name          string
+		probeErr      error
+		expectedState string
+	}{
+		{name: "should close, when probe succeeds", probeErr: nil, expectedState: circuitClosed},
+		{name: "should open again, when probe fails", probeErr: sampleNetworkErr, expectedState: circuitOpen},
+		{name: "should stay half open, when probe cancelled", probeErr: context.Canceled, expectedState: circuitHalfOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+			breaker := newTestCircuitBreaker(&now)
+			recordCalls(breaker, sampleTransientErr, sampleTransientErr, sampleTransientErr, sampleTransientErr)
+			inFlightProbe, inFlightErr := false, error(nil)
+
+			now = now.Add(5 * time.Second)
+			assert.NoError(t, breaker.check(), "should be ready, once calls are let through")
+			probe, err := breaker.allow()
+			assert.True(t, probe)
+			assert.NoError(t, err)
+			_, err = breaker.allow()
+			assert.ErrorIs(t, err, ErrCircuitOpen, "should reject calls while probing")
+			breaker.record(context.Background(), inFlightProbe, inFlightErr)
+			assert.Equal(t, circuitHalfOpen, breaker.currentState(), "should ignore calls allowed before opening")
+			breaker.record(context.Background(), probe, tt.probeErr)
+
+			assert.Equal(t, tt.expectedState, breaker.currentState())
+			assert.False(t, breaker.probing)
+		})
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, isTransient(sampleTransientErr))
+	assert.True(t, isTransient(sampleNetworkErr))
+	assert.True(t, isTransient(mongo.CommandError{Labels: []string{"RetryableWriteError"}}))
+	assert.False(t, isTransient(sampleDuplicateKeyErr))
+	assert.False(t, isTransient(context.DeadlineExceeded))
+	assert.False(t, isTransient(errors.New("decoding failed")))
+	assert.True(t, isFailure(context.Background(), context.DeadlineExceeded))
+	assert.False(t, isFailure(context.Background(), nil))
+}
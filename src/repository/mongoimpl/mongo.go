@@ -3,10 +3,15 @@ package mongoimpl
 import (
 	"context"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/model"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"log/slog"
+	"regexp"
+	"time"
 )
 
 type Configuration struct {
@@ -17,8 +22,24 @@ type Configuration struct {
 }
 
 type Client interface {
-	FindSeos(ctx context.Context, pageId int) (MongoCursor, error)
-	FindProducts(ctx context.Context, pageId int) (MongoCursor, error)
+	FindSeos(ctx context.Context, pageId int, projection []string) (MongoCursor, error)
+	FindProducts(ctx context.Context, pageId int, projection []string) (MongoCursor, error)
+	AggregatePage(ctx context.Context, pageId int, productsLimit int) (MongoCursor, error)
+	FindSeosByPageIds(ctx context.Context, pageIds []int) (MongoCursor, error)
+	FindProductsByPageIds(ctx context.Context, pageIds []int) (MongoCursor, error)
+	InsertSeo(ctx context.Context, seo model.SEO) error
+	ReplaceSeo(ctx context.Context, seo model.SEO) (bool, error)
+	DeleteSeo(ctx context.Context, pageId int) (bool, error)
+	TouchSeo(ctx context.Context, pageId int, updatedAt time.Time) error
+	InsertProducts(ctx context.Context, products []model.Product) error
+	DeleteProducts(ctx context.Context, pageId int) error
+	FindProduct(ctx context.Context, pageId int, productId int) (MongoCursor, error)
+	QueryProducts(ctx context.Context, pageId int, query *model.ProductQuery) (MongoCursor, error)
+	CountProducts(ctx context.Context, pageId int, query *model.ProductQuery) (int64, error)
+	InsertProduct(ctx context.Context, product model.Product) error
+	ReplaceProduct(ctx context.Context, product model.Product) (bool, error)
+	DeleteProduct(ctx context.Context, pageId int, productId int) (bool, error)
+	Ping(ctx context.Context) error
 	CloseMongoClient() error
 }
 
@@ -35,7 +56,7 @@ type ClientImpl struct {
 	mongoClient *mongo.Client
 }
 
-func InitMongoFromEnv() (*ClientImpl, error) {
+func InitMongoFromEnv(logger *slog.Logger) (*ClientImpl, error) {
 	config, err := loadConfigurationFromEnv()
 	if err != nil {
 		return nil, err
@@ -53,10 +74,32 @@ func InitMongoFromEnv() (*ClientImpl, error) {
 	if err := mongoClient.Ping(context.TODO(), readpref.Primary()); err != nil {
 		return nil, err
 	}
-	return &ClientImpl{
+	client := &ClientImpl{
 		config:      config,
 		mongoClient: mongoClient,
-	}, nil
+	}
+	if err := client.ensureIndexes(context.TODO()); err != nil {
+		logger.Warn("failed to create unique indexes, uniqueness is checked only before writes", "error", err)
+	}
+	return client, nil
+}
+
+// ensureIndexes creates unique indexes for seo page_id and for product id within a page,
+// and indexes for queries of products of a page sorted by price or name.
+func (c ClientImpl) ensureIndexes(ctx context.Context) error {
+	_, err := c.collection("seos").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    byPageId(1),
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.collection("products").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: byPageIdAndId(1, 1), Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "page_id", Value: 1}, {Key: "price", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "page_id", Value: 1}, {Key: "name", Value: 1}, {Key: "id", Value: 1}}},
+	})
+	return err
 }
 
 func loadConfigurationFromEnv() (*Configuration, error) {
@@ -65,20 +108,254 @@ func loadConfigurationFromEnv() (*Configuration, error) {
 	return config, err
 }
 
-func (c ClientImpl) FindSeos(ctx context.Context, pageId int) (MongoCursor, error) {
-	return c.findInCollectionByPageId(ctx, pageId, "seos")
+// FindSeos finds seos of the page. Only keys listed in projection are read, or whole documents when it is nil.
+func (c ClientImpl) FindSeos(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+	return c.findInCollectionByPageId(ctx, pageId, "seos", projection)
+}
+
+// FindProducts finds products of the page. Only keys listed in projection are read, or whole documents when it is nil.
+func (c ClientImpl) FindProducts(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+	return c.findInCollectionByPageId(ctx, pageId, "products", projection)
+}
+
+func (c ClientImpl) FindSeosByPageIds(ctx context.Context, pageIds []int) (_ MongoCursor, err error) {
+	defer observeQuery("seos", "find_many", time.Now(), &err)
+	return c.collection("seos").Find(ctx, byPageIdIn(pageIds))
+}
+
+// FindProductsByPageIds finds products of the pages ordered by page_id and id, as the unique index is.
+func (c ClientImpl) FindProductsByPageIds(ctx context.Context, pageIds []int) (_ MongoCursor, err error) {
+	defer observeQuery("products", "find_many", time.Now(), &err)
+	return c.collection("products").Find(ctx, byPageIdIn(pageIds), options.Find().SetSort(byPageIdAndId(1, 1)))
+}
+
+// AggregatePage finds seo of the page joined with its products, in a single query.
+// Seo is returned once for each product, with the product in "products" field, or once without it when
+// the page has no products. Mongo coalesces $unwind into $lookup, so all products are never in one document.
+func (c ClientImpl) AggregatePage(ctx context.Context, pageId int, productsLimit int) (_ MongoCursor, err error) {
+	defer observeQuery("seos", "aggregate", time.Now(), &err)
+	return c.collection("seos").Aggregate(ctx, pagePipeline(pageId, productsLimit))
+}
+
+// pagePipeline joins seo of the page with its products. When productsLimit is above 0, only that many
+// products with the lowest ids are joined. $lookup with both localField and pipeline requires MongoDB 5.0.
+func pagePipeline(pageId int, productsLimit int) mongo.Pipeline {
+	lookup := bson.D{
+		{Key: "from", Value: "products"},
+		{Key: "localField", Value: "page_id"},
+		{Key: "foreignField", Value: "page_id"},
+		{Key: "as", Value: "products"},
+	}
+	if productsLimit > 0 {
+		lookup = append(lookup, bson.E{Key: "pipeline", Value: mongo.Pipeline{
+			{{Key: "$sort", Value: bson.D{{Key: "id", Value: 1}}}},
+			{{Key: "$limit", Value: productsLimit}},
+		}})
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: byPageId(pageId)}},
+		{{Key: "$lookup", Value: lookup}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$products"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+	}
+}
+
+func (c ClientImpl) InsertSeo(ctx context.Context, seo model.SEO) (err error) {
+	defer observeQuery("seos", "insert", time.Now(), &err)
+	_, err = c.collection("seos").InsertOne(ctx, seo)
+	return err
+}
+
+// ReplaceSeo replaces seo with the same page_id, returns false when there was no seo to replace.
+func (c ClientImpl) ReplaceSeo(ctx context.Context, seo model.SEO) (_ bool, err error) {
+	defer observeQuery("seos", "replace", time.Now(), &err)
+	result, err := c.collection("seos").ReplaceOne(ctx, byPageId(seo.PageId), seo)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// DeleteSeo deletes seo with given page_id, returns false when there was no seo to delete.
+func (c ClientImpl) DeleteSeo(ctx context.Context, pageId int) (_ bool, err error) {
+	defer observeQuery("seos", "delete", time.Now(), &err)
+	result, err := c.collection("seos").DeleteOne(ctx, byPageId(pageId))
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// TouchSeo sets updated_at of seo with given page_id, missing seo is ignored.
+func (c ClientImpl) TouchSeo(ctx context.Context, pageId int, updatedAt time.Time) (err error) {
+	defer observeQuery("seos", "update", time.Now(), &err)
+	_, err = c.collection("seos").UpdateOne(ctx, byPageId(pageId), bson.D{{Key: "$set", Value: bson.D{{Key: "updated_at", Value: updatedAt}}}})
+	return err
+}
+
+func (c ClientImpl) InsertProducts(ctx context.Context, products []model.Product) (err error) {
+	if len(products) == 0 {
+		return nil
+	}
+	defer observeQuery("products", "insert", time.Now(), &err)
+	documents := make([]interface{}, 0, len(products))
+	for _, product := range products {
+		documents = append(documents, product)
+	}
+	_, err = c.collection("products").InsertMany(ctx, documents)
+	return err
+}
+
+func (c ClientImpl) DeleteProducts(ctx context.Context, pageId int) (err error) {
+	defer observeQuery("products", "delete", time.Now(), &err)
+	_, err = c.collection("products").DeleteMany(ctx, byPageId(pageId))
+	return err
 }
 
-func (c ClientImpl) FindProducts(ctx context.Context, pageId int) (MongoCursor, error) {
-	return c.findInCollectionByPageId(ctx, pageId, "products")
+func (c ClientImpl) FindProduct(ctx context.Context, pageId int, productId int) (_ MongoCursor, err error) {
+	defer observeQuery("products", "find", time.Now(), &err)
+	return c.collection("products").Find(ctx, byPageIdAndId(pageId, productId))
+}
 
+// QueryProducts finds products of the page matching the query. Products before its cursor are excluded
+// with keyset condition, instead of being skipped, so the query reads only returned products from the index.
+func (c ClientImpl) QueryProducts(ctx context.Context, pageId int, query *model.ProductQuery) (_ MongoCursor, err error) {
+	defer observeQuery("products", "find_page", time.Now(), &err)
+	filter := productFilter(pageId, query)
+	if query.After != nil {
+		filter = append(filter, productsAfter(query.After))
+	}
+	findOptions := options.Find().
+		SetSort(productOrder(query.Sort)).
+		SetLimit(int64(query.Limit))
+	return c.collection("products").Find(ctx, filter, findOptions)
 }
 
-func (c ClientImpl) findInCollectionByPageId(ctx context.Context, pageId int, collection string) (MongoCursor, error) {
+// CountProducts counts products of the page matching filters of the query, its cursor is ignored.
+func (c ClientImpl) CountProducts(ctx context.Context, pageId int, query *model.ProductQuery) (_ int64, err error) {
+	defer observeQuery("products", "count", time.Now(), &err)
+	return c.collection("products").CountDocuments(ctx, productFilter(pageId, query))
+}
+
+func (c ClientImpl) InsertProduct(ctx context.Context, product model.Product) (err error) {
+	defer observeQuery("products", "insert", time.Now(), &err)
+	_, err = c.collection("products").InsertOne(ctx, product)
+	return err
+}
+
+// ReplaceProduct replaces product with the same page_id and id, returns false when there was no product to replace.
+func (c ClientImpl) ReplaceProduct(ctx context.Context, product model.Product) (_ bool, err error) {
+	defer observeQuery("products", "replace", time.Now(), &err)
+	result, err := c.collection("products").ReplaceOne(ctx, byPageIdAndId(product.PageId, product.Id), product)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// DeleteProduct deletes product with given page_id and id, returns false when there was no product to delete.
+func (c ClientImpl) DeleteProduct(ctx context.Context, pageId int, productId int) (_ bool, err error) {
+	defer observeQuery("products", "delete", time.Now(), &err)
+	result, err := c.collection("products").DeleteOne(ctx, byPageIdAndId(pageId, productId))
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (c ClientImpl) findInCollectionByPageId(ctx context.Context, pageId int, collection string, projection []string) (_ MongoCursor, err error) {
+	defer observeQuery(collection, "find", time.Now(), &err)
+	findOptions := options.Find()
+	if projection != nil {
+		findOptions.SetProjection(withKeys(projection))
+	}
+	return c.collection(collection).Find(ctx, byPageId(pageId), findOptions)
+}
+
+func (c ClientImpl) collection(collection string) *mongo.Collection {
 	return c.mongoClient.
 		Database(c.config.Database).
-		Collection(collection).
-		Find(ctx, bson.D{{"page_id", pageId}})
+		Collection(collection)
+}
+
+func byPageId(pageId int) bson.D {
+	return bson.D{{Key: "page_id", Value: pageId}}
+}
+
+func byPageIdIn(pageIds []int) bson.D {
+	return bson.D{{Key: "page_id", Value: bson.D{{Key: "$in", Value: pageIds}}}}
+}
+
+func byPageIdAndId(pageId int, id int) bson.D {
+	return bson.D{{Key: "page_id", Value: pageId}, {Key: "id", Value: id}}
+}
+
+func productFilter(pageId int, query *model.ProductQuery) bson.D {
+	filter := byPageId(pageId)
+	if query.NameContains != "" {
+		filter = append(filter, bson.E{Key: "name", Value: primitive.Regex{Pattern: regexp.QuoteMeta(query.NameContains), Options: "i"}})
+	}
+	price := bson.D{}
+	if query.MinPrice != nil {
+		price = append(price, bson.E{Key: "$gte", Value: *query.MinPrice})
+	}
+	if query.MaxPrice != nil {
+		price = append(price, bson.E{Key: "$lte", Value: *query.MaxPrice})
+	}
+	if len(price) > 0 {
+		filter = append(filter, bson.E{Key: "price", Value: price})
+	}
+	return filter
+}
+
+// productsAfter is condition matching products after the cursor in its sort order.
+func productsAfter(cursor *model.ProductCursor) bson.E {
+	switch cursor.Sort {
+	case model.SortByPrice:
+		return afterKey("price", "$gt", cursor.Price, cursor.Id)
+	case model.SortByPriceDesc:
+		return afterKey("price", "$lt", cursor.Price, cursor.Id)
+	case model.SortByName:
+		return afterKey("name", "$gt", cursor.Name, cursor.Id)
+	default:
+		return bson.E{Key: "id", Value: bson.D{{Key: "$gt", Value: cursor.Id}}}
+	}
+}
+
+// afterKey matches documents with key after value in direction of operator, or equal to value and id greater than id.
+func afterKey(key string, operator string, value interface{}, id int) bson.E {
+	return bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: key, Value: bson.D{{Key: operator, Value: value}}}},
+		bson.D{{Key: key, Value: value}, {Key: "id", Value: bson.D{{Key: "$gt", Value: id}}}},
+	}}
+}
+
+func productOrder(sort model.ProductSort) bson.D {
+	switch sort {
+	case model.SortByPrice:
+		return bson.D{{Key: "price", Value: 1}, {Key: "id", Value: 1}}
+	case model.SortByPriceDesc:
+		return bson.D{{Key: "price", Value: -1}, {Key: "id", Value: 1}}
+	case model.SortByName:
+		return bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}}
+	default:
+		return bson.D{{Key: "id", Value: 1}}
+	}
+}
+
+// withKeys is projection including only given keys, _id is excluded as it is not part of the model.
+func withKeys(keys []string) bson.D {
+	projection := bson.D{{Key: "_id", Value: 0}}
+	for _, key := range keys {
+		projection = append(projection, bson.E{Key: key, Value: 1})
+	}
+	return projection
+}
+
+func (c ClientImpl) Ping(ctx context.Context) error {
+	return c.mongoClient.Ping(ctx, readpref.Primary())
 }
 
 func (c ClientImpl) CloseMongoClient() error {
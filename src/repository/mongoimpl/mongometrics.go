@@ -0,0 +1,39 @@
+package mongoimpl
+
+import (
+	"github.com/remikj/pages-ms/src/metrics"
+	"time"
+)
+
+var (
+	mongoQueryDuration = metrics.NewHistogramVec(
+		"mongo_query_duration_seconds",
+		"Duration of mongo queries in seconds by collection and operation.",
+		metrics.DefaultBuckets,
+		"collection", "operation",
+	)
+	mongoQueryErrors = metrics.NewCounterVec(
+		"mongo_query_errors_total",
+		"Total number of failed mongo queries by collection and operation.",
+		"collection", "operation",
+	)
+	mongoRetries = metrics.NewCounterVec(
+		"mongo_retries_total",
+		"Total number of retried mongo calls by method.",
+		"method",
+	)
+	mongoCircuitRejections = metrics.NewCounterVec(
+		"mongo_circuit_breaker_rejections_total",
+		"Total number of mongo calls rejected by open circuit breaker by method.",
+		"method",
+	)
+)
+
+// observeQuery records duration of query started at start and counts it as failed when *err is not nil.
+// It is meant to be deferred with named error result.
+func observeQuery(collection string, operation string, start time.Time, err *error) {
+	mongoQueryDuration.Observe(time.Since(start).Seconds(), collection, operation)
+	if *err != nil {
+		mongoQueryErrors.Inc(collection, operation)
+	}
+}
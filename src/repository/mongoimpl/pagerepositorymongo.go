@@ -2,62 +2,423 @@ package mongoimpl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/remikj/pages-ms/src/model"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log/slog"
+	"time"
+)
+
+// seoKeys and productKeys are document keys of model.SEO and model.Product fields.
+var (
+	seoKeys = map[string]string{
+		"PageId":      "page_id",
+		"Title":       "title",
+		"Description": "description",
+		"Robots":      "robots",
+	}
+	productKeys = map[string]string{
+		"Id":          "id",
+		"PageId":      "page_id",
+		"Name":        "name",
+		"Description": "description",
+		"Price":       "price",
+	}
 )
 
 type PageRepositoryMongo struct {
 	mongoClient Client
+	logger      *slog.Logger
 }
 
-func InitPageRepositoryMongoFromEnv() (*PageRepositoryMongo, error) {
-	mongoClient, err := InitMongoFromEnv()
+// InitPageRepositoryMongoFromEnv creates repository calling mongo through ResilientClient.
+func InitPageRepositoryMongoFromEnv(logger *slog.Logger) (*PageRepositoryMongo, error) {
+	resilienceConfig, err := ResilienceConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	mongoClient, err := InitMongoFromEnv(logger)
 	if err != nil {
 		return nil, err
 	}
 	return &PageRepositoryMongo{
-		mongoClient: mongoClient,
+		mongoClient: NewResilientClient(resilienceConfig, mongoClient, logger),
+		logger:      logger,
 	}, nil
 }
 
 func (p PageRepositoryMongo) GetSeoForPage(ctx context.Context, pageId int) (*model.SEO, error) {
-	fmt.Printf("Getting seo for page_id: %v\n", pageId)
-	seosCursor, err := p.mongoClient.FindSeos(ctx, pageId)
+	p.logger.Debug("getting seo", "page_id", pageId)
+	return p.findSeo(ctx, pageId, nil)
+}
+
+// GetSeoFieldsForPage reads only selected fields of seo and its updated_at, which is needed for Last-Modified.
+func (p PageRepositoryMongo) GetSeoFieldsForPage(ctx context.Context, pageId int, fields []string) (*model.SEO, error) {
+	p.logger.Debug("getting seo fields", "page_id", pageId, "fields", fields)
+	return p.findSeo(ctx, pageId, append(projectionOf(fields, seoKeys), "updated_at"))
+}
+
+func (p PageRepositoryMongo) findSeo(ctx context.Context, pageId int, projection []string) (*model.SEO, error) {
+	seosCursor, err := p.mongoClient.FindSeos(ctx, pageId, projection)
 	if err != nil {
-		return nil, fmt.Errorf("error happened when using db: %w", err)
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
 	}
 	defer seosCursor.Close(ctx)
 
 	seo := &model.SEO{}
-	if seosCursor.Next(ctx) {
-		err := seosCursor.Decode(seo)
-		if err != nil {
-			return nil, fmt.Errorf("error happened when decoding results: %w", err)
-		}
-	} else {
-		return nil, nil
+	if !seosCursor.Next(ctx) {
+		return nil, cursorErr(seosCursor)
+	}
+	if err := seosCursor.Decode(seo); err != nil {
+		return nil, fmt.Errorf("%w: error happened when decoding results: %w", model.ErrDataIntegrity, err)
 	}
 
 	if seosCursor.Next(ctx) {
-		return nil, fmt.Errorf("too many results")
+		return nil, fmt.Errorf("%w: too many results", model.ErrDataIntegrity)
 	}
-	return seo, seosCursor.Err()
+	return seo, cursorErr(seosCursor)
 }
 
 func (p PageRepositoryMongo) GetProductsForPage(ctx context.Context, pageId int) ([]model.Product, error) {
-	fmt.Printf("Getting products for page_id: %v\n", pageId)
-	productsCursor, err := p.mongoClient.FindProducts(ctx, pageId)
+	p.logger.Debug("getting products", "page_id", pageId)
+	return p.findProducts(ctx, pageId, nil)
+}
+
+func (p PageRepositoryMongo) GetProductFieldsForPage(ctx context.Context, pageId int, fields []string) ([]model.Product, error) {
+	p.logger.Debug("getting product fields", "page_id", pageId, "fields", fields)
+	return p.findProducts(ctx, pageId, projectionOf(fields, productKeys))
+}
+
+func (p PageRepositoryMongo) findProducts(ctx context.Context, pageId int, projection []string) ([]model.Product, error) {
+	productsCursor, err := p.mongoClient.FindProducts(ctx, pageId, projection)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+
+	var products []model.Product
+	if err = productsCursor.All(ctx, &products); err != nil {
+		return nil, allErr(ctx, err)
+	}
+	return products, nil
+}
+
+// StreamProductsForPage decodes products one by one while iterating the cursor, so only the current batch
+// of the cursor is kept in memory.
+func (p PageRepositoryMongo) StreamProductsForPage(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+	p.logger.Debug("streaming products", "page_id", pageId)
+	productsCursor, err := p.mongoClient.FindProducts(ctx, pageId, nil)
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	// cursor is closed even when ctx is cancelled, so the server does not keep it open.
+	defer productsCursor.Close(context.WithoutCancel(ctx))
+
+	for productsCursor.Next(ctx) {
+		product := &model.Product{}
+		if err := productsCursor.Decode(product); err != nil {
+			return fmt.Errorf("%w: error happened when decoding results: %w", model.ErrDataIntegrity, err)
+		}
+		if err := yield(product); err != nil {
+			return err
+		}
+	}
+	if err := productsCursor.Err(); err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return nil
+}
+
+// GetSeosForPages returns seos of the pages that exist, in no particular order.
+func (p PageRepositoryMongo) GetSeosForPages(ctx context.Context, pageIds []int) ([]model.SEO, error) {
+	p.logger.Debug("getting seos", "page_ids", pageIds)
+	seosCursor, err := p.mongoClient.FindSeosByPageIds(ctx, pageIds)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+
+	var seos []model.SEO
+	if err = seosCursor.All(ctx, &seos); err != nil {
+		return nil, allErr(ctx, err)
+	}
+	return seos, nil
+}
+
+// GetProductsForPages returns products of all the pages, ordered by page and id. Products over the limit
+// of their page are skipped while iterating the cursor, so they are never kept in memory.
+func (p PageRepositoryMongo) GetProductsForPages(ctx context.Context, pageIds []int, limit int) ([]model.Product, error) {
+	p.logger.Debug("getting products", "page_ids", pageIds, "limit", limit)
+	productsCursor, err := p.mongoClient.FindProductsByPageIds(ctx, pageIds)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	defer productsCursor.Close(context.WithoutCancel(ctx))
+
+	var products []model.Product
+	pageProducts := map[int]int{}
+	for productsCursor.Next(ctx) {
+		product := model.Product{}
+		if err := productsCursor.Decode(&product); err != nil {
+			return nil, fmt.Errorf("%w: error happened when decoding results: %w", model.ErrDataIntegrity, err)
+		}
+		if limit > 0 && pageProducts[product.PageId] >= limit {
+			continue
+		}
+		pageProducts[product.PageId]++
+		products = append(products, product)
+	}
+	if err := cursorErr(productsCursor); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// CreatePage writes seo and products of the page. Standalone mongo does not support transactions,
+// so when products can not be written the already inserted seo is removed.
+func (p PageRepositoryMongo) CreatePage(ctx context.Context, page *model.Page) error {
+	p.logger.Debug("creating page", "page_id", page.SEO.PageId)
+	existingSeo, err := p.GetSeoForPage(ctx, page.SEO.PageId)
+	if err != nil {
+		return err
+	}
+	if existingSeo != nil {
+		return model.ErrPageAlreadyExists
+	}
+	page.SEO.Touch(time.Now())
+	if err := p.mongoClient.InsertSeo(ctx, page.SEO); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return model.ErrPageAlreadyExists
+		}
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	if err := p.mongoClient.InsertProducts(ctx, page.Products); err != nil {
+		if _, deleteErr := p.mongoClient.DeleteSeo(ctx, page.SEO.PageId); deleteErr != nil {
+			p.logger.Error("failed to rollback seo", "page_id", page.SEO.PageId, "error", deleteErr)
+		}
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return nil
+}
+
+// ReplacePage replaces seo of the page and all of its products. Standalone mongo does not support transactions,
+// so previous seo and products are read first and written back, when the page can not be replaced completely.
+func (p PageRepositoryMongo) ReplacePage(ctx context.Context, page *model.Page) error {
+	p.logger.Debug("replacing page", "page_id", page.SEO.PageId)
+	previousSeo, err := p.GetSeoForPage(ctx, page.SEO.PageId)
+	if err != nil {
+		return err
+	}
+	if previousSeo == nil {
+		return model.ErrPageNotFound
+	}
+	previousProducts, err := p.GetProductsForPage(ctx, page.SEO.PageId)
+	if err != nil {
+		return err
+	}
+	page.SEO.Touch(time.Now())
+	replaced, err := p.mongoClient.ReplaceSeo(ctx, page.SEO)
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	if !replaced {
+		return model.ErrPageNotFound
+	}
+	if err := p.mongoClient.DeleteProducts(ctx, page.SEO.PageId); err != nil {
+		return p.restorePage(ctx, previousSeo, previousProducts, err)
+	}
+	if err := p.mongoClient.InsertProducts(ctx, page.Products); err != nil {
+		return p.restorePage(ctx, previousSeo, previousProducts, err)
+	}
+	return nil
+}
+
+// restorePage writes back seo and products the page had before failed replace. It runs even when ctx is done,
+// as ctx deadline is a common cause of the failure. When the page can not be restored, the returned error tells
+// it is left partially replaced, replacing it again fixes it.
+func (p PageRepositoryMongo) restorePage(ctx context.Context, seo *model.SEO, products []model.Product, cause error) error {
+	ctx = context.WithoutCancel(ctx)
+	err := p.mongoClient.DeleteProducts(ctx, seo.PageId)
+	if err == nil {
+		err = p.mongoClient.InsertProducts(ctx, products)
+	}
+	if err == nil {
+		_, err = p.mongoClient.ReplaceSeo(ctx, *seo)
+	}
+	if err != nil {
+		p.logger.Error("failed to restore page", "page_id", seo.PageId, "error", err)
+		return fmt.Errorf("%w: page is left partially replaced, error happened when using db: %w", model.ErrBackendUnavailable, cause)
+	}
+	return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, cause)
+}
+
+// DeletePage deletes products before seo, so failed deletion can be retried.
+func (p PageRepositoryMongo) DeletePage(ctx context.Context, pageId int) error {
+	p.logger.Debug("deleting page", "page_id", pageId)
+	if err := p.mongoClient.DeleteProducts(ctx, pageId); err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	deleted, err := p.mongoClient.DeleteSeo(ctx, pageId)
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	if !deleted {
+		return model.ErrPageNotFound
+	}
+	return nil
+}
+
+func (p PageRepositoryMongo) GetProductForPage(ctx context.Context, pageId int, productId int) (*model.Product, error) {
+	p.logger.Debug("getting product", "page_id", pageId, "product_id", productId)
+	productsCursor, err := p.mongoClient.FindProduct(ctx, pageId, productId)
 	if err != nil {
-		return nil, fmt.Errorf("error happened when using db: %w", err)
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	defer productsCursor.Close(ctx)
+
+	product := &model.Product{}
+	if !productsCursor.Next(ctx) {
+		return nil, cursorErr(productsCursor)
+	}
+	if err := productsCursor.Decode(product); err != nil {
+		return nil, fmt.Errorf("%w: error happened when decoding results: %w", model.ErrDataIntegrity, err)
+	}
+
+	if productsCursor.Next(ctx) {
+		return nil, fmt.Errorf("%w: too many results", model.ErrDataIntegrity)
+	}
+	return product, cursorErr(productsCursor)
+}
+
+func (p PageRepositoryMongo) QueryProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) ([]model.Product, error) {
+	p.logger.Debug("querying products", "page_id", pageId, "sort", query.Sort, "limit", query.Limit)
+	productsCursor, err := p.mongoClient.QueryProducts(ctx, pageId, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
 	}
 
 	var products []model.Product
 	if err = productsCursor.All(ctx, &products); err != nil {
-		return nil, fmt.Errorf("error happened when decoding results: %w", err)
+		return nil, allErr(ctx, err)
 	}
 	return products, nil
 }
 
+func (p PageRepositoryMongo) CountProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (int, error) {
+	p.logger.Debug("counting products", "page_id", pageId)
+	count, err := p.mongoClient.CountProducts(ctx, pageId, query)
+	if err != nil {
+		return 0, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return int(count), nil
+}
+
+// CreateProduct adds product to existing page, product id has to be unique within the page.
+func (p PageRepositoryMongo) CreateProduct(ctx context.Context, product *model.Product) error {
+	p.logger.Debug("creating product", "page_id", product.PageId, "product_id", product.Id)
+	seo, err := p.GetSeoForPage(ctx, product.PageId)
+	if err != nil {
+		return err
+	}
+	if seo == nil {
+		return model.ErrPageNotFound
+	}
+	existingProduct, err := p.GetProductForPage(ctx, product.PageId, product.Id)
+	if err != nil {
+		return err
+	}
+	if existingProduct != nil {
+		return model.ErrProductAlreadyExists
+	}
+	if err := p.mongoClient.InsertProduct(ctx, *product); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return model.ErrProductAlreadyExists
+		}
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	p.touchPage(ctx, product.PageId)
+	return nil
+}
+
+func (p PageRepositoryMongo) ReplaceProduct(ctx context.Context, product *model.Product) error {
+	p.logger.Debug("replacing product", "page_id", product.PageId, "product_id", product.Id)
+	replaced, err := p.mongoClient.ReplaceProduct(ctx, *product)
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	if !replaced {
+		return model.ErrProductNotFound
+	}
+	p.touchPage(ctx, product.PageId)
+	return nil
+}
+
+func (p PageRepositoryMongo) DeleteProduct(ctx context.Context, pageId int, productId int) error {
+	p.logger.Debug("deleting product", "page_id", pageId, "product_id", productId)
+	deleted, err := p.mongoClient.DeleteProduct(ctx, pageId, productId)
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	if !deleted {
+		return model.ErrProductNotFound
+	}
+	p.touchPage(ctx, pageId)
+	return nil
+}
+
+// touchPage marks the page as changed after its products were written. Products are already stored,
+// so failure is only logged and the page keeps its previous Last-Modified.
+func (p PageRepositoryMongo) touchPage(ctx context.Context, pageId int) {
+	seo := model.SEO{}
+	seo.Touch(time.Now())
+	if err := p.mongoClient.TouchSeo(ctx, pageId, seo.UpdatedAt); err != nil {
+		p.logger.Error("failed to touch page", "page_id", pageId, "error", err)
+	}
+}
+
+// cursorErr returns error of iterating the cursor as ErrBackendUnavailable, like errors of the query itself.
+func cursorErr(cursor MongoCursor) error {
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return nil
+}
+
+// allErr returns error of reading all results of the cursor. Only errors of decoding results are ErrDataIntegrity,
+// failures of mongo, e.g. of getMore, and done ctx are ErrBackendUnavailable, like errors of the query itself.
+func allErr(ctx context.Context, err error) error {
+	var serverErr mongo.ServerError
+	if ctx.Err() != nil || isTransient(err) || mongo.IsTimeout(err) || errors.As(err, &serverErr) ||
+		errors.Is(err, mongo.ErrClientDisconnected) {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return fmt.Errorf("%w: error happened when decoding results: %w", model.ErrDataIntegrity, err)
+}
+
+// projectionOf returns document keys of fields, it is never nil, so only listed keys are read.
+func projectionOf(fields []string, keys map[string]string) []string {
+	projection := make([]string, 0, len(fields)+1)
+	for _, field := range fields {
+		if key, ok := keys[field]; ok {
+			projection = append(projection, key)
+		}
+	}
+	return projection
+}
+
+func (p PageRepositoryMongo) Ping(ctx context.Context) error {
+	return p.mongoClient.Ping(ctx)
+}
+
+// CheckCircuit fails while circuit breaker around mongo calls rejects them, it passes when there is no breaker.
+func (p PageRepositoryMongo) CheckCircuit(ctx context.Context) error {
+	if checker, ok := p.mongoClient.(interface {
+		CheckCircuit(ctx context.Context) error
+	}); ok {
+		return checker.CheckCircuit(ctx)
+	}
+	return nil
+}
+
 func (p PageRepositoryMongo) CloseRepository() error {
 	return p.mongoClient.CloseMongoClient()
 }
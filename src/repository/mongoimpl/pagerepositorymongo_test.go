@@ -3,10 +3,15 @@ package mongoimpl
 import (
 	"context"
 	"fmt"
+	"github.com/remikj/pages-ms/src/logging"
 	"github.com/remikj/pages-ms/src/model"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
 	"testing"
+	"time"
 )
 
 var (
@@ -59,7 +64,7 @@ func TestPageRepositoryMongo_GetSeoForPage(t *testing.T) {
 		{
 			name: "should successfully find seo, when one seo in cursor",
 			mongoClient: mongoClientMock{
-				findSeosFunc: createFindFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
 			},
 			pageId:      0,
 			expectedSeo: &sampleSeo,
@@ -68,7 +73,7 @@ func TestPageRepositoryMongo_GetSeoForPage(t *testing.T) {
 		{
 			name: "should return nil, when no seos in cursor",
 			mongoClient: mongoClientMock{
-				findSeosFunc: createFindFunc(mockMongoCursor([][]byte{}), nil),
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{}), nil),
 			},
 			pageId:      0,
 			expectedSeo: nil,
@@ -77,35 +82,36 @@ func TestPageRepositoryMongo_GetSeoForPage(t *testing.T) {
 		{
 			name: "should return err, when findSeos fails",
 			mongoClient: mongoClientMock{
-				findSeosFunc: createFindFunc(nil, fmt.Errorf("findSeos error")),
+				findSeosFunc: createFindByPageIdFunc(nil, fmt.Errorf("findSeos error")),
 			},
 			pageId:      0,
 			expectedSeo: nil,
-			expectedErr: fmt.Errorf("error happened when using db: findSeos error"),
+			expectedErr: fmt.Errorf("backend unavailable: error happened when using db: findSeos error"),
 		},
 		{
 			name: "should fail, when multiple seos in cursor",
 			mongoClient: mongoClientMock{
-				findSeosFunc: createFindFunc(mockMongoCursor([][]byte{marshal(sampleSeo), marshal(sampleSeo)}), nil),
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo), marshal(sampleSeo)}), nil),
 			},
 			pageId:      0,
 			expectedSeo: nil,
-			expectedErr: fmt.Errorf("too many results"),
+			expectedErr: fmt.Errorf("data integrity violation: too many results"),
 		},
 		{
 			name: "should return err, when decode fails",
 			mongoClient: mongoClientMock{
-				findSeosFunc: createFindFunc(mockMongoCursor([][]byte{[]byte("incorrectBytes")}), nil),
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{[]byte("incorrectBytes")}), nil),
 			},
 			pageId:      0,
 			expectedSeo: nil,
-			expectedErr: fmt.Errorf("error happened when decoding results: invalid document length"),
+			expectedErr: fmt.Errorf("data integrity violation: error happened when decoding results: invalid document length"),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := PageRepositoryMongo{
 				mongoClient: tt.mongoClient,
+				logger:      logging.Discard(),
 			}
 			resultSeo, err := p.GetSeoForPage(context.Background(), tt.pageId)
 
@@ -132,7 +138,7 @@ func TestPageRepositoryMongo_GetProductsForPage(t *testing.T) {
 		{
 			name: "should return empty products array, when no products in cursor",
 			mongoClient: mongoClientMock{
-				findProductsFunc: createFindFunc(mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct2)}), nil),
+				findProductsFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct2)}), nil),
 			},
 			pageId:           0,
 			expectedProducts: sampleProducts,
@@ -141,26 +147,27 @@ func TestPageRepositoryMongo_GetProductsForPage(t *testing.T) {
 		{
 			name: "should return err products array, when no products in cursor",
 			mongoClient: mongoClientMock{
-				findProductsFunc: createFindFunc(nil, fmt.Errorf("findProducts error")),
+				findProductsFunc: createFindByPageIdFunc(nil, fmt.Errorf("findProducts error")),
 			},
 			pageId:           0,
 			expectedProducts: nil,
-			expectedErr:      fmt.Errorf("error happened when using db: findProducts error"),
+			expectedErr:      fmt.Errorf("backend unavailable: error happened when using db: findProducts error"),
 		},
 		{
 			name: "should return empty products array, when no products in cursor",
 			mongoClient: mongoClientMock{
-				findProductsFunc: createFindFunc(mockMongoCursor([][]byte{[]byte("incorrectBytes")}), nil),
+				findProductsFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{[]byte("incorrectBytes")}), nil),
 			},
 			pageId:           0,
 			expectedProducts: nil,
-			expectedErr:      fmt.Errorf("error happened when decoding results: invalid document length"),
+			expectedErr:      fmt.Errorf("data integrity violation: error happened when decoding results: invalid document length"),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := PageRepositoryMongo{
 				mongoClient: tt.mongoClient,
+				logger:      logging.Discard(),
 			}
 			resultSeo, err := p.GetProductsForPage(context.Background(), tt.pageId)
 
@@ -175,6 +182,617 @@ func TestPageRepositoryMongo_GetProductsForPage(t *testing.T) {
 	}
 }
 
+func TestPageRepositoryMongo_StreamProductsForPage(t *testing.T) {
+	tests := []struct {
+		name             string
+		cursor           MongoCursor
+		yieldErr         error
+		expectedProducts []model.Product
+		expectedErr      string
+	}{
+		{
+			name:             "should yield all products of the cursor",
+			cursor:           mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct2)}),
+			expectedProducts: sampleProducts,
+		},
+		{
+			name:             "should stop, when yield fails",
+			cursor:           mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct2)}),
+			yieldErr:         fmt.Errorf("client disconnected"),
+			expectedProducts: sampleProducts[:1],
+			expectedErr:      "client disconnected",
+		},
+		{
+			name:             "should return err, when product can not be decoded",
+			cursor:           mockMongoCursor([][]byte{marshal(sampleProduct1), []byte("incorrectBytes")}),
+			expectedProducts: sampleProducts[:1],
+			expectedErr:      "data integrity violation: error happened when decoding results: invalid document length",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PageRepositoryMongo{
+				mongoClient: mongoClientMock{findProductsFunc: createFindByPageIdFunc(tt.cursor, nil)},
+				logger:      logging.Discard(),
+			}
+
+			var products []model.Product
+			err := p.StreamProductsForPage(context.Background(), 0, func(product *model.Product) error {
+				products = append(products, *product)
+				return tt.yieldErr
+			})
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedProducts, products)
+		})
+	}
+}
+
+func TestPageRepositoryMongo_GetFieldsForPage_shouldReadOnlySelectedFields(t *testing.T) {
+	var seoProjection, productsProjection []string
+	p := PageRepositoryMongo{
+		mongoClient: mongoClientMock{
+			findSeosFunc: func(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+				seoProjection = projection
+				return mockMongoCursor([][]byte{marshal(bson.D{{Key: "title", Value: "title"}})}), nil
+			},
+			findProductsFunc: func(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+				productsProjection = projection
+				return mockMongoCursor([][]byte{marshal(bson.D{{Key: "name", Value: "name0"}, {Key: "price", Value: 1.0}})}), nil
+			},
+		},
+		logger: logging.Discard(),
+	}
+
+	seo, seoErr := p.GetSeoFieldsForPage(context.Background(), 0, []string{"Title"})
+	products, productsErr := p.GetProductFieldsForPage(context.Background(), 0, []string{"Name", "Price"})
+
+	assert.NoError(t, seoErr)
+	assert.NoError(t, productsErr)
+	assert.Equal(t, []string{"title", "updated_at"}, seoProjection)
+	assert.Equal(t, []string{"name", "price"}, productsProjection)
+	assert.Equal(t, &model.SEO{Title: "title"}, seo)
+	assert.Equal(t, []model.Product{{Name: "name0", Price: 1.0}}, products)
+}
+
+func TestPageRepositoryMongo_QueryAndCountProductsForPage(t *testing.T) {
+	query := &model.ProductQuery{Limit: 2, Sort: model.SortByPrice}
+	var queried, counted *model.ProductQuery
+	p := PageRepositoryMongo{
+		mongoClient: mongoClientMock{
+			queryProductsFunc: func(ctx context.Context, pageId int, query *model.ProductQuery) (MongoCursor, error) {
+				queried = query
+				return mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct2)}), nil
+			},
+			countProductsFunc: func(ctx context.Context, pageId int, query *model.ProductQuery) (int64, error) {
+				counted = query
+				return 5, nil
+			},
+		},
+		logger: logging.Discard(),
+	}
+
+	products, productsErr := p.QueryProductsForPage(context.Background(), 0, query)
+	count, countErr := p.CountProductsForPage(context.Background(), 0, query)
+
+	assert.NoError(t, productsErr)
+	assert.NoError(t, countErr)
+	assert.Equal(t, sampleProducts, products)
+	assert.Equal(t, 5, count)
+	assert.Same(t, query, queried)
+	assert.Same(t, query, counted)
+}
+
+func TestProductFilter(t *testing.T) {
+	minPrice := 1.5
+	query := &model.ProductQuery{
+		Sort:         model.SortByPriceDesc,
+		After:        &model.ProductCursor{Sort: model.SortByPriceDesc, Id: 3, Price: 2},
+		NameContains: "a.b",
+		MinPrice:     &minPrice,
+	}
+
+	assert.Equal(t, bson.D{
+		{Key: "page_id", Value: 0},
+		{Key: "name", Value: primitive.Regex{Pattern: `a\.b`, Options: "i"}},
+		{Key: "price", Value: bson.D{{Key: "$gte", Value: 1.5}}},
+	}, productFilter(0, query))
+	assert.Equal(t, bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: "price", Value: bson.D{{Key: "$lt", Value: 2.0}}}},
+		bson.D{{Key: "price", Value: 2.0}, {Key: "id", Value: bson.D{{Key: "$gt", Value: 3}}}},
+	}}, productsAfter(query.After))
+}
+
+func TestPageRepositoryMongo_GetSeosAndProductsForPages(t *testing.T) {
+	otherSeo := model.SEO{PageId: 1, Title: "other title"}
+	var requestedSeoIds, requestedProductIds []int
+	p := PageRepositoryMongo{
+		mongoClient: mongoClientMock{
+			findSeosInFunc: func(ctx context.Context, pageIds []int) (MongoCursor, error) {
+				requestedSeoIds = pageIds
+				return mockMongoCursor([][]byte{marshal(sampleSeo), marshal(otherSeo)}), nil
+			},
+			findProductsInFunc: func(ctx context.Context, pageIds []int) (MongoCursor, error) {
+				requestedProductIds = pageIds
+				return mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct2)}), nil
+			},
+		},
+		logger: logging.Discard(),
+	}
+
+	seos, seosErr := p.GetSeosForPages(context.Background(), []int{0, 1, 2})
+	products, productsErr := p.GetProductsForPages(context.Background(), []int{0, 1, 2}, 0)
+
+	assert.NoError(t, seosErr)
+	assert.NoError(t, productsErr)
+	assert.Equal(t, []model.SEO{sampleSeo, otherSeo}, seos)
+	assert.Equal(t, sampleProducts, products)
+	assert.Equal(t, []int{0, 1, 2}, requestedSeoIds)
+	assert.Equal(t, []int{0, 1, 2}, requestedProductIds)
+}
+
+func TestPageRepositoryMongo_GetProductsForPages_shouldLimitProductsOfEachPage(t *testing.T) {
+	otherProduct := model.Product{Id: 0, PageId: 1, Name: "other"}
+	p := PageRepositoryMongo{
+		mongoClient: mongoClientMock{
+			findProductsInFunc: func(ctx context.Context, pageIds []int) (MongoCursor, error) {
+				return mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct2), marshal(otherProduct)}), nil
+			},
+		},
+		logger: logging.Discard(),
+	}
+
+	products, err := p.GetProductsForPages(context.Background(), []int{0, 1}, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Product{sampleProduct1, otherProduct}, products)
+}
+
+func TestPageRepositoryMongo_shouldReturnErrBackendUnavailable_whenCursorFailsReadingResults(t *testing.T) {
+	networkErr := mongo.CommandError{Message: "connection reset", Labels: []string{"NetworkError"}}
+	failingCursor := func() MongoCursor {
+		return &mongoCursosMock{idx: -1, results: [][]byte{marshal(sampleProduct1)}, err: networkErr}
+	}
+	p := PageRepositoryMongo{
+		mongoClient: mongoClientMock{
+			findProductsFunc: func(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+				return failingCursor(), nil
+			},
+			findSeosInFunc: func(ctx context.Context, pageIds []int) (MongoCursor, error) {
+				return failingCursor(), nil
+			},
+			findProductsInFunc: func(ctx context.Context, pageIds []int) (MongoCursor, error) {
+				return failingCursor(), nil
+			},
+			queryProductsFunc: func(ctx context.Context, pageId int, query *model.ProductQuery) (MongoCursor, error) {
+				return failingCursor(), nil
+			},
+		},
+		logger: logging.Discard(),
+	}
+
+	_, productsErr := p.GetProductsForPage(context.Background(), 0)
+	_, seosErr := p.GetSeosForPages(context.Background(), []int{0})
+	_, pagesProductsErr := p.GetProductsForPages(context.Background(), []int{0}, 0)
+	_, queryErr := p.QueryProductsForPage(context.Background(), 0, &model.ProductQuery{Limit: 1})
+
+	for _, err := range []error{productsErr, seosErr, pagesProductsErr, queryErr} {
+		assert.ErrorIs(t, err, model.ErrBackendUnavailable)
+		assert.EqualError(t, err, "backend unavailable: error happened when using db: connection reset")
+	}
+}
+
+func TestAllErr(t *testing.T) {
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, allErr(context.Background(), context.DeadlineExceeded), model.ErrBackendUnavailable)
+	assert.ErrorIs(t, allErr(cancelledCtx, fmt.Errorf("interrupted")), model.ErrBackendUnavailable)
+	assert.ErrorIs(t, allErr(context.Background(), mongo.CommandError{Code: 43, Name: "CursorNotFound"}), model.ErrBackendUnavailable)
+	assert.ErrorIs(t, allErr(context.Background(), fmt.Errorf("invalid document length")), model.ErrDataIntegrity)
+}
+
+func TestPageRepositoryMongo_GetSeosForPages_shouldReturnErr_whenFindFails(t *testing.T) {
+	p := PageRepositoryMongo{
+		mongoClient: mongoClientMock{
+			findSeosInFunc: func(ctx context.Context, pageIds []int) (MongoCursor, error) {
+				return nil, fmt.Errorf("find failed")
+			},
+		},
+		logger: logging.Discard(),
+	}
+
+	seos, err := p.GetSeosForPages(context.Background(), []int{0})
+
+	assert.Nil(t, seos)
+	assert.EqualError(t, err, "backend unavailable: error happened when using db: find failed")
+}
+
+func TestPageRepositoryMongo_CreatePage(t *testing.T) {
+	page := &model.Page{SEO: sampleSeo, Products: sampleProducts}
+	tests := []struct {
+		name            string
+		mongoClient     Client
+		expectedErr     error
+		expectedDeleted bool
+	}{
+		{
+			name: "should insert seo and products, when page does not exist",
+			mongoClient: mongoClientMock{
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{}), nil),
+				insertSeoFunc: func(ctx context.Context, seo model.SEO) error {
+					if seo.UpdatedAt.IsZero() {
+						return fmt.Errorf("seo not touched")
+					}
+					return nil
+				},
+				insertProductsFunc: func(ctx context.Context, products []model.Product) error { return nil },
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "should return ErrPageAlreadyExists, when seo already exists",
+			mongoClient: mongoClientMock{
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
+			},
+			expectedErr: model.ErrPageAlreadyExists,
+		},
+		{
+			name: "should return err and remove seo, when insert products fails",
+			mongoClient: mongoClientMock{
+				findSeosFunc:       createFindByPageIdFunc(mockMongoCursor([][]byte{}), nil),
+				insertSeoFunc:      func(ctx context.Context, seo model.SEO) error { return nil },
+				insertProductsFunc: func(ctx context.Context, products []model.Product) error { return fmt.Errorf("insert error") },
+			},
+			expectedErr:     fmt.Errorf("backend unavailable: error happened when using db: insert error"),
+			expectedDeleted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted := false
+			mongoClient := tt.mongoClient.(mongoClientMock)
+			mongoClient.deleteSeoFunc = func(ctx context.Context, pageId int) (bool, error) {
+				deleted = true
+				return true, nil
+			}
+			p := PageRepositoryMongo{
+				mongoClient: mongoClient,
+				logger:      logging.Discard(),
+			}
+
+			err := p.CreatePage(context.Background(), page)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedDeleted, deleted)
+		})
+	}
+}
+
+func TestPageRepositoryMongo_ReplacePage(t *testing.T) {
+	page := &model.Page{SEO: model.SEO{PageId: 0, Title: "new title"}, Products: sampleProducts[:1]}
+	tests := []struct {
+		name             string
+		mongoClient      mongoClientMock
+		expectedErr      error
+		expectedSeo      model.SEO
+		expectedProducts []model.Product
+	}{
+		{
+			name: "should replace seo and products, when page exists",
+			mongoClient: mongoClientMock{
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
+			},
+			expectedErr:      nil,
+			expectedSeo:      model.SEO{PageId: 0, Title: "new title"},
+			expectedProducts: sampleProducts[:1],
+		},
+		{
+			name: "should return ErrPageNotFound, when seo does not exist",
+			mongoClient: mongoClientMock{
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{}), nil),
+			},
+			expectedErr:      model.ErrPageNotFound,
+			expectedSeo:      sampleSeo,
+			expectedProducts: sampleProducts,
+		},
+		{
+			name: "should restore previous page, when delete products fails",
+			mongoClient: mongoClientMock{
+				findSeosFunc:       createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
+				deleteProductsFunc: failOnce(func(ctx context.Context, pageId int) error { return nil }, fmt.Errorf("delete error")),
+			},
+			expectedErr:      fmt.Errorf("backend unavailable: error happened when using db: delete error"),
+			expectedSeo:      sampleSeo,
+			expectedProducts: sampleProducts,
+		},
+		{
+			name: "should restore previous page, when insert products fails",
+			mongoClient: mongoClientMock{
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
+				insertProductsFunc: func(ctx context.Context, products []model.Product) error {
+					if len(products) == 1 {
+						return fmt.Errorf("insert error")
+					}
+					return nil
+				},
+			},
+			expectedErr:      fmt.Errorf("backend unavailable: error happened when using db: insert error"),
+			expectedSeo:      sampleSeo,
+			expectedProducts: sampleProducts,
+		},
+		{
+			name: "should return err telling page is partially replaced, when page can not be restored",
+			mongoClient: mongoClientMock{
+				findSeosFunc:       createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
+				insertProductsFunc: func(ctx context.Context, products []model.Product) error { return fmt.Errorf("insert error") },
+			},
+			expectedErr:      fmt.Errorf("backend unavailable: page is left partially replaced, error happened when using db: insert error"),
+			expectedSeo:      model.SEO{PageId: 0, Title: "new title"},
+			expectedProducts: []model.Product{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storedSeo, storedProducts := sampleSeo, sampleProducts
+			mongoClient := tt.mongoClient
+			mongoClient.findProductsFunc = createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct2)}), nil)
+			mongoClient.replaceSeoFunc = func(ctx context.Context, seo model.SEO) (bool, error) {
+				storedSeo = seo
+				return true, nil
+			}
+			deleteProducts := mongoClient.deleteProductsFunc
+			mongoClient.deleteProductsFunc = func(ctx context.Context, pageId int) error {
+				if deleteProducts != nil {
+					if err := deleteProducts(ctx, pageId); err != nil {
+						return err
+					}
+				}
+				storedProducts = []model.Product{}
+				return nil
+			}
+			insertProducts := mongoClient.insertProductsFunc
+			mongoClient.insertProductsFunc = func(ctx context.Context, products []model.Product) error {
+				if insertProducts != nil {
+					if err := insertProducts(ctx, products); err != nil {
+						return err
+					}
+				}
+				storedProducts = append(storedProducts, products...)
+				return nil
+			}
+			p := PageRepositoryMongo{
+				mongoClient: mongoClient,
+				logger:      logging.Discard(),
+			}
+
+			err := p.ReplacePage(context.Background(), page)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			storedSeo.UpdatedAt = time.Time{}
+			assert.Equal(t, tt.expectedSeo, storedSeo)
+			assert.Equal(t, tt.expectedProducts, storedProducts)
+		})
+	}
+}
+
+// failOnce returns err on the first call and calls next on the following ones.
+func failOnce(next func(ctx context.Context, pageId int) error, err error) func(ctx context.Context, pageId int) error {
+	failed := false
+	return func(ctx context.Context, pageId int) error {
+		if !failed {
+			failed = true
+			return err
+		}
+		return next(ctx, pageId)
+	}
+}
+
+func TestPageRepositoryMongo_DeletePage(t *testing.T) {
+	tests := []struct {
+		name        string
+		mongoClient Client
+		expectedErr error
+	}{
+		{
+			name: "should delete products and seo, when page exists",
+			mongoClient: mongoClientMock{
+				deleteProductsFunc: func(ctx context.Context, pageId int) error { return nil },
+				deleteSeoFunc:      func(ctx context.Context, pageId int) (bool, error) { return true, nil },
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "should return ErrPageNotFound, when seo does not exist",
+			mongoClient: mongoClientMock{
+				deleteProductsFunc: func(ctx context.Context, pageId int) error { return nil },
+				deleteSeoFunc:      func(ctx context.Context, pageId int) (bool, error) { return false, nil },
+			},
+			expectedErr: model.ErrPageNotFound,
+		},
+		{
+			name: "should return err, when delete seo fails",
+			mongoClient: mongoClientMock{
+				deleteProductsFunc: func(ctx context.Context, pageId int) error { return nil },
+				deleteSeoFunc:      func(ctx context.Context, pageId int) (bool, error) { return false, fmt.Errorf("delete error") },
+			},
+			expectedErr: fmt.Errorf("backend unavailable: error happened when using db: delete error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PageRepositoryMongo{
+				mongoClient: tt.mongoClient,
+				logger:      logging.Discard(),
+			}
+
+			err := p.DeletePage(context.Background(), 0)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPageRepositoryMongo_GetProductForPage(t *testing.T) {
+	tests := []struct {
+		name            string
+		cursor          MongoCursor
+		expectedProduct *model.Product
+		expectedErr     error
+	}{
+		{
+			name:            "should return product, when one product in cursor",
+			cursor:          mockMongoCursor([][]byte{marshal(sampleProduct1)}),
+			expectedProduct: &sampleProduct1,
+		},
+		{
+			name:            "should return nil, when no products in cursor",
+			cursor:          mockMongoCursor([][]byte{}),
+			expectedProduct: nil,
+		},
+		{
+			name:        "should fail, when multiple products in cursor",
+			cursor:      mockMongoCursor([][]byte{marshal(sampleProduct1), marshal(sampleProduct1)}),
+			expectedErr: fmt.Errorf("data integrity violation: too many results"),
+		},
+		{
+			name:        "should return err, when cursor fails",
+			cursor:      &mongoCursosMock{idx: -1, err: fmt.Errorf("cursor error")},
+			expectedErr: fmt.Errorf("backend unavailable: error happened when using db: cursor error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PageRepositoryMongo{
+				logger: logging.Discard(),
+				mongoClient: mongoClientMock{
+					findProductFunc: func(ctx context.Context, pageId int, productId int) (MongoCursor, error) {
+						return tt.cursor, nil
+					},
+				},
+			}
+
+			product, err := p.GetProductForPage(context.Background(), 0, 0)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedProduct, product)
+		})
+	}
+}
+
+func TestPageRepositoryMongo_CreateProduct(t *testing.T) {
+	tests := []struct {
+		name        string
+		mongoClient Client
+		expectedErr error
+	}{
+		{
+			name: "should insert product, when page exists and product does not",
+			mongoClient: mongoClientMock{
+				findSeosFunc:      createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
+				findProductFunc:   createFindProductFunc(mockMongoCursor([][]byte{})),
+				insertProductFunc: func(ctx context.Context, product model.Product) error { return nil },
+			},
+		},
+		{
+			name: "should return ErrPageNotFound, when page does not exist",
+			mongoClient: mongoClientMock{
+				findSeosFunc: createFindByPageIdFunc(mockMongoCursor([][]byte{}), nil),
+			},
+			expectedErr: model.ErrPageNotFound,
+		},
+		{
+			name: "should return ErrProductAlreadyExists, when product exists in page",
+			mongoClient: mongoClientMock{
+				findSeosFunc:    createFindByPageIdFunc(mockMongoCursor([][]byte{marshal(sampleSeo)}), nil),
+				findProductFunc: createFindProductFunc(mockMongoCursor([][]byte{marshal(sampleProduct1)})),
+			},
+			expectedErr: model.ErrProductAlreadyExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PageRepositoryMongo{
+				mongoClient: tt.mongoClient,
+				logger:      logging.Discard(),
+			}
+
+			err := p.CreateProduct(context.Background(), &sampleProduct1)
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestPageRepositoryMongo_ReplaceAndDeleteProduct_shouldReturnErrProductNotFound_whenNoProductMatched(t *testing.T) {
+	p := PageRepositoryMongo{
+		logger: logging.Discard(),
+		mongoClient: mongoClientMock{
+			replaceProductFunc: func(ctx context.Context, product model.Product) (bool, error) { return false, nil },
+			deleteProductFunc:  func(ctx context.Context, pageId int, productId int) (bool, error) { return false, nil },
+		},
+	}
+
+	replaceErr := p.ReplaceProduct(context.Background(), &sampleProduct1)
+	deleteErr := p.DeleteProduct(context.Background(), 0, 0)
+
+	assert.Equal(t, model.ErrProductNotFound, replaceErr)
+	assert.Equal(t, model.ErrProductNotFound, deleteErr)
+}
+
+func TestPageRepositoryMongo_ProductWrites_shouldTouchPage(t *testing.T) {
+	touched := []int{}
+	p := PageRepositoryMongo{
+		logger: logging.Discard(),
+		mongoClient: mongoClientMock{
+			replaceProductFunc: func(ctx context.Context, product model.Product) (bool, error) { return true, nil },
+			deleteProductFunc:  func(ctx context.Context, pageId int, productId int) (bool, error) { return true, nil },
+			touchSeoFunc: func(ctx context.Context, pageId int, updatedAt time.Time) error {
+				assert.False(t, updatedAt.IsZero())
+				touched = append(touched, pageId)
+				return fmt.Errorf("touch error")
+			},
+		},
+	}
+
+	replaceErr := p.ReplaceProduct(context.Background(), &model.Product{Id: 1, PageId: 2})
+	deleteErr := p.DeleteProduct(context.Background(), 3, 1)
+
+	assert.NoError(t, replaceErr)
+	assert.NoError(t, deleteErr)
+	assert.Equal(t, []int{2, 3}, touched)
+}
+
+func createFindProductFunc(cursor MongoCursor) func(ctx context.Context, pageId int, productId int) (MongoCursor, error) {
+	return func(ctx context.Context, pageId int, productId int) (MongoCursor, error) {
+		return cursor, nil
+	}
+}
+
 func createFindFunc(cursor MongoCursor, err error) func(ctx context.Context, pageId int) (MongoCursor, error) {
 	return func(ctx context.Context, pageId int) (MongoCursor, error) {
 		if pageId == 0 {
@@ -185,6 +803,16 @@ func createFindFunc(cursor MongoCursor, err error) func(ctx context.Context, pag
 	}
 }
 
+// createFindByPageIdFunc returns cursor for whole documents of page 0.
+func createFindByPageIdFunc(cursor MongoCursor, err error) func(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+	return func(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+		if projection != nil {
+			return nil, fmt.Errorf("unexpected projection")
+		}
+		return createFindFunc(cursor, err)(ctx, pageId)
+	}
+}
+
 func mockMongoCursor(results [][]byte) MongoCursor {
 	return &mongoCursosMock{
 		idx:     -1,
@@ -198,17 +826,99 @@ func marshal(val interface{}) []byte {
 }
 
 type mongoClientMock struct {
-	findSeosFunc     func(ctx context.Context, pageId int) (MongoCursor, error)
-	findProductsFunc func(ctx context.Context, pageId int) (MongoCursor, error)
+	findSeosFunc       func(ctx context.Context, pageId int, projection []string) (MongoCursor, error)
+	findProductsFunc   func(ctx context.Context, pageId int, projection []string) (MongoCursor, error)
+	aggregatePageFunc  func(ctx context.Context, pageId int) (MongoCursor, error)
+	findSeosInFunc     func(ctx context.Context, pageIds []int) (MongoCursor, error)
+	findProductsInFunc func(ctx context.Context, pageIds []int) (MongoCursor, error)
+	insertSeoFunc      func(ctx context.Context, seo model.SEO) error
+	replaceSeoFunc     func(ctx context.Context, seo model.SEO) (bool, error)
+	deleteSeoFunc      func(ctx context.Context, pageId int) (bool, error)
+	touchSeoFunc       func(ctx context.Context, pageId int, updatedAt time.Time) error
+	insertProductsFunc func(ctx context.Context, products []model.Product) error
+	deleteProductsFunc func(ctx context.Context, pageId int) error
+	findProductFunc    func(ctx context.Context, pageId int, productId int) (MongoCursor, error)
+	queryProductsFunc  func(ctx context.Context, pageId int, query *model.ProductQuery) (MongoCursor, error)
+	countProductsFunc  func(ctx context.Context, pageId int, query *model.ProductQuery) (int64, error)
+	insertProductFunc  func(ctx context.Context, product model.Product) error
+	replaceProductFunc func(ctx context.Context, product model.Product) (bool, error)
+	deleteProductFunc  func(ctx context.Context, pageId int, productId int) (bool, error)
+}
+
+func (m mongoClientMock) FindSeos(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+	return m.findSeosFunc(ctx, pageId, projection)
+
+}
+
+func (m mongoClientMock) FindProducts(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+	return m.findProductsFunc(ctx, pageId, projection)
+}
+
+func (m mongoClientMock) AggregatePage(ctx context.Context, pageId int, _ int) (MongoCursor, error) {
+	return m.aggregatePageFunc(ctx, pageId)
 }
 
-func (m mongoClientMock) FindSeos(ctx context.Context, pageId int) (MongoCursor, error) {
-	return m.findSeosFunc(ctx, pageId)
+func (m mongoClientMock) FindSeosByPageIds(ctx context.Context, pageIds []int) (MongoCursor, error) {
+	return m.findSeosInFunc(ctx, pageIds)
+}
+
+func (m mongoClientMock) FindProductsByPageIds(ctx context.Context, pageIds []int) (MongoCursor, error) {
+	return m.findProductsInFunc(ctx, pageIds)
+}
+
+func (m mongoClientMock) InsertSeo(ctx context.Context, seo model.SEO) error {
+	return m.insertSeoFunc(ctx, seo)
+}
 
+func (m mongoClientMock) ReplaceSeo(ctx context.Context, seo model.SEO) (bool, error) {
+	return m.replaceSeoFunc(ctx, seo)
+}
+
+func (m mongoClientMock) DeleteSeo(ctx context.Context, pageId int) (bool, error) {
+	return m.deleteSeoFunc(ctx, pageId)
+}
+
+func (m mongoClientMock) TouchSeo(ctx context.Context, pageId int, updatedAt time.Time) error {
+	if m.touchSeoFunc == nil {
+		return nil
+	}
+	return m.touchSeoFunc(ctx, pageId, updatedAt)
 }
 
-func (m mongoClientMock) FindProducts(ctx context.Context, pageId int) (MongoCursor, error) {
-	return m.findProductsFunc(ctx, pageId)
+func (m mongoClientMock) InsertProducts(ctx context.Context, products []model.Product) error {
+	return m.insertProductsFunc(ctx, products)
+}
+
+func (m mongoClientMock) DeleteProducts(ctx context.Context, pageId int) error {
+	return m.deleteProductsFunc(ctx, pageId)
+}
+
+func (m mongoClientMock) FindProduct(ctx context.Context, pageId int, productId int) (MongoCursor, error) {
+	return m.findProductFunc(ctx, pageId, productId)
+}
+
+func (m mongoClientMock) QueryProducts(ctx context.Context, pageId int, query *model.ProductQuery) (MongoCursor, error) {
+	return m.queryProductsFunc(ctx, pageId, query)
+}
+
+func (m mongoClientMock) CountProducts(ctx context.Context, pageId int, query *model.ProductQuery) (int64, error) {
+	return m.countProductsFunc(ctx, pageId, query)
+}
+
+func (m mongoClientMock) InsertProduct(ctx context.Context, product model.Product) error {
+	return m.insertProductFunc(ctx, product)
+}
+
+func (m mongoClientMock) ReplaceProduct(ctx context.Context, product model.Product) (bool, error) {
+	return m.replaceProductFunc(ctx, product)
+}
+
+func (m mongoClientMock) DeleteProduct(ctx context.Context, pageId int, productId int) (bool, error) {
+	return m.deleteProductFunc(ctx, pageId, productId)
+}
+
+func (m mongoClientMock) Ping(_ context.Context) error {
+	return nil
 }
 
 func (m mongoClientMock) CloseMongoClient() error {
@@ -218,6 +928,7 @@ func (m mongoClientMock) CloseMongoClient() error {
 type mongoCursosMock struct {
 	idx     int
 	results [][]byte
+	err     error
 }
 
 func (m *mongoCursosMock) Next(_ context.Context) bool {
@@ -226,16 +937,16 @@ func (m *mongoCursosMock) Next(_ context.Context) bool {
 }
 
 func (m *mongoCursosMock) All(_ context.Context, vals interface{}) error {
-	valsArrPointer := vals.(*[]model.Product)
+	valsArr := reflect.ValueOf(vals).Elem()
 	for _, result := range m.results {
-		resultUnmarshal := model.Product{}
-		err := bson.Unmarshal(result, &resultUnmarshal)
+		resultUnmarshal := reflect.New(valsArr.Type().Elem())
+		err := bson.Unmarshal(result, resultUnmarshal.Interface())
 		if err != nil {
 			return err
 		}
-		*valsArrPointer = append(*valsArrPointer, resultUnmarshal)
+		valsArr.Set(reflect.Append(valsArr, resultUnmarshal.Elem()))
 	}
-	return nil
+	return m.err
 }
 
 func (m *mongoCursosMock) Decode(val interface{}) error {
@@ -247,5 +958,5 @@ func (m *mongoCursosMock) Close(ctx context.Context) error {
 }
 
 func (m *mongoCursosMock) Err() error {
-	return nil
+	return m.err
 }
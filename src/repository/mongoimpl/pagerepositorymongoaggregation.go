@@ -0,0 +1,67 @@
+package mongoimpl
+
+import (
+	"context"
+	"fmt"
+	"github.com/remikj/pages-ms/src/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+)
+
+// PageRepositoryMongoAggregation gets the whole page with a single aggregation query,
+// instead of separate queries for seo and products. Other methods are the same as in PageRepositoryMongo.
+type PageRepositoryMongoAggregation struct {
+	PageRepositoryMongo
+}
+
+// pageDocument is seo joined with one of its products, or with none when the page has no products.
+// Id of the seo document tells documents of different seos apart.
+type pageDocument struct {
+	Id      primitive.ObjectID `bson:"_id,omitempty"`
+	SEO     model.SEO          `bson:",inline"`
+	Product *model.Product     `bson:"products,omitempty"`
+}
+
+func InitPageRepositoryMongoAggregationFromEnv(logger *slog.Logger) (*PageRepositoryMongoAggregation, error) {
+	pageRepositoryMongo, err := InitPageRepositoryMongoFromEnv(logger)
+	if err != nil {
+		return nil, err
+	}
+	return &PageRepositoryMongoAggregation{PageRepositoryMongo: *pageRepositoryMongo}, nil
+}
+
+// GetPage returns page with its products, or nil when there is no seo for the page.
+// Every product comes in its own document, so pages are not limited by maximum size of a document.
+func (p PageRepositoryMongoAggregation) GetPage(ctx context.Context, pageId int, productsLimit int) (*model.Page, error) {
+	p.logger.Debug("getting page", "page_id", pageId, "products_limit", productsLimit)
+	cursor, err := p.mongoClient.AggregatePage(ctx, pageId, productsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	defer cursor.Close(ctx)
+
+	var page *model.Page
+	var seoId primitive.ObjectID
+	for cursor.Next(ctx) {
+		document := pageDocument{}
+		if err := cursor.Decode(&document); err != nil {
+			return nil, fmt.Errorf("%w: error happened when decoding results: %w", model.ErrDataIntegrity, err)
+		}
+		if page == nil {
+			page = &model.Page{SEO: document.SEO}
+			seoId = document.Id
+		} else if document.Id != seoId || document.Product == nil {
+			return nil, fmt.Errorf("%w: too many results", model.ErrDataIntegrity)
+		}
+		if document.Product != nil {
+			page.Products = append(page.Products, *document.Product)
+		}
+	}
+	if err := cursorErr(cursor); err != nil {
+		return nil, err
+	}
+	if page != nil {
+		page.Normalize()
+	}
+	return page, nil
+}
@@ -0,0 +1,106 @@
+package mongoimpl
+
+import (
+	"context"
+	"fmt"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+func TestPageRepositoryMongoAggregation_GetPage(t *testing.T) {
+	seoId := primitive.NewObjectID()
+	tests := []struct {
+		name         string
+		mongoClient  Client
+		expectedPage *model.Page
+		expectedErr  string
+		expectedIs   error
+	}{
+		{
+			name: "should return page with products, when document for each product in cursor",
+			mongoClient: mongoClientMock{
+				aggregatePageFunc: createFindFunc(mockMongoCursor([][]byte{
+					marshal(pageDocument{Id: seoId, SEO: sampleSeo, Product: &sampleProduct1}),
+					marshal(pageDocument{Id: seoId, SEO: sampleSeo, Product: &sampleProduct2}),
+				}), nil),
+			},
+			expectedPage: &model.Page{SEO: sampleSeo, Products: sampleProducts},
+		},
+		{
+			name: "should return page with empty products, when page has no products",
+			mongoClient: mongoClientMock{
+				aggregatePageFunc: createFindFunc(mockMongoCursor([][]byte{
+					marshal(bson.M{"_id": seoId, "page_id": 0, "title": "title", "description": "description", "robots": "robots"}),
+				}), nil),
+			},
+			expectedPage: &model.Page{SEO: sampleSeo, Products: []model.Product{}},
+		},
+		{
+			name: "should return nil, when no documents in cursor",
+			mongoClient: mongoClientMock{
+				aggregatePageFunc: createFindFunc(mockMongoCursor([][]byte{}), nil),
+			},
+			expectedPage: nil,
+		},
+		{
+			name: "should return err, when documents of more than one seo in cursor",
+			mongoClient: mongoClientMock{
+				aggregatePageFunc: createFindFunc(mockMongoCursor([][]byte{
+					marshal(pageDocument{Id: seoId, SEO: sampleSeo, Product: &sampleProduct1}),
+					marshal(pageDocument{Id: primitive.NewObjectID(), SEO: sampleSeo, Product: &sampleProduct2}),
+				}), nil),
+			},
+			expectedErr: "data integrity violation: too many results",
+			expectedIs:  model.ErrDataIntegrity,
+		},
+		{
+			name: "should return err, when cursor fails",
+			mongoClient: mongoClientMock{
+				aggregatePageFunc: createFindFunc(&mongoCursosMock{idx: -1, err: fmt.Errorf("cursor failed")}, nil),
+			},
+			expectedErr: "backend unavailable: error happened when using db: cursor failed",
+			expectedIs:  model.ErrBackendUnavailable,
+		},
+		{
+			name: "should return err, when aggregation fails",
+			mongoClient: mongoClientMock{
+				aggregatePageFunc: createFindFunc(nil, fmt.Errorf("aggregation failed")),
+			},
+			expectedErr: "backend unavailable: error happened when using db: aggregation failed",
+			expectedIs:  model.ErrBackendUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PageRepositoryMongoAggregation{
+				PageRepositoryMongo: PageRepositoryMongo{mongoClient: tt.mongoClient, logger: logging.Discard()},
+			}
+
+			page, err := p.GetPage(context.Background(), 0, 0)
+
+			assert.Equal(t, tt.expectedPage, page)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				assert.ErrorIs(t, err, tt.expectedIs)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPagePipeline_shouldJoinLowestIdProducts_whenProductsLimited(t *testing.T) {
+	unlimitedLookup := pagePipeline(0, 0)[1][0].Value.(bson.D)
+	limitedLookup := pagePipeline(0, 2)[1][0].Value.(bson.D)
+
+	assert.Len(t, unlimitedLookup, 4)
+	assert.Equal(t, bson.E{Key: "pipeline", Value: mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "id", Value: 1}}}},
+		{{Key: "$limit", Value: 2}},
+	}}, limitedLookup[4])
+}
@@ -0,0 +1,305 @@
+package mongoimpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/metrics"
+	"github.com/remikj/pages-ms/src/model"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// ResilienceConfiguration of retries and circuit breaker around mongo calls. RetryAttempts of 1 disables retries,
+// BreakerFailureRatio of 0 disables the circuit breaker.
+type ResilienceConfiguration struct {
+	RetryAttempts       int           `envconfig:"MONGO_RETRY_ATTEMPTS" default:"3"`
+	RetryBackoff        time.Duration `envconfig:"MONGO_RETRY_BACKOFF" default:"50ms"`
+	RetryMaxBackoff     time.Duration `envconfig:"MONGO_RETRY_MAX_BACKOFF" default:"1s"`
+	BreakerFailureRatio float64       `envconfig:"MONGO_BREAKER_FAILURE_RATIO" default:"0.5"`
+	BreakerMinRequests  int           `envconfig:"MONGO_BREAKER_MIN_REQUESTS" default:"20"`
+	BreakerWindow       time.Duration `envconfig:"MONGO_BREAKER_WINDOW" default:"10s"`
+	BreakerOpenTimeout  time.Duration `envconfig:"MONGO_BREAKER_OPEN_TIMEOUT" default:"5s"`
+}
+
+// notPrimaryCodes are codes of server errors returned while replica set is electing new primary
+// or the server is shutting down, the same call is expected to succeed shortly.
+var notPrimaryCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// ResilientClient decorates Client with retries of transient errors and circuit breaker.
+// Reads are retried with exponential backoff with full jitter. Writes are not, the driver already retries them
+// once with retryable writes, and retrying not idempotent write here could apply it twice. Only the call
+// returning cursor is retried, errors of iterating the cursor are returned as they are.
+// Every call, including each retry, goes through the circuit breaker, which fails fast with ErrCircuitOpen.
+type ResilientClient struct {
+	client  Client
+	config  *ResilienceConfiguration
+	breaker *circuitBreaker
+	logger  *slog.Logger
+}
+
+func ResilienceConfigurationFromEnv() (*ResilienceConfiguration, error) {
+	config := &ResilienceConfiguration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	if config.RetryAttempts < 1 {
+		return nil, fmt.Errorf("MONGO_RETRY_ATTEMPTS has to be at least 1, got %v", config.RetryAttempts)
+	}
+	if config.BreakerFailureRatio < 0 || config.BreakerFailureRatio > 1 {
+		return nil, fmt.Errorf("MONGO_BREAKER_FAILURE_RATIO has to be between 0 and 1, got %v", config.BreakerFailureRatio)
+	}
+	return config, nil
+}
+
+func NewResilientClient(config *ResilienceConfiguration, client Client, logger *slog.Logger) *ResilientClient {
+	resilientClient := &ResilientClient{
+		client: client,
+		config: config,
+		logger: logger,
+	}
+	if config.BreakerFailureRatio > 0 {
+		resilientClient.breaker = newCircuitBreaker(config, logger)
+		resilientClient.registerMetrics()
+	}
+	return resilientClient
+}
+
+func (c *ResilientClient) FindSeos(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+	return retry(ctx, c, "FindSeos", func(ctx context.Context) (MongoCursor, error) {
+		return c.client.FindSeos(ctx, pageId, projection)
+	})
+}
+
+func (c *ResilientClient) FindProducts(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+	return retry(ctx, c, "FindProducts", func(ctx context.Context) (MongoCursor, error) {
+		return c.client.FindProducts(ctx, pageId, projection)
+	})
+}
+
+func (c *ResilientClient) AggregatePage(ctx context.Context, pageId int, productsLimit int) (MongoCursor, error) {
+	return retry(ctx, c, "AggregatePage", func(ctx context.Context) (MongoCursor, error) {
+		return c.client.AggregatePage(ctx, pageId, productsLimit)
+	})
+}
+
+func (c *ResilientClient) FindSeosByPageIds(ctx context.Context, pageIds []int) (MongoCursor, error) {
+	return retry(ctx, c, "FindSeosByPageIds", func(ctx context.Context) (MongoCursor, error) {
+		return c.client.FindSeosByPageIds(ctx, pageIds)
+	})
+}
+
+func (c *ResilientClient) FindProductsByPageIds(ctx context.Context, pageIds []int) (MongoCursor, error) {
+	return retry(ctx, c, "FindProductsByPageIds", func(ctx context.Context) (MongoCursor, error) {
+		return c.client.FindProductsByPageIds(ctx, pageIds)
+	})
+}
+
+func (c *ResilientClient) FindProduct(ctx context.Context, pageId int, productId int) (MongoCursor, error) {
+	return retry(ctx, c, "FindProduct", func(ctx context.Context) (MongoCursor, error) {
+		return c.client.FindProduct(ctx, pageId, productId)
+	})
+}
+
+func (c *ResilientClient) QueryProducts(ctx context.Context, pageId int, query *model.ProductQuery) (MongoCursor, error) {
+	return retry(ctx, c, "QueryProducts", func(ctx context.Context) (MongoCursor, error) {
+		return c.client.QueryProducts(ctx, pageId, query)
+	})
+}
+
+func (c *ResilientClient) CountProducts(ctx context.Context, pageId int, query *model.ProductQuery) (int64, error) {
+	return retry(ctx, c, "CountProducts", func(ctx context.Context) (int64, error) {
+		return c.client.CountProducts(ctx, pageId, query)
+	})
+}
+
+func (c *ResilientClient) InsertSeo(ctx context.Context, seo model.SEO) error {
+	return c.callWrite(ctx, "InsertSeo", func() error {
+		return c.client.InsertSeo(ctx, seo)
+	})
+}
+
+func (c *ResilientClient) ReplaceSeo(ctx context.Context, seo model.SEO) (replaced bool, err error) {
+	err = c.callWrite(ctx, "ReplaceSeo", func() error {
+		replaced, err = c.client.ReplaceSeo(ctx, seo)
+		return err
+	})
+	return replaced, err
+}
+
+func (c *ResilientClient) DeleteSeo(ctx context.Context, pageId int) (deleted bool, err error) {
+	err = c.callWrite(ctx, "DeleteSeo", func() error {
+		deleted, err = c.client.DeleteSeo(ctx, pageId)
+		return err
+	})
+	return deleted, err
+}
+
+func (c *ResilientClient) TouchSeo(ctx context.Context, pageId int, updatedAt time.Time) error {
+	return c.callWrite(ctx, "TouchSeo", func() error {
+		return c.client.TouchSeo(ctx, pageId, updatedAt)
+	})
+}
+
+func (c *ResilientClient) InsertProducts(ctx context.Context, products []model.Product) error {
+	return c.callWrite(ctx, "InsertProducts", func() error {
+		return c.client.InsertProducts(ctx, products)
+	})
+}
+
+func (c *ResilientClient) DeleteProducts(ctx context.Context, pageId int) error {
+	return c.callWrite(ctx, "DeleteProducts", func() error {
+		return c.client.DeleteProducts(ctx, pageId)
+	})
+}
+
+func (c *ResilientClient) InsertProduct(ctx context.Context, product model.Product) error {
+	return c.callWrite(ctx, "InsertProduct", func() error {
+		return c.client.InsertProduct(ctx, product)
+	})
+}
+
+func (c *ResilientClient) ReplaceProduct(ctx context.Context, product model.Product) (replaced bool, err error) {
+	err = c.callWrite(ctx, "ReplaceProduct", func() error {
+		replaced, err = c.client.ReplaceProduct(ctx, product)
+		return err
+	})
+	return replaced, err
+}
+
+func (c *ResilientClient) DeleteProduct(ctx context.Context, pageId int, productId int) (deleted bool, err error) {
+	err = c.callWrite(ctx, "DeleteProduct", func() error {
+		deleted, err = c.client.DeleteProduct(ctx, pageId, productId)
+		return err
+	})
+	return deleted, err
+}
+
+// Ping bypasses retries and the circuit breaker, so readiness reports whether mongo is reachable right now.
+func (c *ResilientClient) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx)
+}
+
+func (c *ResilientClient) CloseMongoClient() error {
+	return c.client.CloseMongoClient()
+}
+
+// CheckCircuit returns ErrCircuitOpen while the circuit breaker rejects calls.
+func (c *ResilientClient) CheckCircuit(_ context.Context) error {
+	if c.breaker == nil {
+		return nil
+	}
+	return c.breaker.check()
+}
+
+// retry calls mongo through the circuit breaker until it succeeds, fails with error which is not transient,
+// RetryAttempts are used up or ctx is done. Backoff before each retry is random, up to RetryBackoff doubled
+// with each attempt and capped at RetryMaxBackoff, so clients failed at the same time do not retry together.
+func retry[T any](ctx context.Context, c *ResilientClient, method string, call func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	var err error
+	for attempt := 1; ; attempt++ {
+		result, err = callThroughBreaker(ctx, c, method, func() (T, error) { return call(ctx) })
+		if err == nil || attempt >= c.config.RetryAttempts || !isTransient(err) {
+			return result, err
+		}
+		backoff := c.backoff(attempt)
+		c.logger.Debug("retrying mongo call", "method", method, "attempt", attempt, "backoff", backoff, "error", err)
+		if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+			return result, err
+		}
+		mongoRetries.Inc(method)
+	}
+}
+
+func (c *ResilientClient) callWrite(ctx context.Context, method string, call func() error) error {
+	_, err := callThroughBreaker(ctx, c, method, func() (struct{}, error) { return struct{}{}, call() })
+	return err
+}
+
+func callThroughBreaker[T any](ctx context.Context, c *ResilientClient, method string, call func() (T, error)) (T, error) {
+	if c.breaker == nil {
+		return call()
+	}
+	probe, err := c.breaker.allow()
+	if err != nil {
+		mongoCircuitRejections.Inc(method)
+		var zero T
+		return zero, err
+	}
+	result, err := call()
+	c.breaker.record(ctx, probe, err)
+	return result, err
+}
+
+func (c *ResilientClient) backoff(attempt int) time.Duration {
+	backoff := c.config.RetryMaxBackoff
+	if shift := attempt - 1; shift < 32 && c.config.RetryBackoff<<shift < backoff {
+		backoff = c.config.RetryBackoff << shift
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+func (c *ResilientClient) registerMetrics() {
+	metrics.NewGaugeFunc("mongo_circuit_breaker_state", "State of mongo circuit breaker: 0 closed, 1 half open, 2 open.", func() float64 {
+		return circuitStateValues[c.breaker.currentState()]
+	})
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isTransient reports whether the call failed because mongo was not reachable or was changing primary.
+func isTransient(err error) bool {
+	var selectionErr topology.ServerSelectionError
+	if mongo.IsNetworkError(err) || errors.As(err, &selectionErr) {
+		return true
+	}
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	if serverErr.HasErrorLabel("RetryableWriteError") {
+		return true
+	}
+	for _, code := range notPrimaryCodes {
+		if serverErr.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFailure reports whether the call failed because of mongo, errors of valid requests, e.g. duplicate key,
+// show that mongo works and are not failures. Timeouts are failures only while ctx is live, as deadlines
+// of callers, e.g. REQUEST_TIMEOUT or budgets of page reads, may be shorter than healthy mongo needs.
+func isFailure(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && (isTransient(err) || mongo.IsTimeout(err))
+}
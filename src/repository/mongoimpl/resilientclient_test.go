@@ -0,0 +1,152 @@
+package mongoimpl
+
+import (
+	"context"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var sampleResilienceConfiguration = &ResilienceConfiguration{
+	RetryAttempts:   3,
+	RetryBackoff:    time.Millisecond,
+	RetryMaxBackoff: 5 * time.Millisecond,
+}
+
+func TestResilientClient_FindSeos_shouldRetryTransientErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		errs          []error
+		expectedErr   error
+		expectedCalls int
+	}{
+		{
+			name:          "should return cursor, when call succeeds after transient errors",
+			errs:          []error{sampleNetworkErr, sampleTransientErr, nil},
+			expectedCalls: 3,
+		},
+		{
+			name:          "should return last error, when attempts are used up",
+			errs:          []error{sampleNetworkErr, sampleNetworkErr, sampleTransientErr, nil},
+			expectedErr:   sampleTransientErr,
+			expectedCalls: 3,
+		},
+		{
+			name:          "should not retry, when error is not transient",
+			errs:          []error{sampleDuplicateKeyErr, nil},
+			expectedErr:   sampleDuplicateKeyErr,
+			expectedCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			retriesBefore := mongoRetries.Value("FindSeos")
+			client := NewResilientClient(sampleResilienceConfiguration, mongoClientMock{
+				findSeosFunc: func(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+					err := tt.errs[calls]
+					calls++
+					if err != nil {
+						return nil, err
+					}
+					return &mongoCursosMock{}, nil
+				},
+			}, logging.Discard())
+
+			cursor, err := client.FindSeos(context.Background(), 1, nil)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedErr == nil, cursor != nil)
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.Equal(t, retriesBefore+float64(tt.expectedCalls-1), mongoRetries.Value("FindSeos"))
+		})
+	}
+}
+
+func TestResilientClient_FindSeos_shouldStopRetrying_whenContextDone(t *testing.T) {
+	calls := 0
+	config := *sampleResilienceConfiguration
+	config.RetryBackoff, config.RetryMaxBackoff = time.Second, time.Second
+	client := NewResilientClient(&config, mongoClientMock{
+		findSeosFunc: func(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+			calls++
+			return nil, sampleNetworkErr
+		},
+	}, logging.Discard())
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFunc()
+
+	testStart := time.Now()
+	_, err := client.FindSeos(ctx, 1, nil)
+
+	assert.Equal(t, sampleNetworkErr, err)
+	assert.Equal(t, 1, calls)
+	assert.Less(t, time.Since(testStart), 500*time.Millisecond)
+}
+
+func TestResilientClient_InsertSeo_shouldNotRetryWrites(t *testing.T) {
+	calls := 0
+	client := NewResilientClient(sampleResilienceConfiguration, mongoClientMock{
+		insertSeoFunc: func(ctx context.Context, seo model.SEO) error {
+			calls++
+			return sampleNetworkErr
+		},
+	}, logging.Discard())
+
+	err := client.InsertSeo(context.Background(), model.SEO{PageId: 1})
+
+	assert.Equal(t, sampleNetworkErr, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestResilientClient_shouldFailFast_whenCircuitOpen(t *testing.T) {
+	calls := 0
+	config := *sampleResilienceConfiguration
+	config.RetryAttempts = 1
+	config.BreakerFailureRatio, config.BreakerMinRequests = 0.5, 2
+	config.BreakerWindow, config.BreakerOpenTimeout = time.Minute, time.Minute
+	client := NewResilientClient(&config, mongoClientMock{
+		countProductsFunc: func(ctx context.Context, pageId int, query *model.ProductQuery) (int64, error) {
+			calls++
+			return 0, sampleNetworkErr
+		},
+		replaceProductFunc: func(ctx context.Context, product model.Product) (bool, error) {
+			calls++
+			return true, nil
+		},
+	}, logging.Discard())
+	rejectionsBefore := mongoCircuitRejections.Value("ReplaceProduct")
+
+	_, _ = client.CountProducts(context.Background(), 1, &model.ProductQuery{})
+	_, _ = client.CountProducts(context.Background(), 1, &model.ProductQuery{})
+	replaced, err := client.ReplaceProduct(context.Background(), model.Product{PageId: 1})
+
+	assert.False(t, replaced)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, rejectionsBefore+1, mongoCircuitRejections.Value("ReplaceProduct"))
+	assert.ErrorIs(t, client.CheckCircuit(context.Background()), ErrCircuitOpen)
+	assert.NoError(t, client.Ping(context.Background()), "should ping regardless of circuit breaker")
+}
+
+func TestPageRepositoryMongo_GetSeoForPage_shouldReturnBackendUnavailable_whenCircuitOpen(t *testing.T) {
+	config := *sampleResilienceConfiguration
+	config.BreakerFailureRatio, config.BreakerMinRequests = 0.5, 1
+	config.BreakerWindow, config.BreakerOpenTimeout = time.Minute, time.Minute
+	repository := PageRepositoryMongo{
+		mongoClient: NewResilientClient(&config, mongoClientMock{
+			findSeosFunc: func(ctx context.Context, pageId int, projection []string) (MongoCursor, error) {
+				return nil, sampleNetworkErr
+			},
+		}, logging.Discard()),
+		logger: logging.Discard(),
+	}
+
+	_, err := repository.GetSeoForPage(context.Background(), 1)
+
+	assert.ErrorIs(t, err, model.ErrBackendUnavailable)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, repository.CheckCircuit(context.Background()), ErrCircuitOpen)
+}
@@ -2,16 +2,117 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
 	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/repository/memoryimpl"
 	"github.com/remikj/pages-ms/src/repository/mongoimpl"
+	"github.com/remikj/pages-ms/src/repository/sqlimpl"
+	"log/slog"
 )
 
+const (
+	BackendMongo  = "mongo"
+	BackendMemory = "memory"
+	BackendSQL    = "sql"
+
+	PageQueryFanOut      = "fanout"
+	PageQueryAggregation = "aggregation"
+)
+
+type Configuration struct {
+	Backend   string `envconfig:"REPOSITORY_BACKEND" default:"mongo"`
+	PageQuery string `envconfig:"MONGO_PAGE_QUERY" default:"fanout"`
+}
+
 type PageRepository interface {
 	GetSeoForPage(ctx context.Context, pageId int) (*model.SEO, error)
 	GetProductsForPage(ctx context.Context, pageId int) ([]model.Product, error)
+	GetSeosForPages(ctx context.Context, pageIds []int) ([]model.SEO, error)
+	// GetProductsForPages returns products of all the pages, at most limit products of each page with the lowest ids,
+	// or all of them when limit is 0.
+	GetProductsForPages(ctx context.Context, pageIds []int, limit int) ([]model.Product, error)
+	CreatePage(ctx context.Context, page *model.Page) error
+	ReplacePage(ctx context.Context, page *model.Page) error
+	DeletePage(ctx context.Context, pageId int) error
+	GetProductForPage(ctx context.Context, pageId int, productId int) (*model.Product, error)
+	// QueryProductsForPage returns at most query.Limit products of the page, which match the query, in its order.
+	QueryProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) ([]model.Product, error)
+	// CountProductsForPage returns number of products of the page matching filters of the query, ignoring its cursor.
+	CountProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (int, error)
+	CreateProduct(ctx context.Context, product *model.Product) error
+	ReplaceProduct(ctx context.Context, product *model.Product) error
+	DeleteProduct(ctx context.Context, pageId int, productId int) error
+	Ping(ctx context.Context) error
 	CloseRepository() error
 }
 
-func InitPageRepositoryFromEnv() (PageRepository, error) {
-	return mongoimpl.InitPageRepositoryMongoFromEnv()
+// PageAssembler is implemented by PageRepository able to get the whole page in a single query.
+// GetPage returns nil when the page does not exist. Page has at most productsLimit products with the lowest ids,
+// or all of them when productsLimit is 0.
+type PageAssembler interface {
+	GetPage(ctx context.Context, pageId int, productsLimit int) (*model.Page, error)
+}
+
+// PageProjector is implemented by PageRepository able to read only selected fields of the page.
+// Fields are names of fields of model.SEO and model.Product, fields that are not read have zero values.
+type PageProjector interface {
+	GetSeoFieldsForPage(ctx context.Context, pageId int, fields []string) (*model.SEO, error)
+	GetProductFieldsForPage(ctx context.Context, pageId int, fields []string) ([]model.Product, error)
+}
+
+// ProductStreamer is implemented by PageRepository able to read products of the page one by one, without keeping
+// all of them in memory. StreamProductsForPage calls yield for each product, in the same order as GetProductsForPage
+// returns them, and stops with the first error returned by yield.
+type ProductStreamer interface {
+	StreamProductsForPage(ctx context.Context, pageId int, yield func(product *model.Product) error) error
+}
+
+// CircuitChecker is implemented by PageRepository calling its backend through a circuit breaker.
+// CheckCircuit returns error while the breaker rejects calls, without calling the backend.
+type CircuitChecker interface {
+	CheckCircuit(ctx context.Context) error
+}
+
+func InitPageRepositoryFromEnv(logger *slog.Logger) (PageRepository, error) {
+	config, err := ConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewPageRepository(config, logger)
+}
+
+// NewPageRepository creates repository of configured backend. Mongo repository gets pages with separate
+// seo and products queries, or with a single aggregation when PageQuery is aggregation.
+func NewPageRepository(config *Configuration, logger *slog.Logger) (PageRepository, error) {
+	switch config.Backend {
+	case BackendMongo:
+		return newPageRepositoryMongo(config, logger)
+	case BackendMemory:
+		return memoryimpl.InitPageRepositoryMemoryFromEnv(logger)
+	case BackendSQL:
+		return sqlimpl.InitPageRepositorySQLFromEnv(logger)
+	default:
+		return nil, fmt.Errorf("unknown REPOSITORY_BACKEND %q, expected %v, %v or %v", config.Backend, BackendMongo, BackendMemory, BackendSQL)
+	}
+}
+
+func newPageRepositoryMongo(config *Configuration, logger *slog.Logger) (PageRepository, error) {
+	switch config.PageQuery {
+	case PageQueryFanOut:
+		return mongoimpl.InitPageRepositoryMongoFromEnv(logger)
+	case PageQueryAggregation:
+		return mongoimpl.InitPageRepositoryMongoAggregationFromEnv(logger)
+	default:
+		return nil, fmt.Errorf("unknown MONGO_PAGE_QUERY %q, expected %v or %v", config.PageQuery, PageQueryFanOut, PageQueryAggregation)
+	}
+}
+
+func ConfigurationFromEnv() (*Configuration, error) {
+	config := &Configuration{}
+	err := envconfig.Process("", config)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
 }
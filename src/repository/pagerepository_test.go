@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/repository/memoryimpl"
+	"github.com/remikj/pages-ms/src/repository/sqlimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigurationFromEnv_shouldUseFanOut_whenEnvNotSet(t *testing.T) {
+	config, err := ConfigurationFromEnv()
+
+	require.NoError(t, err)
+	assert.Equal(t, BackendMongo, config.Backend)
+	assert.Equal(t, PageQueryFanOut, config.PageQuery)
+}
+
+func TestNewPageRepository_shouldReturnMemoryRepository_whenMemoryBackend(t *testing.T) {
+	pageRepository, err := NewPageRepository(&Configuration{Backend: BackendMemory}, logging.Discard())
+
+	assert.NoError(t, err)
+	assert.IsType(t, &memoryimpl.PageRepositoryMemory{}, pageRepository)
+}
+
+func TestNewPageRepository_shouldReturnMigratedSQLRepository_whenSQLBackend(t *testing.T) {
+	t.Setenv("SQL_DSN", "file:"+filepath.Join(t.TempDir(), "pages.db")+"?_pragma=foreign_keys(1)")
+
+	pageRepository, err := NewPageRepository(&Configuration{Backend: BackendSQL}, logging.Discard())
+
+	require.NoError(t, err)
+	defer pageRepository.CloseRepository()
+	assert.IsType(t, &sqlimpl.PageRepositorySQL{}, pageRepository)
+	assert.NoError(t, pageRepository.Ping(context.Background()))
+}
+
+func TestNewPageRepository_shouldReturnErr_whenBackendUnknown(t *testing.T) {
+	pageRepository, err := NewPageRepository(&Configuration{Backend: "redis"}, logging.Discard())
+
+	assert.Nil(t, pageRepository)
+	assert.EqualError(t, err, `unknown REPOSITORY_BACKEND "redis", expected mongo, memory or sql`)
+}
+
+func TestInitPageRepositoryFromEnv_shouldReturnErr_whenPageQueryUnknown(t *testing.T) {
+	t.Setenv("MONGO_PAGE_QUERY", "join")
+
+	pageRepository, err := InitPageRepositoryFromEnv(logging.Discard())
+
+	assert.Nil(t, pageRepository)
+	assert.EqualError(t, err, `unknown MONGO_PAGE_QUERY "join", expected fanout or aggregation`)
+}
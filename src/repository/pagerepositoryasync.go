@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
 	"github.com/remikj/pages-ms/src/model"
+	"time"
 )
 
 type ResultSEO struct {
@@ -15,41 +18,158 @@ type ResultProducts struct {
 	Err      error
 }
 
+type ResultSEOs struct {
+	SEOs []model.SEO
+	Err  error
+}
+
+type ResultProduct struct {
+	Product *model.Product
+	Err     error
+}
+
+type ResultCount struct {
+	Count int
+	Err   error
+}
+
+type ResultPage struct {
+	Page *model.Page
+	Err  error
+}
+
 type PageRepositoryAsync interface {
-	GetSeoForPage(pageId int) (<-chan ResultSEO, context.CancelFunc)
-	GetProductsForPage(pageId int) (<-chan ResultProducts, context.CancelFunc)
+	GetSeoForPage(ctx context.Context, pageId int) (<-chan ResultSEO, context.CancelFunc)
+	GetProductsForPage(ctx context.Context, pageId int) (<-chan ResultProducts, context.CancelFunc)
+	GetSeoFieldsForPage(ctx context.Context, pageId int, fields []string) (<-chan ResultSEO, context.CancelFunc)
+	GetProductFieldsForPage(ctx context.Context, pageId int, fields []string) (<-chan ResultProducts, context.CancelFunc)
+	GetSeosForPages(ctx context.Context, pageIds []int) (<-chan ResultSEOs, context.CancelFunc)
+	GetProductsForPages(ctx context.Context, pageIds []int, limit int) (<-chan ResultProducts, context.CancelFunc)
+	CreatePage(ctx context.Context, page *model.Page) (<-chan error, context.CancelFunc)
+	ReplacePage(ctx context.Context, page *model.Page) (<-chan error, context.CancelFunc)
+	DeletePage(ctx context.Context, pageId int) (<-chan error, context.CancelFunc)
+	GetProductForPage(ctx context.Context, pageId int, productId int) (<-chan ResultProduct, context.CancelFunc)
+	QueryProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (<-chan ResultProducts, context.CancelFunc)
+	// StreamProductsForPage sends products of the page one by one and closes the channel after the last one.
+	// Error of the stream is sent as the last result.
+	StreamProductsForPage(ctx context.Context, pageId int) (<-chan ResultProduct, context.CancelFunc)
+	CountProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (<-chan ResultCount, context.CancelFunc)
+	CreateProduct(ctx context.Context, product *model.Product) (<-chan error, context.CancelFunc)
+	ReplaceProduct(ctx context.Context, product *model.Product) (<-chan error, context.CancelFunc)
+	DeleteProduct(ctx context.Context, pageId int, productId int) (<-chan error, context.CancelFunc)
 }
 
+// AsyncConfiguration limits queries run by PageRepositoryAsync. MaxInFlight of 0 runs all queries right away.
+// Streams last as long as their consumer reads them, so they are limited separately by MaxStreams and never wait
+// in the queue, MaxStreams of 0 runs all streams right away.
+type AsyncConfiguration struct {
+	MaxInFlight int           `envconfig:"REPOSITORY_MAX_IN_FLIGHT" default:"64"`
+	QueueSize   int           `envconfig:"REPOSITORY_QUEUE_SIZE" default:"256"`
+	MaxStreams  int           `envconfig:"REPOSITORY_MAX_STREAMS" default:"16"`
+	RetryAfter  time.Duration `envconfig:"REPOSITORY_OVERLOAD_RETRY_AFTER" default:"1s"`
+}
+
+// PageRepositoryAsyncImpl runs queries in contexts derived from ctx passed to each method,
+// so cancelling ctx, or calling returned CancelFunc, cancels outstanding query.
+// At most MaxInFlight queries run at once, others wait in the queue or fail with model.OverloadedError.
+// At most MaxStreams streams run at once, others fail with model.OverloadedError.
 type PageRepositoryAsyncImpl struct {
-	pageRepo PageRepository
+	pageRepo   PageRepository
+	pool       *workerPool
+	streamPool *workerPool
+}
+
+// PageAssemblerAsync is implemented by PageRepositoryAsync able to get the whole page in a single query.
+type PageAssemblerAsync interface {
+	PageRepositoryAsync
+	GetPage(ctx context.Context, pageId int, productsLimit int) (<-chan ResultPage, context.CancelFunc)
 }
 
-func InitPageRepositoryAsyncFromEnv() (PageRepositoryAsync, error) {
-	pageRepo, err := InitPageRepositoryFromEnv()
+// PageAssemblerAsyncImpl is PageRepositoryAsyncImpl for repositories implementing PageAssembler.
+type PageAssemblerAsyncImpl struct {
+	PageRepositoryAsyncImpl
+	pageAssembler PageAssembler
+}
+
+func NewPageRepositoryAsyncFromEnv(pageRepo PageRepository) (PageRepositoryAsync, error) {
+	config, err := AsyncConfigurationFromEnv()
 	if err != nil {
 		return nil, err
 	}
-	return &PageRepositoryAsyncImpl{pageRepo: pageRepo}, nil
+	return NewPageRepositoryAsync(config, pageRepo), nil
+}
+
+// NewPageRepositoryAsync returns PageAssemblerAsync when pageRepo implements PageAssembler.
+func NewPageRepositoryAsync(config *AsyncConfiguration, pageRepo PageRepository) PageRepositoryAsync {
+	pool := newWorkerPool(poolQuery, config)
+	if pool != nil {
+		pool.registerMetrics("queries")
+	}
+	streamPool := newWorkerPool(poolStream, &AsyncConfiguration{MaxInFlight: config.MaxStreams, RetryAfter: config.RetryAfter})
+	if streamPool != nil {
+		streamPool.registerMetrics("streams")
+	}
+	pageRepositoryAsync := PageRepositoryAsyncImpl{pageRepo: pageRepo, pool: pool, streamPool: streamPool}
+	if pageAssembler, ok := pageRepo.(PageAssembler); ok {
+		return PageAssemblerAsyncImpl{PageRepositoryAsyncImpl: pageRepositoryAsync, pageAssembler: pageAssembler}
+	}
+	return pageRepositoryAsync
+}
+
+func AsyncConfigurationFromEnv() (*AsyncConfiguration, error) {
+	config := &AsyncConfiguration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	if config.MaxInFlight > 0 && config.QueueSize < 0 {
+		return nil, fmt.Errorf("REPOSITORY_QUEUE_SIZE can not be negative, got %v", config.QueueSize)
+	}
+	return config, nil
+}
+
+func (p PageAssemblerAsyncImpl) GetPage(ctx context.Context, pageId int, productsLimit int) (<-chan ResultPage, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	pageChan := make(chan ResultPage, 1)
+	go func() {
+		var page *model.Page
+		err := p.pool.run(ctx, func() (err error) {
+			page, err = p.pageAssembler.GetPage(ctx, pageId, productsLimit)
+			return err
+		})
+		pageChan <- ResultPage{
+			Page: page,
+			Err:  err,
+		}
+	}()
+	return pageChan, cancelFunc
 }
 
-func (p PageRepositoryAsyncImpl) GetSeoForPage(pageId int) (<-chan ResultSEO, context.CancelFunc) {
-	ctx, cancelFunc := context.WithCancel(context.TODO())
+func (p PageRepositoryAsyncImpl) GetSeoForPage(ctx context.Context, pageId int) (<-chan ResultSEO, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
 	seoChan := make(chan ResultSEO, 1)
 	go func() {
-		page, err := p.pageRepo.GetSeoForPage(ctx, pageId)
+		var seo *model.SEO
+		err := p.pool.run(ctx, func() (err error) {
+			seo, err = p.pageRepo.GetSeoForPage(ctx, pageId)
+			return err
+		})
 		seoChan <- ResultSEO{
-			SEO: page,
+			SEO: seo,
 			Err: err,
 		}
 	}()
 	return seoChan, cancelFunc
 }
 
-func (p PageRepositoryAsyncImpl) GetProductsForPage(pageId int) (<-chan ResultProducts, context.CancelFunc) {
-	ctx, cancelFunc := context.WithCancel(context.TODO())
+func (p PageRepositoryAsyncImpl) GetProductsForPage(ctx context.Context, pageId int) (<-chan ResultProducts, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
 	productsChan := make(chan ResultProducts, 1)
 	go func() {
-		products, err := p.pageRepo.GetProductsForPage(ctx, pageId)
+		var products []model.Product
+		err := p.pool.run(ctx, func() (err error) {
+			products, err = p.pageRepo.GetProductsForPage(ctx, pageId)
+			return err
+		})
 		productsChan <- ResultProducts{
 			Products: products,
 			Err:      err,
@@ -57,3 +177,226 @@ func (p PageRepositoryAsyncImpl) GetProductsForPage(pageId int) (<-chan ResultPr
 	}()
 	return productsChan, cancelFunc
 }
+
+// GetSeoFieldsForPage reads only selected fields when repository implements PageProjector, whole seo otherwise.
+func (p PageRepositoryAsyncImpl) GetSeoFieldsForPage(ctx context.Context, pageId int, fields []string) (<-chan ResultSEO, context.CancelFunc) {
+	pageProjector, ok := p.pageRepo.(PageProjector)
+	if !ok {
+		return p.GetSeoForPage(ctx, pageId)
+	}
+	ctx, cancelFunc := context.WithCancel(ctx)
+	seoChan := make(chan ResultSEO, 1)
+	go func() {
+		var seo *model.SEO
+		err := p.pool.run(ctx, func() (err error) {
+			seo, err = pageProjector.GetSeoFieldsForPage(ctx, pageId, fields)
+			return err
+		})
+		seoChan <- ResultSEO{
+			SEO: seo,
+			Err: err,
+		}
+	}()
+	return seoChan, cancelFunc
+}
+
+// GetProductFieldsForPage reads only selected fields when repository implements PageProjector, whole products otherwise.
+func (p PageRepositoryAsyncImpl) GetProductFieldsForPage(ctx context.Context, pageId int, fields []string) (<-chan ResultProducts, context.CancelFunc) {
+	pageProjector, ok := p.pageRepo.(PageProjector)
+	if !ok {
+		return p.GetProductsForPage(ctx, pageId)
+	}
+	ctx, cancelFunc := context.WithCancel(ctx)
+	productsChan := make(chan ResultProducts, 1)
+	go func() {
+		var products []model.Product
+		err := p.pool.run(ctx, func() (err error) {
+			products, err = pageProjector.GetProductFieldsForPage(ctx, pageId, fields)
+			return err
+		})
+		productsChan <- ResultProducts{
+			Products: products,
+			Err:      err,
+		}
+	}()
+	return productsChan, cancelFunc
+}
+
+func (p PageRepositoryAsyncImpl) GetSeosForPages(ctx context.Context, pageIds []int) (<-chan ResultSEOs, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	seosChan := make(chan ResultSEOs, 1)
+	go func() {
+		var seos []model.SEO
+		err := p.pool.run(ctx, func() (err error) {
+			seos, err = p.pageRepo.GetSeosForPages(ctx, pageIds)
+			return err
+		})
+		seosChan <- ResultSEOs{
+			SEOs: seos,
+			Err:  err,
+		}
+	}()
+	return seosChan, cancelFunc
+}
+
+func (p PageRepositoryAsyncImpl) GetProductsForPages(ctx context.Context, pageIds []int, limit int) (<-chan ResultProducts, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	productsChan := make(chan ResultProducts, 1)
+	go func() {
+		var products []model.Product
+		err := p.pool.run(ctx, func() (err error) {
+			products, err = p.pageRepo.GetProductsForPages(ctx, pageIds, limit)
+			return err
+		})
+		productsChan <- ResultProducts{
+			Products: products,
+			Err:      err,
+		}
+	}()
+	return productsChan, cancelFunc
+}
+
+func (p PageRepositoryAsyncImpl) CreatePage(ctx context.Context, page *model.Page) (<-chan error, context.CancelFunc) {
+	return p.write(ctx, func(ctx context.Context) error {
+		return p.pageRepo.CreatePage(ctx, page)
+	})
+}
+
+func (p PageRepositoryAsyncImpl) ReplacePage(ctx context.Context, page *model.Page) (<-chan error, context.CancelFunc) {
+	return p.write(ctx, func(ctx context.Context) error {
+		return p.pageRepo.ReplacePage(ctx, page)
+	})
+}
+
+func (p PageRepositoryAsyncImpl) DeletePage(ctx context.Context, pageId int) (<-chan error, context.CancelFunc) {
+	return p.write(ctx, func(ctx context.Context) error {
+		return p.pageRepo.DeletePage(ctx, pageId)
+	})
+}
+
+func (p PageRepositoryAsyncImpl) GetProductForPage(ctx context.Context, pageId int, productId int) (<-chan ResultProduct, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	productChan := make(chan ResultProduct, 1)
+	go func() {
+		var product *model.Product
+		err := p.pool.run(ctx, func() (err error) {
+			product, err = p.pageRepo.GetProductForPage(ctx, pageId, productId)
+			return err
+		})
+		productChan <- ResultProduct{
+			Product: product,
+			Err:     err,
+		}
+	}()
+	return productChan, cancelFunc
+}
+
+func (p PageRepositoryAsyncImpl) QueryProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (<-chan ResultProducts, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	productsChan := make(chan ResultProducts, 1)
+	go func() {
+		var products []model.Product
+		err := p.pool.run(ctx, func() (err error) {
+			products, err = p.pageRepo.QueryProductsForPage(ctx, pageId, query)
+			return err
+		})
+		productsChan <- ResultProducts{
+			Products: products,
+			Err:      err,
+		}
+	}()
+	return productsChan, cancelFunc
+}
+
+// streamBufferSize is number of streamed products read ahead of the consumer.
+const streamBufferSize = 64
+
+// StreamProductsForPage reads products with cursor when repository implements ProductStreamer,
+// all of them at once otherwise. Reading stops once the consumer cancels ctx. The stream takes a slot of the stream
+// pool until all products are sent, so slow consumers do not take slots of other queries.
+func (p PageRepositoryAsyncImpl) StreamProductsForPage(ctx context.Context, pageId int) (<-chan ResultProduct, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	productChan := make(chan ResultProduct, streamBufferSize)
+	send := func(result ResultProduct) error {
+		select {
+		case productChan <- result:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	go func() {
+		defer close(productChan)
+		err := p.streamPool.run(ctx, func() error {
+			if productStreamer, ok := p.pageRepo.(ProductStreamer); ok {
+				return productStreamer.StreamProductsForPage(ctx, pageId, func(product *model.Product) error {
+					return send(ResultProduct{Product: product})
+				})
+			}
+			return p.sendProducts(ctx, pageId, send)
+		})
+		if err != nil && ctx.Err() == nil {
+			_ = send(ResultProduct{Err: err})
+		}
+	}()
+	return productChan, cancelFunc
+}
+
+func (p PageRepositoryAsyncImpl) sendProducts(ctx context.Context, pageId int, send func(result ResultProduct) error) error {
+	products, err := p.pageRepo.GetProductsForPage(ctx, pageId)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		if err := send(ResultProduct{Product: &products[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p PageRepositoryAsyncImpl) CountProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (<-chan ResultCount, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	countChan := make(chan ResultCount, 1)
+	go func() {
+		var count int
+		err := p.pool.run(ctx, func() (err error) {
+			count, err = p.pageRepo.CountProductsForPage(ctx, pageId, query)
+			return err
+		})
+		countChan <- ResultCount{
+			Count: count,
+			Err:   err,
+		}
+	}()
+	return countChan, cancelFunc
+}
+
+func (p PageRepositoryAsyncImpl) CreateProduct(ctx context.Context, product *model.Product) (<-chan error, context.CancelFunc) {
+	return p.write(ctx, func(ctx context.Context) error {
+		return p.pageRepo.CreateProduct(ctx, product)
+	})
+}
+
+func (p PageRepositoryAsyncImpl) ReplaceProduct(ctx context.Context, product *model.Product) (<-chan error, context.CancelFunc) {
+	return p.write(ctx, func(ctx context.Context) error {
+		return p.pageRepo.ReplaceProduct(ctx, product)
+	})
+}
+
+func (p PageRepositoryAsyncImpl) DeleteProduct(ctx context.Context, pageId int, productId int) (<-chan error, context.CancelFunc) {
+	return p.write(ctx, func(ctx context.Context) error {
+		return p.pageRepo.DeleteProduct(ctx, pageId, productId)
+	})
+}
+
+func (p PageRepositoryAsyncImpl) write(ctx context.Context, writeFunc func(ctx context.Context) error) (<-chan error, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- p.pool.run(ctx, func() error {
+			return writeFunc(ctx)
+		})
+	}()
+	return errChan, cancelFunc
+}
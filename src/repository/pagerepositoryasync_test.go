@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/remikj/pages-ms/src/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 )
 
@@ -53,7 +54,7 @@ func TestPageRepositoryAsyncImpl_GetSeoForPage(t *testing.T) {
 				pageRepo: tt.pageRepo,
 			}
 
-			resultChan, cancelFunc := p.GetSeoForPage(tt.pageId)
+			resultChan, cancelFunc := p.GetSeoForPage(context.Background(), tt.pageId)
 			result := <-resultChan
 
 			assert.NotNil(t, cancelFunc)
@@ -98,7 +99,7 @@ func TestPageRepositoryAsyncImpl_GetProductsForPage(t *testing.T) {
 				pageRepo: tt.pageRepo,
 			}
 
-			resultChan, cancelFunc := p.GetProductsForPage(tt.pageId)
+			resultChan, cancelFunc := p.GetProductsForPage(context.Background(), tt.pageId)
 			result := <-resultChan
 
 			assert.NotNil(t, cancelFunc)
@@ -107,6 +108,231 @@ func TestPageRepositoryAsyncImpl_GetProductsForPage(t *testing.T) {
 	}
 }
 
+func TestPageRepositoryAsyncImpl_GetSeosAndProductsForPages(t *testing.T) {
+	p := PageRepositoryAsyncImpl{
+		pageRepo: pageRepositoryMock{
+			getSeosForPagesFunc: func(ctx context.Context, pageIds []int) ([]model.SEO, error) {
+				return []model.SEO{sampleSeo}, nil
+			},
+			getProductsForPagesFunc: func(ctx context.Context, pageIds []int) ([]model.Product, error) {
+				return nil, fmt.Errorf("error when getting products")
+			},
+		},
+	}
+
+	seosChan, seosCancelFunc := p.GetSeosForPages(context.Background(), []int{0, 1})
+	productsChan, productsCancelFunc := p.GetProductsForPages(context.Background(), []int{0, 1}, 0)
+
+	assert.NotNil(t, seosCancelFunc)
+	assert.NotNil(t, productsCancelFunc)
+	assert.Equal(t, ResultSEOs{SEOs: []model.SEO{sampleSeo}}, <-seosChan)
+	assert.Equal(t, ResultProducts{Err: fmt.Errorf("error when getting products")}, <-productsChan)
+}
+
+func TestPageRepositoryAsyncImpl_WritePage(t *testing.T) {
+	writeErr := fmt.Errorf("error when writing page")
+	page := &model.Page{SEO: sampleSeo}
+	p := PageRepositoryAsyncImpl{
+		pageRepo: pageRepositoryMock{
+			createPageFunc:  func(ctx context.Context, page *model.Page) error { return nil },
+			replacePageFunc: func(ctx context.Context, page *model.Page) error { return writeErr },
+			deletePageFunc:  func(ctx context.Context, pageId int) error { return nil },
+		},
+	}
+
+	createChan, createCancelFunc := p.CreatePage(context.Background(), page)
+	replaceChan, replaceCancelFunc := p.ReplacePage(context.Background(), page)
+	deleteChan, deleteCancelFunc := p.DeletePage(context.Background(), 0)
+
+	assert.NotNil(t, createCancelFunc)
+	assert.NotNil(t, replaceCancelFunc)
+	assert.NotNil(t, deleteCancelFunc)
+	assert.NoError(t, <-createChan)
+	assert.Equal(t, writeErr, <-replaceChan)
+	assert.NoError(t, <-deleteChan)
+}
+
+func TestPageRepositoryAsyncImpl_GetProductForPage(t *testing.T) {
+	product := &model.Product{Id: 1, Name: "name"}
+	p := PageRepositoryAsyncImpl{
+		pageRepo: pageRepositoryMock{
+			getProductForPageFunc: func(ctx context.Context, pageId int, productId int) (*model.Product, error) {
+				return product, nil
+			},
+		},
+	}
+
+	resultChan, cancelFunc := p.GetProductForPage(context.Background(), 0, 1)
+	result := <-resultChan
+
+	assert.NotNil(t, cancelFunc)
+	assert.Equal(t, ResultProduct{Product: product}, result)
+}
+
+func TestPageRepositoryAsyncImpl_QueryAndCountProductsForPage(t *testing.T) {
+	query := &model.ProductQuery{Limit: 1, Sort: model.SortByPrice}
+	products := []model.Product{{Id: 1, Name: "name"}}
+	p := PageRepositoryAsyncImpl{
+		pageRepo: pageRepositoryMock{
+			queryProductsFunc: func(ctx context.Context, pageId int, q *model.ProductQuery) ([]model.Product, error) {
+				assert.Same(t, query, q)
+				return products, nil
+			},
+			countProductsFunc: func(ctx context.Context, pageId int, q *model.ProductQuery) (int, error) {
+				return 0, fmt.Errorf("count failed")
+			},
+		},
+	}
+
+	productsChan, productsCancelFunc := p.QueryProductsForPage(context.Background(), 0, query)
+	defer productsCancelFunc()
+	countChan, countCancelFunc := p.CountProductsForPage(context.Background(), 0, query)
+	defer countCancelFunc()
+
+	assert.Equal(t, ResultProducts{Products: products}, <-productsChan)
+	assert.Equal(t, ResultCount{Err: fmt.Errorf("count failed")}, <-countChan)
+}
+
+func TestPageRepositoryAsyncImpl_StreamProductsForPage(t *testing.T) {
+	products := []model.Product{{Id: 1, Name: "name1"}, {Id: 2, Name: "name2"}}
+	tests := []struct {
+		name            string
+		pageRepo        PageRepository
+		expectedResults []ResultProduct
+	}{
+		{
+			name:     "should stream products, when repository streams them",
+			pageRepo: productStreamerMock{products: products},
+			expectedResults: []ResultProduct{
+				{Product: &products[0]},
+				{Product: &products[1]},
+			},
+		},
+		{
+			name:     "should send error as the last result, when stream fails",
+			pageRepo: productStreamerMock{products: products[:1], err: fmt.Errorf("stream failed")},
+			expectedResults: []ResultProduct{
+				{Product: &products[0]},
+				{Err: fmt.Errorf("stream failed")},
+			},
+		},
+		{
+			name: "should send products read at once, when repository does not stream them",
+			pageRepo: pageRepositoryMock{
+				getProductsForPageFunc: func(ctx context.Context, pageId int) ([]model.Product, error) {
+					return products, nil
+				},
+			},
+			expectedResults: []ResultProduct{
+				{Product: &products[0]},
+				{Product: &products[1]},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PageRepositoryAsyncImpl{pageRepo: tt.pageRepo}
+
+			productChan, cancelFunc := p.StreamProductsForPage(context.Background(), 0)
+			defer cancelFunc()
+
+			var results []ResultProduct
+			for result := range productChan {
+				results = append(results, result)
+			}
+			assert.Equal(t, tt.expectedResults, results)
+		})
+	}
+}
+
+func TestPageRepositoryAsyncImpl_StreamProductsForPage_shouldStopReading_whenCancelled(t *testing.T) {
+	streamed := make(chan error, 1)
+	p := PageRepositoryAsyncImpl{pageRepo: productStreamerMock{products: make([]model.Product, 2*streamBufferSize), streamed: streamed}}
+
+	productChan, cancelFunc := p.StreamProductsForPage(context.Background(), 0)
+	<-productChan
+	cancelFunc()
+
+	assert.ErrorIs(t, <-streamed, context.Canceled)
+	for range productChan {
+	}
+}
+
+func TestPageRepositoryAsyncImpl_shouldCancelOutstandingQuery_whenCallerContextCancelled(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	p := NewPageRepositoryAsync(&AsyncConfiguration{}, pageRepositoryMock{
+		getSeoForPageFunc: func(ctx context.Context, pageId int) (*model.SEO, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	resultChan, queryCancelFunc := p.GetSeoForPage(ctx, 0)
+	defer queryCancelFunc()
+	cancelFunc()
+
+	assert.Equal(t, ResultSEO{Err: context.Canceled}, <-resultChan)
+}
+
+func TestNewPageRepositoryAsync_shouldReturnPageAssembler_whenRepositoryAssemblesPages(t *testing.T) {
+	page := &model.Page{SEO: sampleSeo, Products: []model.Product{}}
+	fanOut := NewPageRepositoryAsync(&AsyncConfiguration{}, pageRepositoryMock{})
+	assembling := NewPageRepositoryAsync(&AsyncConfiguration{}, pageAssemblerMock{
+		getPageFunc: func(ctx context.Context, pageId int) (*model.Page, error) {
+			return page, nil
+		},
+	})
+
+	_, isFanOutAssembler := fanOut.(PageAssemblerAsync)
+	pageAssembler, isAssembler := assembling.(PageAssemblerAsync)
+	assert.False(t, isFanOutAssembler)
+	require.True(t, isAssembler)
+	resultChan, cancelFunc := pageAssembler.GetPage(context.Background(), 0, 0)
+	defer cancelFunc()
+	assert.Equal(t, ResultPage{Page: page}, <-resultChan)
+}
+
+func TestPageRepositoryAsyncImpl_GetFieldsForPage(t *testing.T) {
+	fullSeo, projectedSeo := &model.SEO{Title: "title", Robots: "robots"}, &model.SEO{Title: "title"}
+	fullProducts, projectedProducts := []model.Product{{Id: 1, Name: "name"}}, []model.Product{{Name: "name"}}
+	repo := pageRepositoryMock{
+		getSeoForPageFunc:      createGetSeoForPageFunc(fullSeo, nil),
+		getProductsForPageFunc: createGetProductsForPageFunc(fullProducts, nil),
+	}
+	tests := []struct {
+		name             string
+		pageRepo         PageRepository
+		expectedSeo      *model.SEO
+		expectedProducts []model.Product
+	}{
+		{
+			name:             "should read selected fields, when repository projects pages",
+			pageRepo:         pageProjectorMock{pageRepositoryMock: repo, seo: projectedSeo, products: projectedProducts},
+			expectedSeo:      projectedSeo,
+			expectedProducts: projectedProducts,
+		},
+		{
+			name:             "should read whole page, when repository does not project pages",
+			pageRepo:         repo,
+			expectedSeo:      fullSeo,
+			expectedProducts: fullProducts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPageRepositoryAsync(&AsyncConfiguration{}, tt.pageRepo)
+
+			seoChan, seoCancelFunc := p.GetSeoFieldsForPage(context.Background(), 0, []string{"Title"})
+			defer seoCancelFunc()
+			productsChan, productsCancelFunc := p.GetProductFieldsForPage(context.Background(), 0, []string{"Name"})
+			defer productsCancelFunc()
+
+			assert.Equal(t, ResultSEO{SEO: tt.expectedSeo}, <-seoChan)
+			assert.Equal(t, ResultProducts{Products: tt.expectedProducts}, <-productsChan)
+		})
+	}
+}
+
 func createGetSeoForPageFunc(seo *model.SEO, err error) func(ctx context.Context, pageId int) (*model.SEO, error) {
 	return func(ctx context.Context, pageId int) (*model.SEO, error) {
 		if pageId == 0 {
@@ -127,9 +353,75 @@ func createGetProductsForPageFunc(products []model.Product, err error) func(ctx
 	}
 }
 
+type pageAssemblerMock struct {
+	pageRepositoryMock
+	getPageFunc func(ctx context.Context, pageId int) (*model.Page, error)
+}
+
+func (p pageAssemblerMock) GetPage(ctx context.Context, pageId int, _ int) (*model.Page, error) {
+	return p.getPageFunc(ctx, pageId)
+}
+
+// productStreamerMock yields products and then returns err, the result of the stream is sent to streamed.
+type productStreamerMock struct {
+	pageRepositoryMock
+	products []model.Product
+	err      error
+	streamed chan<- error
+}
+
+func (p productStreamerMock) StreamProductsForPage(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+	err := p.stream(yield)
+	if p.streamed != nil {
+		p.streamed <- err
+	}
+	return err
+}
+
+func (p productStreamerMock) stream(yield func(product *model.Product) error) error {
+	for i := range p.products {
+		if err := yield(&p.products[i]); err != nil {
+			return err
+		}
+	}
+	return p.err
+}
+
+// pageProjectorMock returns seo and products when selected fields are Title and Name.
+type pageProjectorMock struct {
+	pageRepositoryMock
+	seo      *model.SEO
+	products []model.Product
+}
+
+func (p pageProjectorMock) GetSeoFieldsForPage(ctx context.Context, pageId int, fields []string) (*model.SEO, error) {
+	if len(fields) != 1 || fields[0] != "Title" {
+		return nil, fmt.Errorf("unexpected fields %v", fields)
+	}
+	return p.seo, nil
+}
+
+func (p pageProjectorMock) GetProductFieldsForPage(ctx context.Context, pageId int, fields []string) ([]model.Product, error) {
+	if len(fields) != 1 || fields[0] != "Name" {
+		return nil, fmt.Errorf("unexpected fields %v", fields)
+	}
+	return p.products, nil
+}
+
 type pageRepositoryMock struct {
-	getSeoForPageFunc      func(ctx context.Context, pageId int) (*model.SEO, error)
-	getProductsForPageFunc func(ctx context.Context, pageId int) ([]model.Product, error)
+	getSeoForPageFunc       func(ctx context.Context, pageId int) (*model.SEO, error)
+	getProductsForPageFunc  func(ctx context.Context, pageId int) ([]model.Product, error)
+	getSeosForPagesFunc     func(ctx context.Context, pageIds []int) ([]model.SEO, error)
+	getProductsForPagesFunc func(ctx context.Context, pageIds []int) ([]model.Product, error)
+	createPageFunc          func(ctx context.Context, page *model.Page) error
+	replacePageFunc         func(ctx context.Context, page *model.Page) error
+	deletePageFunc          func(ctx context.Context, pageId int) error
+	getProductForPageFunc   func(ctx context.Context, pageId int, productId int) (*model.Product, error)
+	queryProductsFunc       func(ctx context.Context, pageId int, query *model.ProductQuery) ([]model.Product, error)
+	countProductsFunc       func(ctx context.Context, pageId int, query *model.ProductQuery) (int, error)
+	createProductFunc       func(ctx context.Context, product *model.Product) error
+	replaceProductFunc      func(ctx context.Context, product *model.Product) error
+	deleteProductFunc       func(ctx context.Context, pageId int, productId int) error
 }
 
 func (p pageRepositoryMock) GetSeoForPage(ctx context.Context, pageId int) (*model.SEO, error) {
@@ -140,6 +432,54 @@ func (p pageRepositoryMock) GetProductsForPage(ctx context.Context, pageId int)
 	return p.getProductsForPageFunc(ctx, pageId)
 }
 
+func (p pageRepositoryMock) GetSeosForPages(ctx context.Context, pageIds []int) ([]model.SEO, error) {
+	return p.getSeosForPagesFunc(ctx, pageIds)
+}
+
+func (p pageRepositoryMock) GetProductsForPages(ctx context.Context, pageIds []int, _ int) ([]model.Product, error) {
+	return p.getProductsForPagesFunc(ctx, pageIds)
+}
+
+func (p pageRepositoryMock) CreatePage(ctx context.Context, page *model.Page) error {
+	return p.createPageFunc(ctx, page)
+}
+
+func (p pageRepositoryMock) ReplacePage(ctx context.Context, page *model.Page) error {
+	return p.replacePageFunc(ctx, page)
+}
+
+func (p pageRepositoryMock) DeletePage(ctx context.Context, pageId int) error {
+	return p.deletePageFunc(ctx, pageId)
+}
+
+func (p pageRepositoryMock) GetProductForPage(ctx context.Context, pageId int, productId int) (*model.Product, error) {
+	return p.getProductForPageFunc(ctx, pageId, productId)
+}
+
+func (p pageRepositoryMock) QueryProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) ([]model.Product, error) {
+	return p.queryProductsFunc(ctx, pageId, query)
+}
+
+func (p pageRepositoryMock) CountProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (int, error) {
+	return p.countProductsFunc(ctx, pageId, query)
+}
+
+func (p pageRepositoryMock) CreateProduct(ctx context.Context, product *model.Product) error {
+	return p.createProductFunc(ctx, product)
+}
+
+func (p pageRepositoryMock) ReplaceProduct(ctx context.Context, product *model.Product) error {
+	return p.replaceProductFunc(ctx, product)
+}
+
+func (p pageRepositoryMock) DeleteProduct(ctx context.Context, pageId int, productId int) error {
+	return p.deleteProductFunc(ctx, pageId, productId)
+}
+
+func (p pageRepositoryMock) Ping(_ context.Context) error {
+	return nil
+}
+
 func (p pageRepositoryMock) CloseRepository() error {
 	return nil
 }
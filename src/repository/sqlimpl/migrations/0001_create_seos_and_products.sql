@@ -0,0 +1,15 @@
+CREATE TABLE seos (
+    page_id     INTEGER PRIMARY KEY,
+    title       TEXT    NOT NULL,
+    description TEXT    NOT NULL,
+    robots      TEXT    NOT NULL
+);
+
+CREATE TABLE products (
+    page_id     INTEGER NOT NULL REFERENCES seos (page_id) ON DELETE CASCADE,
+    id          INTEGER NOT NULL,
+    name        TEXT    NOT NULL,
+    description TEXT    NOT NULL,
+    price       REAL    NOT NULL,
+    PRIMARY KEY (page_id, id)
+);
@@ -0,0 +1,2 @@
+-- Milliseconds since epoch of the last change of the page or its products, NULL for pages written before.
+ALTER TABLE seos ADD COLUMN updated_at INTEGER;
@@ -0,0 +1,3 @@
+-- Products of a page are paginated by keyset on sort key and id, the primary key covers sorting by id.
+CREATE INDEX products_page_id_price_id ON products (page_id, price, id);
+CREATE INDEX products_page_id_name_id ON products (page_id, name, id);
@@ -0,0 +1,459 @@
+package sqlimpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/model"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// driverName is the only supported driver, queries use its placeholders and functions.
+const driverName = "sqlite"
+
+type Configuration struct {
+	DSN          string `envconfig:"SQL_DSN" default:"file:pages.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"`
+	MaxOpenConns int    `envconfig:"SQL_MAX_OPEN_CONNS" default:"1"`
+}
+
+// PageRepositorySQL stores seos and products in tables of the same names.
+// Writes of a page with its products are done in a single transaction.
+type PageRepositorySQL struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+// InitPageRepositorySQLFromEnv opens database and applies missing migrations.
+func InitPageRepositorySQLFromEnv(logger *slog.Logger) (*PageRepositorySQL, error) {
+	config := &Configuration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driverName, config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	if err := Migrate(context.TODO(), db, logger); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return NewPageRepositorySQL(db, logger), nil
+}
+
+// NewPageRepositorySQL creates repository over db with already migrated schema.
+func NewPageRepositorySQL(db *sql.DB, logger *slog.Logger) *PageRepositorySQL {
+	return &PageRepositorySQL{db: db, logger: logger}
+}
+
+func (p *PageRepositorySQL) GetSeoForPage(ctx context.Context, pageId int) (*model.SEO, error) {
+	p.logger.Debug("getting seo", "page_id", pageId)
+	return getSeo(ctx, p.db, pageId)
+}
+
+func (p *PageRepositorySQL) GetProductsForPage(ctx context.Context, pageId int) ([]model.Product, error) {
+	p.logger.Debug("getting products", "page_id", pageId)
+	return queryProducts(ctx, p.db,
+		`SELECT id, page_id, name, description, price FROM products WHERE page_id = ? ORDER BY id`, pageId)
+}
+
+// streamBatchSize is number of products read by each query of StreamProductsForPage.
+const streamBatchSize = 100
+
+// StreamProductsForPage reads products in batches of streamBatchSize ordered by id, each batch continuing after
+// the last id of the previous one. The connection is released before products of the batch are yielded,
+// so a slow consumer does not hold it and block other queries. Batches are read by separate queries,
+// so products written during the stream may be missed or included.
+func (p *PageRepositorySQL) StreamProductsForPage(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+	p.logger.Debug("streaming products", "page_id", pageId)
+	lastId := math.MinInt64
+	for {
+		products, err := queryProducts(ctx, p.db,
+			`SELECT id, page_id, name, description, price FROM products WHERE page_id = ? AND id > ? ORDER BY id LIMIT ?`,
+			pageId, lastId, streamBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range products {
+			if err := yield(&products[i]); err != nil {
+				return err
+			}
+		}
+		if len(products) < streamBatchSize {
+			return nil
+		}
+		lastId = products[len(products)-1].Id
+	}
+}
+
+func (p *PageRepositorySQL) GetSeosForPages(ctx context.Context, pageIds []int) ([]model.SEO, error) {
+	p.logger.Debug("getting seos", "page_ids", pageIds)
+	if len(pageIds) == 0 {
+		return nil, nil
+	}
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT `+seoColumns+` FROM seos WHERE page_id IN (`+placeholders(len(pageIds))+`)`,
+		intArgs(pageIds)...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	defer rows.Close()
+	var seos []model.SEO
+	for rows.Next() {
+		seo, err := scanSeo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: error happened when decoding results: %w", model.ErrDataIntegrity, err)
+		}
+		seos = append(seos, *seo)
+	}
+	if err := rowsErr(rows); err != nil {
+		return nil, err
+	}
+	return seos, nil
+}
+
+// GetProductsForPages numbers products of each page by id, so only the first limit of them are read.
+func (p *PageRepositorySQL) GetProductsForPages(ctx context.Context, pageIds []int, limit int) ([]model.Product, error) {
+	p.logger.Debug("getting products", "page_ids", pageIds, "limit", limit)
+	if len(pageIds) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	return queryProducts(ctx, p.db,
+		`SELECT id, page_id, name, description, price FROM (
+			SELECT id, page_id, name, description, price, ROW_NUMBER() OVER (PARTITION BY page_id ORDER BY id) AS position
+			FROM products WHERE page_id IN (`+placeholders(len(pageIds))+`)
+		) WHERE position <= ? ORDER BY page_id, id`,
+		append(intArgs(pageIds), limit)...)
+}
+
+func (p *PageRepositorySQL) CreatePage(ctx context.Context, page *model.Page) error {
+	p.logger.Debug("creating page", "page_id", page.SEO.PageId)
+	return p.inTransaction(ctx, func(tx *sql.Tx) error {
+		existingSeo, err := getSeo(ctx, tx, page.SEO.PageId)
+		if err != nil {
+			return err
+		}
+		if existingSeo != nil {
+			return model.ErrPageAlreadyExists
+		}
+		page.SEO.Touch(time.Now())
+		if _, err := tx.ExecContext(ctx, `INSERT INTO seos (page_id, title, description, robots, updated_at) VALUES (?, ?, ?, ?, ?)`,
+			page.SEO.PageId, page.SEO.Title, page.SEO.Description, page.SEO.Robots, page.SEO.UpdatedAt.UnixMilli()); err != nil {
+			return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+		}
+		return insertProducts(ctx, tx, page.Products)
+	})
+}
+
+func (p *PageRepositorySQL) ReplacePage(ctx context.Context, page *model.Page) error {
+	p.logger.Debug("replacing page", "page_id", page.SEO.PageId)
+	return p.inTransaction(ctx, func(tx *sql.Tx) error {
+		page.SEO.Touch(time.Now())
+		result, err := tx.ExecContext(ctx, `UPDATE seos SET title = ?, description = ?, robots = ?, updated_at = ? WHERE page_id = ?`,
+			page.SEO.Title, page.SEO.Description, page.SEO.Robots, page.SEO.UpdatedAt.UnixMilli(), page.SEO.PageId)
+		if err := expectAffectedRow(result, err, model.ErrPageNotFound); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM products WHERE page_id = ?`, page.SEO.PageId); err != nil {
+			return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+		}
+		return insertProducts(ctx, tx, page.Products)
+	})
+}
+
+// DeletePage deletes products before seo, so it does not rely on foreign keys being enforced by the connection.
+func (p *PageRepositorySQL) DeletePage(ctx context.Context, pageId int) error {
+	p.logger.Debug("deleting page", "page_id", pageId)
+	return p.inTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM products WHERE page_id = ?`, pageId); err != nil {
+			return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM seos WHERE page_id = ?`, pageId)
+		return expectAffectedRow(result, err, model.ErrPageNotFound)
+	})
+}
+
+func (p *PageRepositorySQL) GetProductForPage(ctx context.Context, pageId int, productId int) (*model.Product, error) {
+	p.logger.Debug("getting product", "page_id", pageId, "product_id", productId)
+	return getProduct(ctx, p.db, pageId, productId)
+}
+
+// QueryProductsForPage reads only products after the cursor, using keyset condition on sort key and id.
+func (p *PageRepositorySQL) QueryProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) ([]model.Product, error) {
+	p.logger.Debug("querying products", "page_id", pageId, "sort", query.Sort, "limit", query.Limit)
+	where, args := productFilter(pageId, query)
+	if query.After != nil {
+		condition, afterArgs := productsAfter(query.After)
+		where += " AND " + condition
+		args = append(args, afterArgs...)
+	}
+	return queryProducts(ctx, p.db, `SELECT id, page_id, name, description, price FROM products WHERE `+where+
+		` ORDER BY `+productOrder(query.Sort)+` LIMIT ?`, append(args, query.Limit)...)
+}
+
+func (p *PageRepositorySQL) CountProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (int, error) {
+	p.logger.Debug("counting products", "page_id", pageId)
+	where, args := productFilter(pageId, query)
+	count := 0
+	if err := p.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM products WHERE `+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return count, nil
+}
+
+func (p *PageRepositorySQL) CreateProduct(ctx context.Context, product *model.Product) error {
+	p.logger.Debug("creating product", "page_id", product.PageId, "product_id", product.Id)
+	return p.inTransaction(ctx, func(tx *sql.Tx) error {
+		seo, err := getSeo(ctx, tx, product.PageId)
+		if err != nil {
+			return err
+		}
+		if seo == nil {
+			return model.ErrPageNotFound
+		}
+		existingProduct, err := getProduct(ctx, tx, product.PageId, product.Id)
+		if err != nil {
+			return err
+		}
+		if existingProduct != nil {
+			return model.ErrProductAlreadyExists
+		}
+		if err := insertProducts(ctx, tx, []model.Product{*product}); err != nil {
+			return err
+		}
+		return touchPage(ctx, tx, product.PageId)
+	})
+}
+
+func (p *PageRepositorySQL) ReplaceProduct(ctx context.Context, product *model.Product) error {
+	p.logger.Debug("replacing product", "page_id", product.PageId, "product_id", product.Id)
+	return p.inTransaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE products SET name = ?, description = ?, price = ? WHERE page_id = ? AND id = ?`,
+			product.Name, product.Description, product.Price, product.PageId, product.Id)
+		if err := expectAffectedRow(result, err, model.ErrProductNotFound); err != nil {
+			return err
+		}
+		return touchPage(ctx, tx, product.PageId)
+	})
+}
+
+func (p *PageRepositorySQL) DeleteProduct(ctx context.Context, pageId int, productId int) error {
+	p.logger.Debug("deleting product", "page_id", pageId, "product_id", productId)
+	return p.inTransaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM products WHERE page_id = ? AND id = ?`, pageId, productId)
+		if err := expectAffectedRow(result, err, model.ErrProductNotFound); err != nil {
+			return err
+		}
+		return touchPage(ctx, tx, pageId)
+	})
+}
+
+func (p *PageRepositorySQL) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+func (p *PageRepositorySQL) CloseRepository() error {
+	return p.db.Close()
+}
+
+func (p *PageRepositorySQL) inTransaction(ctx context.Context, txFunc func(tx *sql.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	defer tx.Rollback()
+	if err := txFunc(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return nil
+}
+
+// queryer is implemented by both sql.DB and sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+const seoColumns = `page_id, title, description, robots, updated_at`
+
+// scanSeo reads row of seoColumns.
+func scanSeo(row interface {
+	Scan(dest ...interface{}) error
+}) (*model.SEO, error) {
+	seo := &model.SEO{}
+	var updatedAt sql.NullInt64
+	if err := row.Scan(&seo.PageId, &seo.Title, &seo.Description, &seo.Robots, &updatedAt); err != nil {
+		return nil, err
+	}
+	if updatedAt.Valid {
+		seo.UpdatedAt = time.UnixMilli(updatedAt.Int64).UTC()
+	}
+	return seo, nil
+}
+
+func getSeo(ctx context.Context, q queryer, pageId int) (*model.SEO, error) {
+	seo, err := scanSeo(q.QueryRowContext(ctx, `SELECT `+seoColumns+` FROM seos WHERE page_id = ?`, pageId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return seo, nil
+}
+
+func getProduct(ctx context.Context, q queryer, pageId int, productId int) (*model.Product, error) {
+	product := &model.Product{}
+	err := q.QueryRowContext(ctx, `SELECT id, page_id, name, description, price FROM products WHERE page_id = ? AND id = ?`, pageId, productId).
+		Scan(&product.Id, &product.PageId, &product.Name, &product.Description, &product.Price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return product, nil
+}
+
+func queryProducts(ctx context.Context, q queryer, query string, args ...interface{}) ([]model.Product, error) {
+	var products []model.Product
+	err := scanProducts(ctx, q, func(product *model.Product) error {
+		products = append(products, *product)
+		return nil
+	}, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// scanProducts calls yield for each product read by the query, stopping with the first error returned by yield.
+func scanProducts(ctx context.Context, q queryer, yield func(product *model.Product) error, query string, args ...interface{}) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		product := &model.Product{}
+		if err := rows.Scan(&product.Id, &product.PageId, &product.Name, &product.Description, &product.Price); err != nil {
+			return fmt.Errorf("%w: error happened when decoding results: %w", model.ErrDataIntegrity, err)
+		}
+		if err := yield(product); err != nil {
+			return err
+		}
+	}
+	return rowsErr(rows)
+}
+
+// rowsErr returns error of iterating rows as ErrBackendUnavailable, like errors of the query itself.
+func rowsErr(rows *sql.Rows) error {
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return nil
+}
+
+// productFilter returns condition selecting products of the page matching filters of the query.
+func productFilter(pageId int, query *model.ProductQuery) (string, []interface{}) {
+	conditions, args := []string{"page_id = ?"}, []interface{}{pageId}
+	if query.NameContains != "" {
+		conditions = append(conditions, "instr(lower(name), lower(?)) > 0")
+		args = append(args, query.NameContains)
+	}
+	if query.MinPrice != nil {
+		conditions = append(conditions, "price >= ?")
+		args = append(args, *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		conditions = append(conditions, "price <= ?")
+		args = append(args, *query.MaxPrice)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// productsAfter returns condition selecting products after the cursor in its sort order.
+func productsAfter(cursor *model.ProductCursor) (string, []interface{}) {
+	switch cursor.Sort {
+	case model.SortByPrice:
+		return "(price > ? OR (price = ? AND id > ?))", []interface{}{cursor.Price, cursor.Price, cursor.Id}
+	case model.SortByPriceDesc:
+		return "(price < ? OR (price = ? AND id > ?))", []interface{}{cursor.Price, cursor.Price, cursor.Id}
+	case model.SortByName:
+		return "(name > ? OR (name = ? AND id > ?))", []interface{}{cursor.Name, cursor.Name, cursor.Id}
+	default:
+		return "id > ?", []interface{}{cursor.Id}
+	}
+}
+
+func productOrder(sort model.ProductSort) string {
+	switch sort {
+	case model.SortByPrice:
+		return "price, id"
+	case model.SortByPriceDesc:
+		return "price DESC, id"
+	case model.SortByName:
+		return "name, id"
+	default:
+		return "id"
+	}
+}
+
+func insertProducts(ctx context.Context, q queryer, products []model.Product) error {
+	for _, product := range products {
+		if _, err := q.ExecContext(ctx, `INSERT INTO products (page_id, id, name, description, price) VALUES (?, ?, ?, ?, ?)`,
+			product.PageId, product.Id, product.Name, product.Description, product.Price); err != nil {
+			return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+		}
+	}
+	return nil
+}
+
+// touchPage marks page as changed now, after its products were written.
+func touchPage(ctx context.Context, q queryer, pageId int) error {
+	if _, err := q.ExecContext(ctx, `UPDATE seos SET updated_at = ? WHERE page_id = ?`, time.Now().UnixMilli(), pageId); err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	return nil
+}
+
+// expectAffectedRow returns notFoundErr when statement did not affect any row.
+func expectAffectedRow(result sql.Result, err error, notFoundErr error) error {
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: error happened when using db: %w", model.ErrBackendUnavailable, err)
+	}
+	if affected == 0 {
+		return notFoundErr
+	}
+	return nil
+}
+
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+func intArgs(values []int) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return args
+}
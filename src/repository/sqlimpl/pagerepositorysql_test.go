@@ -0,0 +1,330 @@
+package sqlimpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var (
+	sampleSeo      = model.SEO{PageId: 1, Title: "title", Description: "description", Robots: "robots"}
+	sampleProduct1 = model.Product{Id: 1, PageId: 1, Name: "name1", Description: "description1", Price: 2.5}
+	sampleProduct2 = model.Product{Id: 2, PageId: 1, Name: "name2", Description: "description2", Price: 20.99}
+	samplePage     = model.Page{SEO: sampleSeo, Products: []model.Product{sampleProduct2, sampleProduct1}}
+)
+
+func newTestRepository(t *testing.T) *PageRepositorySQL {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "pages.db")+"?_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, Migrate(context.Background(), db, logging.Discard()))
+	return NewPageRepositorySQL(db, logging.Discard())
+}
+
+func TestPageRepositorySQL_CreateAndGetPage(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	page := samplePage
+
+	require.NoError(t, p.CreatePage(ctx, &page))
+	seo, seoErr := p.GetSeoForPage(ctx, 1)
+	products, productsErr := p.GetProductsForPage(ctx, 1)
+	missingSeo, missingSeoErr := p.GetSeoForPage(ctx, 2)
+
+	assert.NoError(t, seoErr)
+	assert.NoError(t, productsErr)
+	assert.NoError(t, missingSeoErr)
+	assert.Equal(t, &page.SEO, seo)
+	assert.False(t, seo.UpdatedAt.IsZero())
+	assert.Equal(t, []model.Product{sampleProduct1, sampleProduct2}, products)
+	assert.Nil(t, missingSeo)
+	assert.Equal(t, model.ErrPageAlreadyExists, p.CreatePage(ctx, &samplePage))
+}
+
+func TestPageRepositorySQL_CreatePage_shouldRollback_whenProductsCanNotBeInserted(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	page := &model.Page{SEO: sampleSeo, Products: []model.Product{sampleProduct1, sampleProduct1}}
+
+	err := p.CreatePage(ctx, page)
+
+	assert.Error(t, err)
+	seo, _ := p.GetSeoForPage(ctx, 1)
+	assert.Nil(t, seo)
+}
+
+func TestPageRepositorySQL_ReplaceAndDeletePage(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	replacement := &model.Page{SEO: model.SEO{PageId: 1, Title: "new title"}, Products: []model.Product{sampleProduct2}}
+
+	assert.Equal(t, model.ErrPageNotFound, p.ReplacePage(ctx, replacement))
+	require.NoError(t, p.CreatePage(ctx, &samplePage))
+	require.NoError(t, p.ReplacePage(ctx, replacement))
+	seo, _ := p.GetSeoForPage(ctx, 1)
+	products, _ := p.GetProductsForPage(ctx, 1)
+	assert.Equal(t, &replacement.SEO, seo)
+	assert.Equal(t, []model.Product{sampleProduct2}, products)
+
+	require.NoError(t, p.DeletePage(ctx, 1))
+	seo, _ = p.GetSeoForPage(ctx, 1)
+	products, _ = p.GetProductsForPage(ctx, 1)
+	assert.Nil(t, seo)
+	assert.Empty(t, products)
+	assert.Equal(t, model.ErrPageNotFound, p.DeletePage(ctx, 1))
+}
+
+func TestPageRepositorySQL_Products(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	otherProduct := model.Product{Id: 3, PageId: 1, Name: "other"}
+
+	assert.Equal(t, model.ErrPageNotFound, p.CreateProduct(ctx, &otherProduct))
+	require.NoError(t, p.CreatePage(ctx, &samplePage))
+	require.NoError(t, p.CreateProduct(ctx, &otherProduct))
+	assert.Equal(t, model.ErrProductAlreadyExists, p.CreateProduct(ctx, &otherProduct))
+
+	replacedProduct := model.Product{Id: 2, PageId: 1, Name: "replaced", Price: 1}
+	require.NoError(t, p.ReplaceProduct(ctx, &replacedProduct))
+	product, err := p.GetProductForPage(ctx, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &replacedProduct, product)
+
+	require.NoError(t, p.DeleteProduct(ctx, 1, 2))
+	missingProduct, err := p.GetProductForPage(ctx, 1, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, missingProduct)
+	assert.Equal(t, model.ErrProductNotFound, p.DeleteProduct(ctx, 1, 2))
+	assert.Equal(t, model.ErrProductNotFound, p.ReplaceProduct(ctx, &replacedProduct))
+}
+
+func TestPageRepositorySQL_QueryProductsForPage(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	products := []model.Product{
+		{Id: 1, PageId: 1, Name: "Blue shirt", Price: 20},
+		{Id: 2, PageId: 1, Name: "Red shirt", Price: 10},
+		{Id: 3, PageId: 1, Name: "Shoes", Price: 20},
+		{Id: 4, PageId: 1, Name: "Green SHIRT", Price: 15},
+		{Id: 5, PageId: 1, Name: "Hat", Price: 5},
+	}
+	require.NoError(t, p.CreatePage(ctx, &model.Page{SEO: sampleSeo, Products: products}))
+	minPrice := 10.0
+	query := &model.ProductQuery{Limit: 2, Sort: model.SortByPriceDesc, MinPrice: &minPrice}
+
+	firstPage, firstErr := p.QueryProductsForPage(ctx, 1, query)
+	query.After = model.CursorAfter(query.Sort, &firstPage[len(firstPage)-1])
+	secondPage, secondErr := p.QueryProductsForPage(ctx, 1, query)
+	count, countErr := p.CountProductsForPage(ctx, 1, query)
+
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.NoError(t, countErr)
+	assert.Equal(t, []model.Product{products[0], products[2]}, firstPage)
+	assert.Equal(t, []model.Product{products[3], products[1]}, secondPage)
+	assert.Equal(t, 4, count)
+
+	nameQuery := &model.ProductQuery{Limit: 10, Sort: model.SortByName, NameContains: "shirt"}
+	byName, err := p.QueryProductsForPage(ctx, 1, nameQuery)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Product{products[0], products[3], products[1]}, byName)
+	otherPage, err := p.QueryProductsForPage(ctx, 2, nameQuery)
+	assert.NoError(t, err)
+	assert.Empty(t, otherPage)
+}
+
+func TestPageRepositorySQL_StreamProductsForPage(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	require.NoError(t, p.CreatePage(ctx, &samplePage))
+
+	var products []model.Product
+	err := p.StreamProductsForPage(ctx, 1, func(product *model.Product) error {
+		products = append(products, *product)
+		return nil
+	})
+	stopErr := p.StreamProductsForPage(ctx, 1, func(product *model.Product) error {
+		return fmt.Errorf("client disconnected")
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Product{sampleProduct1, sampleProduct2}, products)
+	assert.EqualError(t, stopErr, "client disconnected")
+}
+
+func TestPageRepositorySQL_StreamProductsForPage_shouldNotHoldConnection_whenConsumerIsSlow(t *testing.T) {
+	p := newTestRepository(t)
+	page := model.Page{SEO: sampleSeo}
+	for id := 1; id <= 2*streamBatchSize+50; id++ {
+		page.Products = append(page.Products, model.Product{Id: id, PageId: 1, Name: "name"})
+	}
+	require.NoError(t, p.CreatePage(context.Background(), &page))
+
+	var streamedIds []int
+	err := p.StreamProductsForPage(context.Background(), 1, func(product *model.Product) error {
+		streamedIds = append(streamedIds, product.Id)
+		if product.Id%streamBatchSize != 1 {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		seo, err := p.GetSeoForPage(ctx, 1)
+		if err != nil {
+			return err
+		}
+		_, err = p.GetProductForPage(ctx, seo.PageId, 1)
+		return err
+	})
+
+	assert.NoError(t, err, "should read other queries on the only connection while streaming")
+	require.Len(t, streamedIds, len(page.Products))
+	for i, id := range streamedIds {
+		assert.Equal(t, i+1, id)
+	}
+}
+
+func TestScanProducts_shouldReturnErrBackendUnavailable_whenIterationFails(t *testing.T) {
+	p := newTestRepository(t)
+	page := model.Page{SEO: sampleSeo}
+	for id := 1; id <= 100; id++ {
+		page.Products = append(page.Products, model.Product{Id: id, PageId: 1, Name: "name"})
+	}
+	require.NoError(t, p.CreatePage(context.Background(), &page))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := scanProducts(ctx, p.db, func(product *model.Product) error {
+		cancel()
+		// rows are closed with error of ctx asynchronously
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, `SELECT id, page_id, name, description, price FROM products WHERE page_id = ? ORDER BY id`, 1)
+
+	assert.ErrorIs(t, err, model.ErrBackendUnavailable)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPageRepositorySQL_shouldTouchPage_whenProductsChange(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	_, err := p.db.Exec(`INSERT INTO seos (page_id, title, description, robots) VALUES (1, 'title', '', '')`)
+	require.NoError(t, err)
+	product := model.Product{Id: 3, PageId: 1, Name: "name"}
+
+	beforeCreate, _ := p.GetSeoForPage(ctx, 1)
+	require.NoError(t, p.CreateProduct(ctx, &product))
+	afterCreate, _ := p.GetSeoForPage(ctx, 1)
+	require.NoError(t, p.DeleteProduct(ctx, 1, 3))
+	afterDelete, _ := p.GetSeoForPage(ctx, 1)
+
+	assert.True(t, beforeCreate.UpdatedAt.IsZero())
+	assert.False(t, afterCreate.UpdatedAt.IsZero())
+	assert.False(t, afterDelete.UpdatedAt.Before(afterCreate.UpdatedAt))
+}
+
+func TestPageRepositorySQL_GetSeosAndProductsForPages(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	page := samplePage
+	otherPage := model.Page{SEO: model.SEO{PageId: 3, Title: "other"}, Products: []model.Product{}}
+	require.NoError(t, p.CreatePage(ctx, &page))
+	require.NoError(t, p.CreatePage(ctx, &otherPage))
+
+	seos, seosErr := p.GetSeosForPages(ctx, []int{3, 1, 2})
+	products, productsErr := p.GetProductsForPages(ctx, []int{3, 1, 2}, 0)
+
+	assert.NoError(t, seosErr)
+	assert.NoError(t, productsErr)
+	assert.ElementsMatch(t, []model.SEO{page.SEO, otherPage.SEO}, seos)
+	assert.Equal(t, []model.Product{sampleProduct1, sampleProduct2}, products)
+}
+
+func TestPageRepositorySQL_GetProductsForPages_shouldLimitProductsOfEachPage(t *testing.T) {
+	p := newTestRepository(t)
+	ctx := context.Background()
+	page := samplePage
+	otherProduct := model.Product{Id: 1, PageId: 3, Name: "other"}
+	otherPage := model.Page{SEO: model.SEO{PageId: 3, Title: "other"}, Products: []model.Product{otherProduct}}
+	require.NoError(t, p.CreatePage(ctx, &page))
+	require.NoError(t, p.CreatePage(ctx, &otherPage))
+
+	products, err := p.GetProductsForPages(ctx, []int{1, 3}, 1)
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []model.Product{sampleProduct1, otherProduct}, products)
+}
+
+func TestPageRepositorySQL_shouldEnforceProductsForeignKey(t *testing.T) {
+	p := newTestRepository(t)
+
+	_, err := p.db.Exec(`INSERT INTO products (page_id, id, name, description, price) VALUES (100, 1, 'name', '', 1)`)
+
+	assert.Error(t, err)
+}
+
+func TestMigrate_shouldApplyMigrationsOnce(t *testing.T) {
+	p := newTestRepository(t)
+
+	require.NoError(t, Migrate(context.Background(), p.db, logging.Discard()))
+
+	var versions int
+	require.NoError(t, p.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&versions))
+	assert.Equal(t, 3, versions)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            fstest.MapFS
+		expectedVersions []int
+		expectErr        bool
+	}{
+		{
+			name: "should sort migrations by version",
+			files: fstest.MapFS{
+				"migrations/10_third.sql":   {Data: []byte("SELECT 3")},
+				"migrations/2_second.sql":   {Data: []byte("SELECT 2")},
+				"migrations/0001_first.sql": {Data: []byte("SELECT 1")},
+			},
+			expectedVersions: []int{1, 2, 10},
+		},
+		{
+			name:      "should return err, when migration has no version",
+			files:     fstest.MapFS{"migrations/first.sql": {Data: []byte("SELECT 1")}},
+			expectErr: true,
+		},
+		{
+			name: "should return err, when versions are duplicated",
+			files: fstest.MapFS{
+				"migrations/1_first.sql":  {Data: []byte("SELECT 1")},
+				"migrations/01_other.sql": {Data: []byte("SELECT 1")},
+			},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.files)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			versions := make([]int, 0, len(migrations))
+			for _, m := range migrations {
+				versions = append(versions, m.version)
+			}
+			assert.Equal(t, tt.expectedVersions, versions)
+		})
+	}
+}
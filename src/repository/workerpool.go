@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"github.com/remikj/pages-ms/src/metrics"
+	"github.com/remikj/pages-ms/src/model"
+	"sync/atomic"
+)
+
+var rejectedQueriesTotal = metrics.NewCounterVec(
+	"repository_rejected_queries_total",
+	"Total number of repository queries rejected because running and queued queries reached their limits, by pool.",
+	"pool",
+)
+
+// Pools of repository work, used as pool label of their metrics.
+const (
+	poolQuery  = "query"
+	poolStream = "stream"
+)
+
+// workerPool limits number of concurrently running queries to MaxInFlight. Queries over the limit wait
+// in a queue of QueueSize, and are rejected with model.OverloadedError once it is full.
+// Nil workerPool runs all queries right away.
+type workerPool struct {
+	name   string
+	config *AsyncConfiguration
+	slots  chan struct{}
+	queued atomic.Int64
+}
+
+func newWorkerPool(name string, config *AsyncConfiguration) *workerPool {
+	if config.MaxInFlight <= 0 {
+		return nil
+	}
+	return &workerPool{
+		name:   name,
+		config: config,
+		slots:  make(chan struct{}, config.MaxInFlight),
+	}
+}
+
+// run runs query once there is a free slot. It returns error of the query, model.OverloadedError when
+// the queue is full, or error of ctx when it is done while the query waits.
+func (p *workerPool) run(ctx context.Context, query func() error) error {
+	if p == nil {
+		return query()
+	}
+	if err := p.acquire(ctx); err != nil {
+		return err
+	}
+	defer func() { <-p.slots }()
+	return query()
+}
+
+func (p *workerPool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if p.queued.Add(1) > int64(p.config.QueueSize) {
+		p.queued.Add(-1)
+		rejectedQueriesTotal.Inc(p.name)
+		return &model.OverloadedError{RetryAfter: p.config.RetryAfter}
+	}
+	defer p.queued.Add(-1)
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *workerPool) inFlight() int {
+	return len(p.slots)
+}
+
+func (p *workerPool) queueDepth() int {
+	return int(p.queued.Load())
+}
+
+// registerMetrics registers gauges of running and waiting work of the pool, kind is queries or streams.
+func (p *workerPool) registerMetrics(kind string) {
+	metrics.NewGaugeFunc("repository_"+kind+"_in_flight", "Number of running repository "+kind+".", func() float64 {
+		return float64(p.inFlight())
+	})
+	if p.config.QueueSize > 0 {
+		metrics.NewGaugeFunc("repository_"+kind+"_queued", "Number of repository "+kind+" waiting for a free slot.", func() float64 {
+			return float64(p.queueDepth())
+		})
+	}
+}
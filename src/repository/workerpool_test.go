@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_run_shouldQueueAndRejectQueriesOverLimit(t *testing.T) {
+	pool := newWorkerPool(poolQuery, &AsyncConfiguration{MaxInFlight: 1, QueueSize: 1, RetryAfter: 2 * time.Second})
+	rejectedBefore, rejectedStreamsBefore := rejectedQueriesTotal.Value(poolQuery), rejectedQueriesTotal.Value(poolStream)
+	release := make(chan struct{})
+	running := make(chan struct{})
+	results := make(chan error, 2)
+	go func() {
+		results <- pool.run(context.Background(), func() error {
+			close(running)
+			<-release
+			return nil
+		})
+	}()
+	<-running
+	go func() {
+		results <- pool.run(context.Background(), func() error { return errors.New("queued query") })
+	}()
+	assert.Eventually(t, func() bool { return pool.queueDepth() == 1 }, time.Second, time.Millisecond)
+
+	err := pool.run(context.Background(), func() error { return nil })
+
+	assert.Equal(t, &model.OverloadedError{RetryAfter: 2 * time.Second}, err)
+	assert.ErrorIs(t, err, model.ErrOverloaded)
+	assert.Equal(t, rejectedBefore+1, rejectedQueriesTotal.Value(poolQuery))
+	assert.Equal(t, rejectedStreamsBefore, rejectedQueriesTotal.Value(poolStream))
+	assert.Equal(t, 1, pool.inFlight())
+	close(release)
+	assert.NoError(t, <-results)
+	assert.EqualError(t, <-results, "queued query")
+	assert.Equal(t, 0, pool.inFlight())
+	assert.Equal(t, 0, pool.queueDepth())
+}
+
+func TestWorkerPool_run_shouldCountRejectionsByPool(t *testing.T) {
+	pool := newWorkerPool(poolStream, &AsyncConfiguration{MaxInFlight: 1})
+	rejectedQueriesBefore, rejectedStreamsBefore := rejectedQueriesTotal.Value(poolQuery), rejectedQueriesTotal.Value(poolStream)
+	release := make(chan struct{})
+	defer close(release)
+	running := make(chan struct{})
+	go func() {
+		_ = pool.run(context.Background(), func() error {
+			close(running)
+			<-release
+			return nil
+		})
+	}()
+	<-running
+
+	err := pool.run(context.Background(), func() error { return errors.New("should not run") })
+
+	assert.ErrorIs(t, err, model.ErrOverloaded)
+	assert.Equal(t, rejectedStreamsBefore+1, rejectedQueriesTotal.Value(poolStream))
+	assert.Equal(t, rejectedQueriesBefore, rejectedQueriesTotal.Value(poolQuery))
+}
+
+func TestWorkerPool_run_shouldStopWaiting_whenContextDone(t *testing.T) {
+	pool := newWorkerPool(poolQuery, &AsyncConfiguration{MaxInFlight: 1, QueueSize: 1})
+	release := make(chan struct{})
+	defer close(release)
+	running := make(chan struct{})
+	go func() {
+		_ = pool.run(context.Background(), func() error {
+			close(running)
+			<-release
+			return nil
+		})
+	}()
+	<-running
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFunc()
+
+	err := pool.run(ctx, func() error { return errors.New("should not run") })
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, pool.queueDepth())
+}
+
+func TestWorkerPool_run_shouldRunRightAway_whenUnbounded(t *testing.T) {
+	pool := newWorkerPool(poolQuery, &AsyncConfiguration{})
+
+	err := pool.run(context.Background(), func() error { return errors.New("query error") })
+
+	assert.Nil(t, pool)
+	assert.EqualError(t, err, "query error")
+}
+
+func TestPageRepositoryAsyncImpl_GetSeoForPage_shouldReturnOverloadedError_whenPoolSaturated(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	p := NewPageRepositoryAsync(&AsyncConfiguration{MaxInFlight: 1, QueueSize: 0, RetryAfter: time.Second}, pageRepositoryMock{
+		getSeoForPageFunc: func(ctx context.Context, pageId int) (*model.SEO, error) {
+			if pageId == 1 {
+				<-release
+			}
+			return &sampleSeo, nil
+		},
+	})
+	_, cancelFunc := p.GetSeoForPage(context.Background(), 1)
+	defer cancelFunc()
+	assert.Eventually(t, func() bool { return p.(PageRepositoryAsyncImpl).pool.inFlight() == 1 }, time.Second, time.Millisecond)
+
+	seoChan, cancelFunc := p.GetSeoForPage(context.Background(), 2)
+	defer cancelFunc()
+
+	result := <-seoChan
+	assert.Nil(t, result.SEO)
+	assert.ErrorIs(t, result.Err, model.ErrOverloaded)
+}
+
+func TestPageRepositoryAsyncImpl_StreamProductsForPage_shouldLimitStreamsSeparatelyFromQueries(t *testing.T) {
+	products := make([]model.Product, 2*streamBufferSize)
+	p := NewPageRepositoryAsync(&AsyncConfiguration{MaxInFlight: 1, QueueSize: 0, MaxStreams: 1, RetryAfter: time.Second}, pageRepositoryMock{
+		getSeoForPageFunc:      func(ctx context.Context, pageId int) (*model.SEO, error) { return &sampleSeo, nil },
+		getProductsForPageFunc: func(ctx context.Context, pageId int) ([]model.Product, error) { return products, nil },
+	})
+	slowChan, slowCancelFunc := p.StreamProductsForPage(context.Background(), 1)
+	defer slowCancelFunc()
+	assert.Eventually(t, func() bool { return len(slowChan) == streamBufferSize }, time.Second, time.Millisecond)
+
+	seoChan, seoCancelFunc := p.GetSeoForPage(context.Background(), 1)
+	defer seoCancelFunc()
+	rejectedChan, rejectedCancelFunc := p.StreamProductsForPage(context.Background(), 2)
+	defer rejectedCancelFunc()
+
+	assert.NoError(t, (<-seoChan).Err, "should run queries, while slow stream is blocked")
+	rejected := <-rejectedChan
+	assert.ErrorIs(t, rejected.Err, model.ErrOverloaded)
+	_, open := <-rejectedChan
+	assert.False(t, open)
+}
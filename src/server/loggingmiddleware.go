@@ -0,0 +1,41 @@
+package server
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/remikj/pages-ms/src/logging"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// loggingMiddleware passes logger with request id to handlers through request context
+// and logs every handled request with its status, latency and page id.
+// It expects request id to be already set by middleware.RequestID.
+func loggingMiddleware(logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			start := time.Now()
+			requestId := middleware.GetReqID(request.Context())
+			requestLogger := logger.With("request_id", requestId)
+			writer.Header().Set(middleware.RequestIDHeader, requestId)
+			wrappedWriter := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+
+			next.ServeHTTP(wrappedWriter, request.WithContext(logging.WithLogger(request.Context(), requestLogger)))
+
+			attributes := []any{
+				"method", request.Method,
+				"path", request.URL.Path,
+				"status", wrappedWriter.Status(),
+				"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if routeContext := chi.RouteContext(request.Context()); routeContext != nil {
+				attributes = append(attributes, "route", routeContext.RoutePattern())
+				if pageId := routeContext.URLParam("id"); pageId != "" {
+					attributes = append(attributes, "page_id", pageId)
+				}
+			}
+			requestLogger.Info("request handled", attributes...)
+		})
+	}
+}
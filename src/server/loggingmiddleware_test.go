@@ -0,0 +1,44 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoggingMiddleware_shouldLogRequestWithRequestIdAndPageId(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buffer, nil))
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID, loggingMiddleware(logger))
+	router.Get("/pages/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		logging.FromContext(request.Context(), nil).Info("handler message")
+		writer.WriteHeader(http.StatusNotFound)
+	})
+	request := httptest.NewRequest("GET", "/pages/7", nil)
+	request.Header.Set("X-Request-Id", "request-1")
+	responseRecorder := httptest.NewRecorder()
+
+	router.ServeHTTP(responseRecorder, request)
+
+	lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	handlerRecord, requestRecord := map[string]any{}, map[string]any{}
+	require.NoError(t, json.Unmarshal(lines[0], &handlerRecord))
+	require.NoError(t, json.Unmarshal(lines[1], &requestRecord))
+	assert.Equal(t, "request-1", handlerRecord["request_id"])
+	assert.Equal(t, "request-1", requestRecord["request_id"])
+	assert.Equal(t, "7", requestRecord["page_id"])
+	assert.Equal(t, "/pages/{id}", requestRecord["route"])
+	assert.Equal(t, float64(http.StatusNotFound), requestRecord["status"])
+	assert.Contains(t, requestRecord, "latency_ms")
+	assert.Equal(t, "request-1", responseRecorder.Header().Get("X-Request-Id"))
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/remikj/pages-ms/src/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounterVec(
+		"http_requests_total",
+		"Total number of HTTP requests by method, route and status.",
+		"method", "route", "status",
+	)
+	httpRequestDuration = metrics.NewHistogramVec(
+		"http_request_duration_seconds",
+		"Duration of HTTP requests in seconds by method, route and status.",
+		metrics.DefaultBuckets,
+		"method", "route", "status",
+	)
+)
+
+// metricsMiddleware records count and duration of requests, labeled with chi route pattern
+// instead of the path, so page ids do not create new series.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		wrappedWriter := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+
+		next.ServeHTTP(wrappedWriter, request)
+
+		route := "unmatched"
+		if routeContext := chi.RouteContext(request.Context()); routeContext != nil && routeContext.RoutePattern() != "" {
+			route = routeContext.RoutePattern()
+		}
+		status := wrappedWriter.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		statusLabel := strconv.Itoa(status)
+		httpRequestsTotal.Inc(request.Method, route, statusLabel)
+		httpRequestDuration.Observe(time.Since(start).Seconds(), request.Method, route, statusLabel)
+	})
+}
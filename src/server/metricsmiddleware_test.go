@@ -0,0 +1,34 @@
+package server
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsMiddleware_shouldCountRequestsByRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(metricsMiddleware)
+	router.Get("/test-metrics/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusTeapot)
+	})
+	before := httpRequestsTotal.Value("GET", "/test-metrics/{id}", "418")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test-metrics/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test-metrics/2", nil))
+
+	assert.Equal(t, before+2, httpRequestsTotal.Value("GET", "/test-metrics/{id}", "418"))
+}
+
+func TestMetricsMiddleware_shouldCountUnmatchedRequests(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(metricsMiddleware)
+	router.Get("/test-metrics", func(writer http.ResponseWriter, request *http.Request) {})
+	before := httpRequestsTotal.Value("GET", "unmatched", "404")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/not-existing", nil))
+
+	assert.Equal(t, before+1, httpRequestsTotal.Value("GET", "unmatched", "404"))
+}
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyAPIKey = "api_key"
+	RateLimitKeyHeader = "header"
+
+	// apiKeyHeader identifies clients with RateLimitKeyAPIKey.
+	apiKeyHeader = "X-API-Key"
+	// bucketSweepInterval is how often buckets refilled to their burst are removed, as they are
+	// the same as new buckets.
+	bucketSweepInterval = time.Minute
+)
+
+// RateLimitConfiguration of per-client rate limits. Each client gets a token bucket refilled with Rate tokens
+// per second up to Burst tokens, and every request takes one token. Routes listed in Routes have their own
+// buckets and limits, other routes share a bucket with the default limit. Rate of 0 disables the limit.
+// At most MaxBuckets buckets are kept, least recently used ones are evicted first.
+type RateLimitConfiguration struct {
+	Rate   float64     `envconfig:"RATE_LIMIT_RATE" default:"0"`
+	Burst  int         `envconfig:"RATE_LIMIT_BURST" default:"20"`
+	Routes RouteLimits `envconfig:"RATE_LIMIT_ROUTES"`
+	// Key identifies clients: ip, api_key from X-API-Key header or header named Header. Clients could get
+	// a new bucket with every new value, so only verified values are used: api keys listed in APIKeys,
+	// and the header set by one of TrustedProxies. Other clients are identified by ip.
+	Key     string   `envconfig:"RATE_LIMIT_KEY" default:"ip"`
+	Header  string   `envconfig:"RATE_LIMIT_HEADER"`
+	APIKeys []string `envconfig:"RATE_LIMIT_API_KEYS"`
+	// TrustedProxies are addresses or CIDR ranges of proxies, whose X-Forwarded-For is used to find client ip.
+	TrustedProxies []string `envconfig:"RATE_LIMIT_TRUSTED_PROXIES"`
+	MaxBuckets     int      `envconfig:"RATE_LIMIT_MAX_BUCKETS" default:"10000"`
+}
+
+// RateLimit is rate in tokens per second and burst of a token bucket.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RouteLimits are limits of routes keyed by "METHOD /route" or "/route" for all methods, where route is pattern
+// registered in the router, e.g. "POST /pages" or "/pages/{id}".
+type RouteLimits map[string]RateLimit
+
+// Decode parses limits separated by semicolon, each as route=rate,burst, e.g. "POST /pages=1,5;/graphql=5,10".
+func (r *RouteLimits) Decode(value string) error {
+	limits := RouteLimits{}
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, limitValue, found := strings.Cut(entry, "=")
+		rateValue, burstValue, hasBurst := strings.Cut(limitValue, ",")
+		if !found || !hasBurst {
+			return fmt.Errorf("invalid route limit %q, expected route=rate,burst", entry)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateValue), 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("invalid rate of route limit %q", entry)
+		}
+		burst, err := strconv.Atoi(strings.TrimSpace(burstValue))
+		if err != nil || (rate > 0 && burst < 1) {
+			return fmt.Errorf("invalid burst of route limit %q", entry)
+		}
+		limits[strings.Join(strings.Fields(route), " ")] = RateLimit{Rate: rate, Burst: burst}
+	}
+	*r = limits
+	return nil
+}
+
+// rateLimitDecision is result of taking a token, Remaining tokens and Reset, time until the bucket is full,
+// are reported in RateLimit headers, RetryAfter is time until the next token when the request is not allowed.
+type rateLimitDecision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type tokenBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps token buckets of clients, ordered from the most recently used. Buckets refilled to their burst
+// are removed every bucketSweepInterval, the least recently used ones also when there are more than MaxBuckets.
+type rateLimiter struct {
+	config         *RateLimitConfiguration
+	trustedProxies []netip.Prefix
+	apiKeys        map[string]bool
+	now            func() time.Time
+
+	mutex     sync.Mutex
+	buckets   map[string]*list.Element
+	lru       *list.List
+	lastSweep time.Time
+}
+
+// newRateLimiter returns nil when no limit is configured.
+func newRateLimiter(config *RateLimitConfiguration) (*rateLimiter, error) {
+	if config.Rate <= 0 && len(config.Routes) == 0 {
+		return nil, nil
+	}
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	apiKeys := make(map[string]bool, len(config.APIKeys))
+	for _, apiKey := range config.APIKeys {
+		apiKeys[apiKey] = true
+	}
+	return &rateLimiter{
+		config:         config,
+		trustedProxies: trustedProxies,
+		apiKeys:        apiKeys,
+		now:            time.Now,
+		buckets:        map[string]*list.Element{},
+		lru:            list.New(),
+		lastSweep:      time.Now(),
+	}, nil
+}
+
+// limitOf returns limit of the route and name of its bucket, routes without their own limit share the default one.
+func (l *rateLimiter) limitOf(method string, route string) (RateLimit, string) {
+	for _, key := range []string{method + " " + route, route} {
+		if limit, ok := l.config.Routes[key]; ok {
+			return limit, key
+		}
+	}
+	return RateLimit{Rate: l.config.Rate, Burst: l.config.Burst}, ""
+}
+
+// take takes a token from bucket of the client for the limit. It is allowed without a bucket when rate is 0.
+func (l *rateLimiter) take(bucketKey string, limit RateLimit) (rateLimitDecision, bool) {
+	if limit.Rate <= 0 {
+		return rateLimitDecision{}, false
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= bucketSweepInterval {
+		l.sweep(now)
+	}
+	bucket := l.bucket(bucketKey, limit, now)
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.updated).Seconds()*limit.Rate)
+	bucket.updated = now
+	decision := rateLimitDecision{Limit: limit.Burst}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - bucket.tokens) / limit.Rate)
+	}
+	decision.Remaining = int(bucket.tokens)
+	decision.Reset = secondsToDuration((float64(limit.Burst) - bucket.tokens) / limit.Rate)
+	return decision, true
+}
+
+// bucket returns bucket of the key, new bucket is full. Evicted bucket is refilled, when its client comes back.
+func (l *rateLimiter) bucket(bucketKey string, limit RateLimit, now time.Time) *tokenBucket {
+	if element, ok := l.buckets[bucketKey]; ok {
+		l.lru.MoveToFront(element)
+		return element.Value.(*tokenBucket)
+	}
+	bucket := &tokenBucket{key: bucketKey, tokens: float64(limit.Burst), updated: now}
+	l.buckets[bucketKey] = l.lru.PushFront(bucket)
+	for l.config.MaxBuckets > 0 && l.lru.Len() > l.config.MaxBuckets {
+		l.removeElement(l.lru.Back())
+		rateLimitEvictionsTotal.Inc()
+	}
+	return bucket
+}
+
+// sweep removes buckets, which would be full by now. Limits of routes may differ, so buckets are kept
+// until the largest burst would be refilled at the lowest rate. Buckets are ordered by their last use,
+// so only idle ones at the back are visited.
+func (l *rateLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	slowest := l.slowestRefill()
+	for element := l.lru.Back(); element != nil && now.Sub(element.Value.(*tokenBucket).updated) >= slowest; element = l.lru.Back() {
+		l.removeElement(element)
+	}
+}
+
+func (l *rateLimiter) removeElement(element *list.Element) {
+	l.lru.Remove(element)
+	delete(l.buckets, element.Value.(*tokenBucket).key)
+}
+
+func (l *rateLimiter) slowestRefill() time.Duration {
+	slowest := time.Duration(0)
+	limits := []RateLimit{{Rate: l.config.Rate, Burst: l.config.Burst}}
+	for _, limit := range l.config.Routes {
+		limits = append(limits, limit)
+	}
+	for _, limit := range limits {
+		if limit.Rate > 0 {
+			slowest = max(slowest, secondsToDuration(float64(limit.Burst)/limit.Rate))
+		}
+	}
+	return slowest
+}
+
+// clientKey identifies client of the request by ip, unless it has verified api key or header.
+func (l *rateLimiter) clientKey(request *http.Request) string {
+	switch l.config.Key {
+	case RateLimitKeyAPIKey:
+		if apiKey := request.Header.Get(apiKeyHeader); l.apiKeys[apiKey] {
+			return "key:" + apiKey
+		}
+	case RateLimitKeyHeader:
+		if value := request.Header.Get(l.config.Header); value != "" && l.fromTrustedProxy(request) {
+			return "key:" + value
+		}
+	}
+	return "ip:" + l.clientIP(request)
+}
+
+// fromTrustedProxy tells whether the request was sent by one of trusted proxies.
+func (l *rateLimiter) fromTrustedProxy(request *http.Request) bool {
+	address, err := netip.ParseAddr(remoteHost(request))
+	return err == nil && l.isTrustedProxy(address)
+}
+
+// clientIP is remote address of the request. When it is a trusted proxy, addresses in X-Forwarded-For are checked
+// from the last one, appended by the nearest proxy, and the first one which is not a trusted proxy is the client.
+func (l *rateLimiter) clientIP(request *http.Request) string {
+	host := remoteHost(request)
+	address, err := netip.ParseAddr(host)
+	if err != nil || !l.isTrustedProxy(address) {
+		return host
+	}
+	forwardedFor := strings.Split(strings.Join(request.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		forwarded, err := netip.ParseAddr(strings.TrimSpace(forwardedFor[i]))
+		if err != nil {
+			break
+		}
+		address = forwarded
+		if !l.isTrustedProxy(address) {
+			break
+		}
+	}
+	return address.String()
+}
+
+func remoteHost(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+func (l *rateLimiter) isTrustedProxy(address netip.Addr) bool {
+	for _, prefix := range l.trustedProxies {
+		if prefix.Contains(address.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			address, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(address.Unmap(), address.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
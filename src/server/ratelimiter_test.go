@@ -0,0 +1,198 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteLimits_Decode(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		expectedLimits RouteLimits
+		expectedErr    string
+	}{
+		{
+			name:  "should parse limits of routes",
+			value: "POST  /pages=1,5; /graphql=0.5,10;",
+			expectedLimits: RouteLimits{
+				"POST /pages": {Rate: 1, Burst: 5},
+				"/graphql":    {Rate: 0.5, Burst: 10},
+			},
+		},
+		{
+			name:           "should parse disabled limit",
+			value:          "GET /pages=0,0",
+			expectedLimits: RouteLimits{"GET /pages": {}},
+		},
+		{name: "should fail, when burst missing", value: "/pages=1", expectedErr: `invalid route limit "/pages=1", expected route=rate,burst`},
+		{name: "should fail, when rate invalid", value: "/pages=fast,1", expectedErr: `invalid rate of route limit "/pages=fast,1"`},
+		{name: "should fail, when burst below 1", value: "/pages=1,0", expectedErr: `invalid burst of route limit "/pages=1,0"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := RouteLimits{}
+
+			err := limits.Decode(tt.value)
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLimits, limits)
+		})
+	}
+}
+
+func TestRateLimiter_take_shouldRefillBucketAtRate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	limiter, err := newRateLimiter(&RateLimitConfiguration{Rate: 2, Burst: 2})
+	require.NoError(t, err)
+	limiter.now = func() time.Time { return now }
+	limit := RateLimit{Rate: 2, Burst: 2}
+
+	first, _ := limiter.take("client", limit)
+	second, _ := limiter.take("client", limit)
+	rejected, _ := limiter.take("client", limit)
+	now = now.Add(500 * time.Millisecond)
+	refilled, _ := limiter.take("client", limit)
+	other, _ := limiter.take("other", limit)
+
+	assert.Equal(t, rateLimitDecision{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond}, first)
+	assert.Equal(t, rateLimitDecision{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Second}, second)
+	assert.Equal(t, rateLimitDecision{Allowed: false, Limit: 2, Remaining: 0, Reset: time.Second, RetryAfter: 500 * time.Millisecond}, rejected)
+	assert.True(t, refilled.Allowed)
+	assert.True(t, other.Allowed, "should keep separate bucket for each client")
+}
+
+func TestRateLimiter_take_shouldRemoveFullBuckets(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	limiter, err := newRateLimiter(&RateLimitConfiguration{Rate: 1, Burst: 10, Routes: RouteLimits{"/graphql": {Rate: 0.1, Burst: 10}}})
+	require.NoError(t, err)
+	limiter.now = func() time.Time { return now }
+	limiter.lastSweep = now
+
+	limiter.take("|idle", RateLimit{Rate: 1, Burst: 10})
+	limiter.take("/graphql|slow", RateLimit{Rate: 0.1, Burst: 10})
+	now = now.Add(bucketSweepInterval)
+	limiter.take("|active", RateLimit{Rate: 1, Burst: 10})
+	assert.Len(t, limiter.buckets, 3, "should keep buckets until the slowest one could be refilled")
+	now = now.Add(bucketSweepInterval)
+	limiter.take("|active", RateLimit{Rate: 1, Burst: 10})
+
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "|active")
+}
+
+func TestRateLimiter_take_shouldEvictLeastRecentlyUsedBuckets(t *testing.T) {
+	limiter, err := newRateLimiter(&RateLimitConfiguration{Rate: 1, Burst: 10, MaxBuckets: 2})
+	require.NoError(t, err)
+	limit := RateLimit{Rate: 1, Burst: 10}
+
+	limiter.take("|first", limit)
+	limiter.take("|second", limit)
+	limiter.take("|first", limit)
+	limiter.take("|third", limit)
+
+	assert.Len(t, limiter.buckets, 2)
+	assert.Equal(t, 2, limiter.lru.Len())
+	assert.Contains(t, limiter.buckets, "|first", "should keep recently used bucket")
+	assert.Contains(t, limiter.buckets, "|third")
+	assert.NotContains(t, limiter.buckets, "|second")
+}
+
+func TestRateLimiter_clientKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      RateLimitConfiguration
+		remoteAddr  string
+		headers     map[string][]string
+		expectedKey string
+	}{
+		{
+			name:        "should use remote address, when it is not trusted proxy",
+			config:      RateLimitConfiguration{Key: RateLimitKeyIP, TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr:  "192.0.2.1:1234",
+			headers:     map[string][]string{"X-Forwarded-For": {"198.51.100.7"}},
+			expectedKey: "ip:192.0.2.1",
+		},
+		{
+			name:        "should use the last untrusted address of X-Forwarded-For, when remote address is trusted proxy",
+			config:      RateLimitConfiguration{Key: RateLimitKeyIP, TrustedProxies: []string{"10.0.0.0/8", "192.0.2.10"}},
+			remoteAddr:  "10.1.2.3:1234",
+			headers:     map[string][]string{"X-Forwarded-For": {"203.0.113.5, 198.51.100.7", "192.0.2.10"}},
+			expectedKey: "ip:198.51.100.7",
+		},
+		{
+			name:        "should use the first address, when all addresses are trusted proxies",
+			config:      RateLimitConfiguration{Key: RateLimitKeyIP, TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr:  "10.1.2.3:1234",
+			headers:     map[string][]string{"X-Forwarded-For": {"10.0.0.1"}},
+			expectedKey: "ip:10.0.0.1",
+		},
+		{
+			name:        "should use api key",
+			config:      RateLimitConfiguration{Key: RateLimitKeyAPIKey, APIKeys: []string{"secret"}},
+			remoteAddr:  "192.0.2.1:1234",
+			headers:     map[string][]string{"X-Api-Key": {"secret"}},
+			expectedKey: "key:secret",
+		},
+		{
+			name:        "should use ip, when api key unknown",
+			config:      RateLimitConfiguration{Key: RateLimitKeyAPIKey, APIKeys: []string{"secret"}},
+			remoteAddr:  "192.0.2.1:1234",
+			headers:     map[string][]string{"X-Api-Key": {"rotated"}},
+			expectedKey: "ip:192.0.2.1",
+		},
+		{
+			name:        "should use header, when set by trusted proxy",
+			config:      RateLimitConfiguration{Key: RateLimitKeyHeader, Header: "X-Client-Id", TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr:  "10.1.2.3:1234",
+			headers:     map[string][]string{"X-Client-Id": {"c1"}},
+			expectedKey: "key:c1",
+		},
+		{
+			name:        "should use ip, when header not set by trusted proxy",
+			config:      RateLimitConfiguration{Key: RateLimitKeyHeader, Header: "X-Client-Id", TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr:  "192.0.2.1:1234",
+			headers:     map[string][]string{"X-Client-Id": {"c1"}},
+			expectedKey: "ip:192.0.2.1",
+		},
+		{
+			name:        "should use ip, when header missing",
+			config:      RateLimitConfiguration{Key: RateLimitKeyHeader, Header: "X-Client-Id", TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr:  "[2001:db8::1]:1234",
+			headers:     map[string][]string{"X-Api-Key": {"secret"}},
+			expectedKey: "ip:2001:db8::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Rate, tt.config.Burst = 1, 1
+			limiter, err := newRateLimiter(&tt.config)
+			require.NoError(t, err)
+			request := httptest.NewRequest("GET", "/pages/1", nil)
+			request.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.headers {
+				for _, value := range values {
+					request.Header.Add(name, value)
+				}
+			}
+
+			assert.Equal(t, tt.expectedKey, limiter.clientKey(request))
+		})
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	limiter, err := newRateLimiter(&RateLimitConfiguration{Burst: 20})
+	assert.NoError(t, err)
+	assert.Nil(t, limiter, "should not limit, when no limit configured")
+
+	_, err = newRateLimiter(&RateLimitConfiguration{Rate: 1, Burst: 1, TrustedProxies: []string{"10.0.0.0/33"}})
+	assert.ErrorContains(t, err, `invalid trusted proxy "10.0.0.0/33"`)
+}
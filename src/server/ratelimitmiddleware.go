@@ -0,0 +1,63 @@
+package server
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/metrics"
+	"github.com/remikj/pages-ms/src/problem"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRateLimitedTotal = metrics.NewCounterVec(
+		"http_rate_limited_total",
+		"Total number of HTTP requests rejected by rate limit by method and route.",
+		"method", "route",
+	)
+	rateLimitEvictionsTotal = metrics.NewCounterVec(
+		"http_rate_limit_evictions_total",
+		"Total number of rate limit buckets evicted because there were more than maximal number of buckets.",
+	)
+)
+
+// rateLimitMiddleware takes a token of the client for every request and rejects the request with 429
+// when there is none. Limited responses get RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+// Limits depend on the route, so the middleware has to be used on routes, not on the whole router.
+// Nil limiter disables limits.
+func rateLimitMiddleware(limiter *rateLimiter, logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			route := chi.RouteContext(request.Context()).RoutePattern()
+			limit, limitName := limiter.limitOf(request.Method, route)
+			decision, limited := limiter.take(limitName+"|"+limiter.clientKey(request), limit)
+			if !limited {
+				next.ServeHTTP(writer, request)
+				return
+			}
+			writer.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			writer.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			writer.Header().Set("RateLimit-Reset", ceilSeconds(decision.Reset))
+			if !decision.Allowed {
+				httpRateLimitedTotal.Inc(request.Method, route)
+				logging.FromContext(request.Context(), logger).Info("rate limit exceeded", "outcome", "rate_limited",
+					"key", limiter.config.Key, "limit", limitName)
+				writer.Header().Set("Retry-After", ceilSeconds(decision.RetryAfter))
+				problem.Write(writer, request, logger, http.StatusTooManyRequests, problem.CodeRateLimited, "Rate limit exceeded, retry later")
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// ceilSeconds formats duration as whole seconds, rounded up, so the client never retries too early.
+func ceilSeconds(duration time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(duration.Seconds())))
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/remikj/pages-ms/src/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitMiddleware_shouldLimitRoutesOfApi(t *testing.T) {
+	server := newTestServer(&Configuration{RateLimit: RateLimitConfiguration{
+		Rate:   0.001,
+		Burst:  1,
+		Key:    RateLimitKeyIP,
+		Routes: RouteLimits{"GET /pages/{id}": {Rate: 0.001, Burst: 2}},
+	}}, &healthControllerMock{})
+	router, err := server.Router()
+	require.NoError(t, err)
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	first := serve(http.MethodGet, "/pages/first")
+	second := serve(http.MethodGet, "/pages/second")
+	limited := serve(http.MethodGet, "/pages/third")
+	otherRoute := serve(http.MethodDelete, "/pages/first")
+	otherRouteLimited := serve(http.MethodDelete, "/pages/second")
+	health := serve(http.MethodGet, "/healthz")
+
+	assert.Equal(t, http.StatusBadRequest, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1000", first.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, http.StatusBadRequest, second.Code)
+	assert.Equal(t, "0", second.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "1000", limited.Header().Get("Retry-After"))
+	assert.Equal(t, "0", limited.Header().Get("RateLimit-Remaining"))
+	limitedProblem := problem.Problem{}
+	require.NoError(t, json.Unmarshal(limited.Body.Bytes(), &limitedProblem))
+	assert.Equal(t, problem.CodeRateLimited, limitedProblem.Code)
+	assert.Equal(t, http.StatusBadRequest, otherRoute.Code, "should limit other routes with their own bucket")
+	assert.Equal(t, "1", otherRoute.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusTooManyRequests, otherRouteLimited.Code)
+	assert.Equal(t, http.StatusOK, health.Code)
+	assert.Empty(t, health.Header().Get("RateLimit-Limit"), "should not limit health checks")
+}
+
+func TestRateLimitMiddleware_shouldNotLimit_whenLimitDisabled(t *testing.T) {
+	server := newTestServer(&Configuration{RateLimit: RateLimitConfiguration{
+		Rate:   0.001,
+		Burst:  1,
+		Key:    RateLimitKeyIP,
+		Routes: RouteLimits{"/pages/{id}": {}},
+	}}, &healthControllerMock{})
+	router, err := server.Router()
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/pages/first", nil))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	}
+}
@@ -1,29 +1,59 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/remikj/pages-ms/src/contoller"
+	"github.com/remikj/pages-ms/src/grpcapi"
+	"github.com/remikj/pages-ms/src/metrics"
+	"github.com/remikj/pages-ms/src/openapi"
+	"log/slog"
+	"net"
 	"net/http"
+	"time"
 )
 import "github.com/go-chi/chi/v5"
+import "github.com/go-chi/chi/v5/middleware"
 import "github.com/kelseyhightower/envconfig"
 
 type Server struct {
-	Config         *Configuration
-	PageController contoller.PageController
+	Config            *Configuration
+	PageController    contoller.PageController
+	ProductController contoller.ProductController
+	HealthController  contoller.HealthController
+	GraphQLController contoller.GraphQLController
+	// GrpcServer is run next to HTTP server, nil disables it.
+	GrpcServer *grpcapi.Server
+	Logger     *slog.Logger
 }
 
 type Configuration struct {
-	Port int `envconfig:"SERVICE_PORT" default:"8080"`
+	Port            int           `envconfig:"SERVICE_PORT" default:"8080"`
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	RequestTimeout  time.Duration `envconfig:"REQUEST_TIMEOUT" default:"10s"`
+	// ValidateRequests rejects requests not matching the OpenAPI specification with 400.
+	ValidateRequests bool `envconfig:"OPENAPI_VALIDATE_REQUESTS" default:"false"`
+	// ValidateResponses replaces responses not matching the OpenAPI specification with 500, requests are validated too.
+	// Responses are buffered for validation, so it is meant for tests.
+	ValidateResponses bool `envconfig:"OPENAPI_VALIDATE_RESPONSES" default:"false"`
+	// RateLimit limits requests of API routes, health, metrics and docs are not limited.
+	RateLimit RateLimitConfiguration `ignored:"true"`
 }
 
-func NewServerFromEnv(pageController contoller.PageController) (*Server, error) {
+func NewServerFromEnv(
+	pageController contoller.PageController,
+	productController contoller.ProductController,
+	healthController contoller.HealthController,
+	graphQLController contoller.GraphQLController,
+	grpcServer *grpcapi.Server,
+	logger *slog.Logger,
+) (*Server, error) {
 	configFromEnv, err := ConfigurationFromEnv()
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
-	return NewServer(configFromEnv, pageController), nil
+	return NewServer(configFromEnv, pageController, productController, healthController, graphQLController, grpcServer, logger), nil
 }
 
 func ConfigurationFromEnv() (*Configuration, error) {
@@ -32,20 +62,183 @@ func ConfigurationFromEnv() (*Configuration, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := envconfig.Process("", &config.RateLimit); err != nil {
+		return nil, err
+	}
+	switch config.RateLimit.Key {
+	case RateLimitKeyIP:
+	case RateLimitKeyAPIKey:
+		if len(config.RateLimit.APIKeys) == 0 {
+			return nil, fmt.Errorf("RATE_LIMIT_API_KEYS has to be set, when RATE_LIMIT_KEY is %v", RateLimitKeyAPIKey)
+		}
+	case RateLimitKeyHeader:
+		if config.RateLimit.Header == "" {
+			return nil, fmt.Errorf("RATE_LIMIT_HEADER has to be set, when RATE_LIMIT_KEY is %v", RateLimitKeyHeader)
+		}
+		if len(config.RateLimit.TrustedProxies) == 0 {
+			return nil, fmt.Errorf("RATE_LIMIT_TRUSTED_PROXIES has to be set, when RATE_LIMIT_KEY is %v", RateLimitKeyHeader)
+		}
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_KEY %q, expected %v, %v or %v", config.RateLimit.Key,
+			RateLimitKeyIP, RateLimitKeyAPIKey, RateLimitKeyHeader)
+	}
+	if config.RateLimit.Rate > 0 && config.RateLimit.Burst < 1 {
+		return nil, fmt.Errorf("RATE_LIMIT_BURST has to be at least 1, got %v", config.RateLimit.Burst)
+	}
+	if config.RateLimit.MaxBuckets < 1 {
+		return nil, fmt.Errorf("RATE_LIMIT_MAX_BUCKETS has to be at least 1, got %v", config.RateLimit.MaxBuckets)
+	}
 	return config, nil
 }
 
-func NewServer(config *Configuration, pageController contoller.PageController) *Server {
+func NewServer(
+	config *Configuration,
+	pageController contoller.PageController,
+	productController contoller.ProductController,
+	healthController contoller.HealthController,
+	graphQLController contoller.GraphQLController,
+	grpcServer *grpcapi.Server,
+	logger *slog.Logger,
+) *Server {
 	return &Server{
-		Config:         config,
-		PageController: pageController,
+		Config:            config,
+		PageController:    pageController,
+		ProductController: productController,
+		HealthController:  healthController,
+		GraphQLController: graphQLController,
+		GrpcServer:        grpcServer,
+		Logger:            logger,
 	}
 }
 
-func (s *Server) Run() error {
+// Run serves HTTP and gRPC requests until ctx is done, then stops accepting new connections and waits
+// up to ShutdownTimeout for in-flight requests. Requests still running after the timeout are cancelled.
+// When one of the servers fails, the other one is stopped immediately.
+func (s *Server) Run(ctx context.Context) error {
+	router, err := s.Router()
+	if err != nil {
+		return err
+	}
+	baseCtx, cancelBaseCtx := context.WithCancel(context.Background())
+	defer cancelBaseCtx()
+	server := &http.Server{
+		Addr:        fmt.Sprintf(":%v", s.Config.Port),
+		Handler:     router,
+		BaseContext: func(_ net.Listener) context.Context { return baseCtx },
+	}
+	grpcErrChan := make(chan error, 1)
+	if s.GrpcServer != nil {
+		listener, err := s.GrpcServer.Listen()
+		if err != nil {
+			return fmt.Errorf("failed to start grpc server: %w", err)
+		}
+		go func() {
+			grpcErrChan <- s.GrpcServer.Serve(listener)
+		}()
+		s.Logger.Info("starting grpc server", "port", s.GrpcServer.Config.Port)
+	}
+	serveErrChan := make(chan error, 1)
+	go func() {
+		serveErrChan <- server.ListenAndServe()
+	}()
+	s.Logger.Info("starting server", "port", s.Config.Port)
+
+	select {
+	case err := <-serveErrChan:
+		if s.GrpcServer != nil {
+			s.GrpcServer.Stop()
+		}
+		return err
+	case err := <-grpcErrChan:
+		if closeErr := server.Close(); closeErr != nil {
+			s.Logger.Warn("failed to close server", "error", closeErr)
+		}
+		return fmt.Errorf("grpc server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	s.Logger.Info("shutting down server", "timeout", s.Config.ShutdownTimeout.String())
+	s.HealthController.SetShuttingDown()
+	shutdownCtx, cancelShutdownCtx := context.WithTimeout(context.Background(), s.Config.ShutdownTimeout)
+	defer cancelShutdownCtx()
+	grpcShutdownErrChan := make(chan error, 1)
+	if s.GrpcServer != nil {
+		go func() {
+			grpcShutdownErrChan <- s.GrpcServer.Shutdown(shutdownCtx)
+		}()
+	} else {
+		grpcShutdownErrChan <- nil
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		cancelBaseCtx()
+		if closeErr := server.Close(); closeErr != nil {
+			s.Logger.Warn("failed to close server", "error", closeErr)
+		}
+		return fmt.Errorf("failed to drain requests within %v: %w", s.Config.ShutdownTimeout, err)
+	}
+	if err := <-serveErrChan; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if err := <-grpcShutdownErrChan; err != nil {
+		return fmt.Errorf("failed to drain grpc requests within %v: %w", s.Config.ShutdownTimeout, err)
+	}
+	s.Logger.Info("server stopped")
+	return nil
+}
+
+func (s *Server) Router() (http.Handler, error) {
+	spec, err := openapi.Spec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi specification: %w", err)
+	}
+	specHandler, err := openapi.Handler()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openapi handler: %w", err)
+	}
+	limiter, err := newRateLimiter(&s.Config.RateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limiter: %w", err)
+	}
 	router := chi.NewRouter()
-	router.Get("/pages/{id}", s.PageController.HandlePageGet)
-	server := &http.Server{Addr: fmt.Sprintf(":%v", s.Config.Port), Handler: router}
-	fmt.Printf("Starting server on port: %v\n", s.Config.Port)
-	return server.ListenAndServe()
+	router.Use(middleware.RequestID, loggingMiddleware(s.Logger), metricsMiddleware)
+	if s.Config.ValidateRequests || s.Config.ValidateResponses {
+		validationMiddleware, err := openapi.ValidationMiddleware(spec, s.Config.ValidateResponses, s.Logger)
+		if err != nil {
+			return nil, err
+		}
+		router.Use(validationMiddleware)
+	}
+	router.Group(func(router chi.Router) {
+		router.Use(timeoutMiddleware(s.Config.RequestTimeout, nil))
+		router.Method(http.MethodGet, "/metrics", metrics.Handler())
+		router.Method(http.MethodGet, "/openapi.json", specHandler)
+		router.Method(http.MethodGet, "/docs", openapi.DocsHandler())
+		router.Get("/healthz", s.HealthController.HandleLiveness)
+		router.Get("/readyz", s.HealthController.HandleReadiness)
+	})
+	router.Group(func(router chi.Router) {
+		router.Use(rateLimitMiddleware(limiter, s.Logger), timeoutMiddleware(s.Config.RequestTimeout, isProductsStream))
+		router.Get("/pages", s.PageController.HandlePagesGet)
+		router.Post("/pages", s.PageController.HandlePagePost)
+		router.Get("/pages/{id}", s.PageController.HandlePageGet)
+		router.Put("/pages/{id}", s.PageController.HandlePagePut)
+		router.Patch("/pages/{id}", s.PageController.HandlePagePatch)
+		router.Delete("/pages/{id}", s.PageController.HandlePageDelete)
+		router.Get("/pages/{id}/products", s.ProductController.HandleProductsGet)
+		router.Post("/pages/{id}/products", s.ProductController.HandleProductsPost)
+		router.Get("/pages/{id}/products/{productId}", s.ProductController.HandleProductGet)
+		router.Put("/pages/{id}/products/{productId}", s.ProductController.HandleProductPut)
+		router.Patch("/pages/{id}/products/{productId}", s.ProductController.HandleProductPatch)
+		router.Delete("/pages/{id}/products/{productId}", s.ProductController.HandleProductDelete)
+		router.Get("/graphql", s.GraphQLController.HandleGraphQL)
+		router.Post("/graphql", s.GraphQLController.HandleGraphQL)
+	})
+	return router, nil
+}
+
+// isProductsStream exempts streams of products from request timeout, they are bounded by their idle timeout.
+// Timeout middleware has to run after routing, so route pattern is known.
+func isProductsStream(request *http.Request) bool {
+	return request.Method == http.MethodGet && chi.RouteContext(request.Context()).RoutePattern() == "/pages/{id}/products" &&
+		contoller.IsProductsStream(request)
 }
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/remikj/pages-ms/src/contoller"
+	"github.com/remikj/pages-ms/src/graphqlapi"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/pagespb"
+	"github.com/remikj/pages-ms/src/repository"
+	"github.com/remikj/pages-ms/src/repository/memoryimpl"
+	"github.com/remikj/pages-ms/src/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newEndToEndServer runs the whole service over in-memory repository seeded with sample data.
+// Responses are validated against the OpenAPI specification, so tests fail when it gets out of date.
+func newEndToEndServer(t *testing.T) *httptest.Server {
+	logger := logging.Discard()
+	pageRepository := memoryimpl.NewPageRepositoryMemory(logger)
+	require.NoError(t, pageRepository.SeedFromFiles(
+		"../../resources/mongodb/sample-seos.json",
+		"../../resources/mongodb/sample-products.json",
+	))
+	pageService := service.NewPageServiceCache(
+		&service.CacheConfiguration{TTL: time.Minute, NegativeTTL: time.Minute, MaxEntries: 10},
+		service.NewPageService(&service.Configuration{}, repository.NewPageRepositoryAsync(&repository.AsyncConfiguration{}, pageRepository), logger),
+	)
+	graphQLExecutor, err := graphqlapi.NewExecutor(
+		&graphqlapi.Configuration{MaxDepth: 5, MaxComplexity: 1000, MaxBatchSize: 10}, pageService, logger)
+	require.NoError(t, err)
+	server := NewServer(
+		&Configuration{RequestTimeout: time.Second, ValidateResponses: true},
+		contoller.NewPageController(&contoller.PageConfiguration{MaxBatchSize: 10, CacheControl: "no-cache"}, pageService, logger),
+		contoller.NewProductController(&contoller.ProductConfiguration{DefaultLimit: 20, MaxLimit: 100}, pageService, logger),
+		contoller.NewHealthController(&contoller.HealthConfiguration{CheckTimeout: time.Second},
+			map[string]contoller.HealthCheck{repository.BackendMemory: pageRepository.Ping}, logger),
+		contoller.NewGraphQLController(graphQLExecutor, logger),
+		nil,
+		logger,
+	)
+	router, err := server.Router()
+	require.NoError(t, err)
+	testServer := httptest.NewServer(router)
+	t.Cleanup(testServer.Close)
+	return testServer
+}
+
+func TestEndToEnd_shouldGetSeededPage(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	response, err := http.Get(testServer.URL + "/pages/1")
+
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	page := model.Page{}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+	assert.Equal(t, model.SEO{PageId: 1, Title: "title1", Description: "description1", Robots: "robots1"}, page.SEO)
+	assert.Len(t, page.Products, 2)
+}
+
+func TestEndToEnd_shouldReadOwnWrites(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	created := doRequest(t, "POST", testServer.URL+"/pages", `{"SEO": {"PageId": 5, "Title": "title5"}, "Products": [{"Id": 1, "Name": "name1", "Price": 1}]}`)
+	beforePatch := doRequest(t, "GET", testServer.URL+"/pages/5", "")
+	patched := doRequest(t, "PATCH", testServer.URL+"/pages/5", `{"SEO": {"Title": "new title"}}`)
+	afterPatch := doRequest(t, "GET", testServer.URL+"/pages/5", "")
+	deleted := doRequest(t, "DELETE", testServer.URL+"/pages/5/products/1", "")
+	afterDelete := doRequest(t, "GET", testServer.URL+"/pages/5/products/1", "")
+
+	assert.Equal(t, http.StatusCreated, created.StatusCode)
+	assert.Equal(t, http.StatusOK, beforePatch.StatusCode)
+	assert.Contains(t, beforePatch.body, `"Title":"title5"`)
+	assert.Equal(t, http.StatusOK, patched.StatusCode)
+	assert.Contains(t, afterPatch.body, `"Title":"new title"`)
+	assert.Equal(t, http.StatusNoContent, deleted.StatusCode)
+	assert.Equal(t, http.StatusNotFound, afterDelete.StatusCode)
+}
+
+func TestEndToEnd_shouldGetPagesInBatch(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	response := doRequest(t, "GET", testServer.URL+"/pages?ids=2,9", "")
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, response.body, `{"PageId":2,"Status":"found"`)
+	assert.Contains(t, response.body, `{"PageId":9,"Status":"not_found"}`)
+}
+
+func TestEndToEnd_shouldBeReady(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	response := doRequest(t, "GET", testServer.URL+"/readyz", "")
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, response.body, `"memory":{"status":"up"`)
+}
+
+func TestEndToEnd_shouldGetPageAsProtobuf(t *testing.T) {
+	testServer := newEndToEndServer(t)
+	request, err := http.NewRequest("GET", testServer.URL+"/pages/1", nil)
+	require.NoError(t, err)
+	request.Header.Set("Accept", "application/x-protobuf")
+
+	response, err := http.DefaultClient.Do(request)
+
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/x-protobuf", response.Header.Get("Content-Type"))
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	page := &pagespb.Page{}
+	require.NoError(t, proto.Unmarshal(body, page))
+	assert.Equal(t, "title1", page.GetSeo().GetTitle())
+	assert.Len(t, page.GetProducts(), 2)
+}
+
+func TestEndToEnd_shouldReturnProblem_whenPageNotFound(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	response := doRequest(t, "GET", testServer.URL+"/pages/9", "")
+
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, "application/problem+json", response.Header.Get("Content-Type"))
+	problem := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(response.body), &problem))
+	assert.Equal(t, "not_found", problem["code"])
+	assert.NotEmpty(t, problem["request_id"])
+}
+
+func TestEndToEnd_shouldServeApiDocumentation(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	spec := doRequest(t, "GET", testServer.URL+"/openapi.json", "")
+	docs := doRequest(t, "GET", testServer.URL+"/docs", "")
+
+	assert.Equal(t, http.StatusOK, spec.StatusCode)
+	assert.Contains(t, spec.body, `"openapi":"3.0.3"`)
+	assert.Equal(t, http.StatusOK, docs.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", docs.Header.Get("Content-Type"))
+}
+
+func TestEndToEnd_shouldSupportConditionalRequests(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	created := doRequest(t, "POST", testServer.URL+"/pages", `{"SEO": {"PageId": 5, "Title": "title5"}, "Products": []}`)
+	etag := created.Header.Get("ETag")
+	notModified := doRequest(t, "GET", testServer.URL+"/pages/5", "", "If-None-Match", etag)
+	patched := doRequest(t, "PATCH", testServer.URL+"/pages/5", `{"SEO": {"Title": "new title"}}`, "If-Match", etag)
+	stalePatch := doRequest(t, "PATCH", testServer.URL+"/pages/5", `{"SEO": {"Title": "stale title"}}`, "If-Match", etag)
+	modified := doRequest(t, "GET", testServer.URL+"/pages/5", "", "If-None-Match", etag)
+
+	assert.Equal(t, http.StatusCreated, created.StatusCode)
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, created.Header.Get("Last-Modified"))
+	assert.Equal(t, http.StatusNotModified, notModified.StatusCode)
+	assert.Equal(t, "no-cache", notModified.Header.Get("Cache-Control"))
+	assert.Equal(t, http.StatusOK, patched.StatusCode)
+	assert.Equal(t, http.StatusPreconditionFailed, stalePatch.StatusCode)
+	assert.Contains(t, stalePatch.body, `"code":"precondition_failed"`)
+	assert.Equal(t, http.StatusOK, modified.StatusCode)
+	assert.Equal(t, patched.Header.Get("ETag"), modified.Header.Get("ETag"))
+	assert.Contains(t, modified.body, `"Title":"new title"`)
+}
+
+func TestEndToEnd_shouldGetSelectedFieldsOfPage(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	response := doRequest(t, "GET", testServer.URL+"/pages/1?fields=seo.title,products.name,products.price", "")
+	invalid := doRequest(t, "GET", testServer.URL+"/pages/1?fields=seo.price", "")
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"SEO":{"Title":"title1"},"Products":[{"Name":"name1","Price":2.5},{"Name":"name2","Price":20.99}]}`, response.body)
+	assert.Equal(t, http.StatusBadRequest, invalid.StatusCode)
+	assert.Contains(t, invalid.body, `"code":"invalid_fields"`)
+}
+
+func TestEndToEnd_shouldPaginateProducts(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	first := doRequest(t, "GET", testServer.URL+"/pages/1/products?limit=1&sort=-price&count=true", "")
+	link := strings.TrimSuffix(strings.TrimPrefix(first.Header.Get("Link"), "<"), `>; rel="next"`)
+	second := doRequest(t, "GET", testServer.URL+link, "")
+	invalid := doRequest(t, "GET", testServer.URL+"/pages/1/products?cursor=not-a-cursor", "")
+
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+	assert.Contains(t, first.body, `"Name":"name2"`)
+	assert.Equal(t, "2", first.Header.Get("X-Total-Count"))
+	assert.Equal(t, http.StatusOK, second.StatusCode)
+	assert.Contains(t, second.body, `"Name":"name1"`)
+	assert.Empty(t, second.Header.Get("Link"))
+	assert.Equal(t, http.StatusBadRequest, invalid.StatusCode)
+	assert.Contains(t, invalid.body, `"code":"invalid_query"`)
+}
+
+func TestEndToEnd_shouldStreamProducts(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	ndjson := doRequest(t, "GET", testServer.URL+"/pages/1/products", "", "Accept", "application/x-ndjson")
+	array := doRequest(t, "GET", testServer.URL+"/pages/1/products?stream=true", "")
+	notFound := doRequest(t, "GET", testServer.URL+"/pages/404/products?stream=true", "")
+
+	assert.Equal(t, http.StatusOK, ndjson.StatusCode)
+	assert.Equal(t, "application/x-ndjson", ndjson.Header.Get("Content-Type"))
+	lines := strings.Split(strings.TrimSuffix(ndjson.body, "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"Name":"name2"`)
+	assert.Equal(t, http.StatusOK, array.StatusCode)
+	assert.JSONEq(t, "["+strings.Join(lines, ",")+"]", array.body)
+	assert.Equal(t, http.StatusNotFound, notFound.StatusCode)
+}
+
+func TestEndToEnd_shouldQueryPageWithGraphQL(t *testing.T) {
+	testServer := newEndToEndServer(t)
+
+	response := doRequest(t, "POST", testServer.URL+"/graphql",
+		`{"query": "{ page(id: 1) { seo { title } products(minPrice: 10) { name price } } }"}`)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"data":{"page":{"seo":{"title":"title1"},"products":[{"name":"name2","price":20.99}]}}}`, response.body)
+}
+
+type testResponse struct {
+	*http.Response
+	body string
+}
+
+// doRequest sends request with body and headers given as name and value pairs.
+func doRequest(t *testing.T, method string, url string, body string, headers ...string) testResponse {
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return testResponse{Response: response, body: string(responseBody)}
+}
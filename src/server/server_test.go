@@ -1,10 +1,19 @@
 package server
 
 import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/remikj/pages-ms/src/contoller"
+	"github.com/remikj/pages-ms/src/grpcapi"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/openapi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestNewServerFromEnv_shouldInitializeServerFromEnv_whenEnvsValid(t *testing.T) {
@@ -14,6 +23,8 @@ func TestNewServerFromEnv_shouldInitializeServerFromEnv_whenEnvsValid(t *testing
 	assert.NoError(t, err)
 	require.NotNil(t, config)
 	assert.Equal(t, 8081, config.Port)
+	assert.Equal(t, 15*time.Second, config.ShutdownTimeout)
+	assert.Equal(t, 10*time.Second, config.RequestTimeout)
 }
 
 func TestNewServerFromEnv_shouldInitializeDefaultServerFromEnv_whenEnvsNotSet(t *testing.T) {
@@ -33,3 +44,208 @@ func TestNewServerFromEnv_shouldReturnErr_whenEnvsInvalid(t *testing.T) {
 	assert.Nil(t, server)
 	require.Error(t, err)
 }
+
+func TestConfigurationFromEnv_shouldLoadRateLimit(t *testing.T) {
+	t.Setenv("RATE_LIMIT_RATE", "10")
+	t.Setenv("RATE_LIMIT_ROUTES", "POST /pages=1,5")
+	t.Setenv("RATE_LIMIT_KEY", "api_key")
+	t.Setenv("RATE_LIMIT_API_KEYS", "k1,k2")
+	t.Setenv("RATE_LIMIT_TRUSTED_PROXIES", "10.0.0.0/8,192.0.2.1")
+
+	config, err := ConfigurationFromEnv()
+
+	require.NoError(t, err)
+	assert.Equal(t, RateLimitConfiguration{
+		Rate:           10,
+		Burst:          20,
+		Routes:         RouteLimits{"POST /pages": {Rate: 1, Burst: 5}},
+		Key:            RateLimitKeyAPIKey,
+		APIKeys:        []string{"k1", "k2"},
+		TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"},
+		MaxBuckets:     10000,
+	}, config.RateLimit)
+}
+
+func TestConfigurationFromEnv_shouldReturnErr_whenRateLimitInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		envs        map[string]string
+		expectedErr string
+	}{
+		{
+			name:        "should fail, when key unknown",
+			envs:        map[string]string{"RATE_LIMIT_KEY": "cookie"},
+			expectedErr: `unknown RATE_LIMIT_KEY "cookie", expected ip, api_key or header`,
+		},
+		{
+			name:        "should fail, when header not set",
+			envs:        map[string]string{"RATE_LIMIT_KEY": "header"},
+			expectedErr: "RATE_LIMIT_HEADER has to be set, when RATE_LIMIT_KEY is header",
+		},
+		{
+			name:        "should fail, when trusted proxies not set for header",
+			envs:        map[string]string{"RATE_LIMIT_KEY": "header", "RATE_LIMIT_HEADER": "X-Client-Id"},
+			expectedErr: "RATE_LIMIT_TRUSTED_PROXIES has to be set, when RATE_LIMIT_KEY is header",
+		},
+		{
+			name:        "should fail, when api keys not set",
+			envs:        map[string]string{"RATE_LIMIT_KEY": "api_key"},
+			expectedErr: "RATE_LIMIT_API_KEYS has to be set, when RATE_LIMIT_KEY is api_key",
+		},
+		{
+			name:        "should fail, when max buckets below 1",
+			envs:        map[string]string{"RATE_LIMIT_MAX_BUCKETS": "0"},
+			expectedErr: "RATE_LIMIT_MAX_BUCKETS has to be at least 1, got 0",
+		},
+		{
+			name:        "should fail, when route limit invalid",
+			envs:        map[string]string{"RATE_LIMIT_ROUTES": "/pages"},
+			expectedErr: `envconfig.Process: assigning RATE_LIMIT_ROUTES to Routes: converting '/pages' to type server.RouteLimits. details: invalid route limit "/pages", expected route=rate,burst`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.envs {
+				t.Setenv(name, value)
+			}
+
+			config, err := ConfigurationFromEnv()
+
+			assert.Nil(t, config)
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestServer_Run_shouldStopAndFailReadiness_whenContextDone(t *testing.T) {
+	healthController := &healthControllerMock{}
+	server := newTestServer(&Configuration{Port: 0, ShutdownTimeout: time.Second}, healthController)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Run(ctx)
+	}()
+	cancelFunc()
+
+	select {
+	case err := <-errChan:
+		assert.NoError(t, err)
+		assert.True(t, healthController.shuttingDown)
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestServer_Run_shouldStopGrpcServer_whenContextDone(t *testing.T) {
+	server := newTestServer(&Configuration{Port: 0, ShutdownTimeout: time.Second}, &healthControllerMock{})
+	server.GrpcServer = grpcapi.NewServer(&grpcapi.Configuration{Port: 0, MaxBatchSize: 50}, nil, logging.Discard())
+	ctx, cancelFunc := context.WithCancel(context.Background())
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Run(ctx)
+	}()
+	cancelFunc()
+
+	select {
+	case err := <-errChan:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestServer_Run_shouldReturnErr_whenGrpcServerCanNotStart(t *testing.T) {
+	server := newTestServer(&Configuration{Port: 0, ShutdownTimeout: time.Second}, &healthControllerMock{})
+	server.GrpcServer = grpcapi.NewServer(&grpcapi.Configuration{Port: -1, MaxBatchSize: 50}, nil, logging.Discard())
+
+	err := server.Run(context.Background())
+
+	assert.ErrorContains(t, err, "failed to start grpc server")
+}
+
+func TestServer_Run_shouldReturnErr_whenServerCanNotStart(t *testing.T) {
+	server := newTestServer(&Configuration{Port: -1, ShutdownTimeout: time.Second}, &healthControllerMock{})
+
+	err := server.Run(context.Background())
+
+	assert.Error(t, err)
+}
+
+func TestServer_Router_shouldDocumentEveryRoute(t *testing.T) {
+	server := newTestServer(&Configuration{}, &healthControllerMock{})
+	router, err := server.Router()
+	require.NoError(t, err)
+	spec, err := openapi.Spec()
+	require.NoError(t, err)
+
+	err = chi.Walk(router.(chi.Routes), func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		pathItem := spec.Paths.Find(route)
+		if assert.NotNil(t, pathItem, "route %v is not documented", route) {
+			assert.NotNil(t, pathItem.GetOperation(method), "operation %v %v is not documented", method, route)
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestServer_Router_shouldNotSetRequestDeadline_whenProductsStreamed(t *testing.T) {
+	tests := []struct {
+		name             string
+		accept           string
+		expectedDeadline bool
+	}{
+		{name: "should not set deadline, when products streamed", accept: "application/x-ndjson", expectedDeadline: false},
+		{name: "should set deadline, when products not streamed", accept: "application/json", expectedDeadline: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(&Configuration{RequestTimeout: time.Second}, &healthControllerMock{})
+			productController := &productControllerMock{}
+			server.ProductController = productController
+			router, err := server.Router()
+			require.NoError(t, err)
+			request := httptest.NewRequest(http.MethodGet, "/pages/1/products", nil)
+			request.Header.Set("Accept", tt.accept)
+
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			assert.Equal(t, tt.expectedDeadline, productController.hasDeadline)
+		})
+	}
+}
+
+func newTestServer(config *Configuration, healthController contoller.HealthController) *Server {
+	return NewServer(
+		config,
+		contoller.NewPageController(&contoller.PageConfiguration{MaxBatchSize: 50}, nil, logging.Discard()),
+		contoller.NewProductController(&contoller.ProductConfiguration{}, nil, logging.Discard()),
+		healthController,
+		contoller.NewGraphQLController(nil, logging.Discard()),
+		nil,
+		logging.Discard(),
+	)
+}
+
+type productControllerMock struct {
+	contoller.ProductController
+	hasDeadline bool
+}
+
+func (p *productControllerMock) HandleProductsGet(_ http.ResponseWriter, request *http.Request) {
+	_, p.hasDeadline = request.Context().Deadline()
+}
+
+type healthControllerMock struct {
+	shuttingDown bool
+}
+
+func (h *healthControllerMock) HandleLiveness(_ http.ResponseWriter, _ *http.Request) {}
+
+func (h *healthControllerMock) HandleReadiness(_ http.ResponseWriter, _ *http.Request) {}
+
+func (h *healthControllerMock) SetShuttingDown() {
+	h.shuttingDown = true
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// timeoutMiddleware sets deadline on request context, so queries made for the request are cancelled
+// once timeout passes. Handlers are responsible for responding when the deadline is exceeded.
+// Timeout of 0 or less disables the deadline. Requests for which exempt returns true get no deadline,
+// exempt may be nil.
+func timeoutMiddleware(timeout time.Duration, exempt func(request *http.Request) bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if exempt != nil && exempt(request) {
+				next.ServeHTTP(writer, request)
+				return
+			}
+			ctx, cancel := context.WithTimeout(request.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(writer, request.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	tests := []struct {
+		name             string
+		timeout          time.Duration
+		exempt           func(request *http.Request) bool
+		expectedDeadline bool
+	}{
+		{name: "should set deadline on request context", timeout: time.Second, expectedDeadline: true},
+		{name: "should not set deadline, when timeout disabled", timeout: 0, expectedDeadline: false},
+		{
+			name:             "should not set deadline, when request is exempt",
+			timeout:          time.Second,
+			exempt:           func(request *http.Request) bool { return true },
+			expectedDeadline: false,
+		},
+		{
+			name:             "should set deadline, when request is not exempt",
+			timeout:          time.Second,
+			exempt:           func(request *http.Request) bool { return false },
+			expectedDeadline: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var hasDeadline bool
+			handler := timeoutMiddleware(tt.timeout, tt.exempt)(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+				deadline, hasDeadline = request.Context().Deadline()
+			}))
+
+			requestStart := time.Now()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/pages/1", nil))
+
+			assert.Equal(t, tt.expectedDeadline, hasDeadline)
+			if tt.expectedDeadline {
+				assert.WithinDuration(t, requestStart.Add(tt.timeout), deadline, 100*time.Millisecond)
+			}
+		})
+	}
+}
@@ -1,39 +1,197 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/metrics"
 	"github.com/remikj/pages-ms/src/model"
 	"github.com/remikj/pages-ms/src/repository"
+	"log/slog"
+	"time"
 )
 
+var (
+	getPageTotal = metrics.NewCounterVec(
+		"page_service_get_page_total",
+		"Total number of pages retrieved by outcome: found, partial, not_found or error.",
+		"outcome",
+	)
+	degradedPageTotal = metrics.NewCounterVec(
+		"page_service_degraded_page_total",
+		"Total number of pages returned without a dependency, by dependency and reason: timeout or error.",
+		"dependency", "reason",
+	)
+)
+
+const (
+	// DegradationNone fails getting the page when any of its queries fails.
+	DegradationNone = "none"
+	// DegradationOmitProducts returns page without products, marked as partial, when products query fails
+	// or exceeds its budget. Seo is still required.
+	DegradationOmitProducts = "omit_products"
+)
+
+// Configuration of getting single pages. SEOTimeout and ProductsTimeout are budgets of seo and products
+// queries, which run concurrently, 0 leaves a query bounded only by the request deadline.
+// Budgets and degradation do not apply when the whole page is read with a single query.
+// Pages are read with at most EmbeddedProductsLimit products with the lowest ids, the rest is left
+// to products queries, 0 reads all of them.
+type Configuration struct {
+	SEOTimeout            time.Duration `envconfig:"PAGE_SEO_TIMEOUT" default:"0s"`
+	ProductsTimeout       time.Duration `envconfig:"PAGE_PRODUCTS_TIMEOUT" default:"0s"`
+	Degradation           string        `envconfig:"PAGE_DEGRADATION" default:"none"`
+	EmbeddedProductsLimit int           `envconfig:"PAGE_EMBEDDED_PRODUCTS_LIMIT" default:"1000"`
+}
+
 type PageService interface {
-	GetPage(pageId int) (*model.Page, error)
+	GetPage(ctx context.Context, pageId int) (*model.Page, error)
+	GetPageFields(ctx context.Context, pageId int, fields *model.PageFields) (*model.Page, error)
+	GetPages(ctx context.Context, pageIds []int) (map[int]*model.Page, error)
+	CreatePage(ctx context.Context, page *model.Page) (*model.Page, error)
+	ReplacePage(ctx context.Context, page *model.Page) (*model.Page, error)
+	PatchPage(ctx context.Context, pageId int, patch model.PagePatch) (*model.Page, error)
+	DeletePage(ctx context.Context, pageId int) error
+	// GetProducts returns products read with the page, Next is set when the page has more of them.
+	GetProducts(ctx context.Context, pageId int) (*model.ProductList, error)
+	GetProduct(ctx context.Context, pageId int, productId int) (*model.Product, error)
+	QueryProducts(ctx context.Context, pageId int, query *model.ProductQuery, count bool) (*model.ProductList, error)
+	// StreamProducts calls yield for each product of the page as it is read, without reading all of them first.
+	// ErrPageNotFound is returned before yield is called, when the page does not exist.
+	StreamProducts(ctx context.Context, pageId int, yield func(product *model.Product) error) error
+	CreateProduct(ctx context.Context, pageId int, product *model.Product) (*model.Product, error)
+	ReplaceProduct(ctx context.Context, pageId int, product *model.Product) (*model.Product, error)
+	PatchProduct(ctx context.Context, pageId int, productId int, patch model.ProductPatch) (*model.Product, error)
+	DeleteProduct(ctx context.Context, pageId int, productId int) error
 }
 
+// PageServiceImpl gets pages with PageRepositoryAsync. Zero Config sets no budgets and no degradation.
 type PageServiceImpl struct {
+	Config              Configuration
 	PageRepositoryAsync repository.PageRepositoryAsync
+	Logger              *slog.Logger
 }
 
-func NewPageService(pageRepositoryAsync repository.PageRepositoryAsync) *PageServiceImpl {
+func NewPageServiceFromEnv(pageRepositoryAsync repository.PageRepositoryAsync, logger *slog.Logger) (*PageServiceImpl, error) {
+	config, err := ConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewPageService(config, pageRepositoryAsync, logger), nil
+}
+
+func NewPageService(config *Configuration, pageRepositoryAsync repository.PageRepositoryAsync, logger *slog.Logger) *PageServiceImpl {
 	return &PageServiceImpl{
+		Config:              *config,
 		PageRepositoryAsync: pageRepositoryAsync,
+		Logger:              logger,
 	}
 }
 
-func (ps *PageServiceImpl) GetPage(pageId int) (*model.Page, error) {
-	fmt.Printf("Getting page for id: %v\n", pageId)
-	seoChan, getSeoCancelFunc := ps.PageRepositoryAsync.GetSeoForPage(pageId)
+func ConfigurationFromEnv() (*Configuration, error) {
+	config := &Configuration{}
+	if err := envconfig.Process("", config); err != nil {
+		return nil, err
+	}
+	if config.Degradation != DegradationNone && config.Degradation != DegradationOmitProducts {
+		return nil, fmt.Errorf("unknown PAGE_DEGRADATION %q, expected %v or %v", config.Degradation, DegradationNone, DegradationOmitProducts)
+	}
+	if config.EmbeddedProductsLimit < 0 {
+		return nil, fmt.Errorf("PAGE_EMBEDDED_PRODUCTS_LIMIT can not be negative, got %v", config.EmbeddedProductsLimit)
+	}
+	return config, nil
+}
+
+// GetPage gets page with at most EmbeddedProductsLimit products, ProductsNext of the page is set when it has more.
+func (ps *PageServiceImpl) GetPage(ctx context.Context, pageId int) (*model.Page, error) {
+	page, err := ps.getPage(ctx, pageId, ps.Config.EmbeddedProductsLimit)
+	countGetPage(page, err)
+	return page, err
+}
+
+func countGetPage(page *model.Page, err error) {
+	switch {
+	case err != nil:
+		getPageTotal.Inc("error")
+	case page == nil:
+		getPageTotal.Inc("not_found")
+	case page.Partial:
+		getPageTotal.Inc("partial")
+	default:
+		getPageTotal.Inc("found")
+	}
+}
+
+// getPage gets page with at most productsLimit products, or with all of them when productsLimit is 0.
+// One more product is read to find out whether the page has more of them.
+func (ps *PageServiceImpl) getPage(ctx context.Context, pageId int, productsLimit int) (*model.Page, error) {
+	ps.Logger.Debug("getting page", "page_id", pageId, "products_limit", productsLimit)
+	if pageAssembler, ok := ps.PageRepositoryAsync.(repository.PageAssemblerAsync); ok {
+		return getAssembledPage(ctx, pageAssembler, pageId, productsLimit)
+	}
+	seoCtx, seoCancelFunc := withBudget(ctx, ps.Config.SEOTimeout)
+	defer seoCancelFunc()
+	seoChan, getSeoCancelFunc := ps.PageRepositoryAsync.GetSeoForPage(seoCtx, pageId)
 	defer getSeoCancelFunc()
-	productsChan, getProductsCancelFunc := ps.PageRepositoryAsync.GetProductsForPage(pageId)
+	productsCtx, productsCancelFunc := withBudget(ctx, ps.Config.ProductsTimeout)
+	defer productsCancelFunc()
+	productsChan, getProductsCancelFunc := ps.getProducts(productsCtx, pageId, productsLimit)
 	defer getProductsCancelFunc()
+	return ps.awaitPage(ctx, &pageQueries{
+		pageId:        pageId,
+		seoCtx:        seoCtx,
+		seoChan:       seoChan,
+		productsCtx:   productsCtx,
+		productsChan:  productsChan,
+		productsLimit: productsLimit,
+	})
+}
 
-	seoReceived, productsReceived := false, false
+// getProducts queries all products of the page, or limit of them and one more ordered by id.
+func (ps *PageServiceImpl) getProducts(ctx context.Context, pageId int, limit int) (<-chan repository.ResultProducts, context.CancelFunc) {
+	if limit <= 0 {
+		return ps.PageRepositoryAsync.GetProductsForPage(ctx, pageId)
+	}
+	return ps.PageRepositoryAsync.QueryProductsForPage(ctx, pageId, &model.ProductQuery{Limit: limit + 1, Sort: model.SortById})
+}
+
+// pageQueries are outstanding seo and products queries of the page, each bounded by its own context.
+// productsChan is nil when products are not queried. Products over productsLimit are dropped, if it is above 0.
+type pageQueries struct {
+	pageId        int
+	seoCtx        context.Context
+	seoChan       <-chan repository.ResultSEO
+	productsCtx   context.Context
+	productsChan  <-chan repository.ResultProducts
+	productsLimit int
+}
+
+// withBudget returns context of a single query, which is cancelled once timeout passes.
+// Timeout of 0 or less leaves the query bounded only by ctx.
+func withBudget(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// awaitPage assembles page from seo and products results. Page has no products when they are not queried.
+// When products query fails or exceeds its budget and products can be omitted, the page is returned
+// without them and marked as partial, once seo is received.
+func (ps *PageServiceImpl) awaitPage(ctx context.Context, queries *pageQueries) (*model.Page, error) {
+	seoReceived, productsReceived := false, queries.productsChan == nil
+	seoBudget, productsBudget := queries.seoCtx.Done(), (<-chan struct{})(nil)
+	if !productsReceived {
+		productsBudget = queries.productsCtx.Done()
+	}
 	page := model.Page{
 		Products: []model.Product{},
 	}
+	var productsErr error
 	for !seoReceived || !productsReceived {
 		select {
-		case seoResult := <-seoChan:
+		case seoResult := <-queries.seoChan:
 			if seoResult.Err != nil {
 				return nil, seoResult.Err
 			}
@@ -41,16 +199,206 @@ func (ps *PageServiceImpl) GetPage(pageId int) (*model.Page, error) {
 				return nil, nil
 			}
 			page.SEO = *seoResult.SEO
-			seoReceived = true
+			seoReceived, seoBudget = true, nil
+		case productsResult := <-queries.productsChan:
+			productsErr = productsResult.Err
+			if productsResult.Products != nil {
+				page.Products = productsResult.Products
+			}
+			productsReceived, productsBudget = true, nil
+		case <-seoBudget:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("seo query exceeded its budget of %v: %w", ps.Config.SEOTimeout, queries.seoCtx.Err())
+		case <-productsBudget:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			productsErr = fmt.Errorf("products query exceeded its budget of %v: %w", ps.Config.ProductsTimeout, queries.productsCtx.Err())
+			productsReceived, productsBudget = true, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if productsErr != nil && (ps.Config.Degradation != DegradationOmitProducts || ctx.Err() != nil) {
+			return nil, productsErr
+		}
+	}
+	if productsErr != nil {
+		reason := "error"
+		if errors.Is(productsErr, context.DeadlineExceeded) {
+			reason = "timeout"
+		}
+		degradedPageTotal.Inc("products", reason)
+		ps.Logger.Warn("returning page without products", "page_id", queries.pageId, "reason", reason, "error", productsErr)
+		page.Products = []model.Product{}
+		page.Partial = true
+	}
+	page.CapProducts(queries.productsLimit)
+	return &page, nil
+}
+
+// GetPageFields gets page with only selected fields read from the repository, other fields have zero values.
+// Products are not queried when none of their fields is selected. Pages read this way are never assembled
+// with a single query, as seo and products are projected separately. When products are limited, they are
+// read with all fields, which are left to be dropped by the representation of the page.
+func (ps *PageServiceImpl) GetPageFields(ctx context.Context, pageId int, fields *model.PageFields) (*model.Page, error) {
+	page, err := ps.getPageFields(ctx, pageId, fields)
+	countGetPage(page, err)
+	return page, err
+}
+
+func (ps *PageServiceImpl) getPageFields(ctx context.Context, pageId int, fields *model.PageFields) (*model.Page, error) {
+	ps.Logger.Debug("getting page fields", "page_id", pageId, "seo_fields", fields.SEO, "product_fields", fields.Products)
+	seoCtx, seoCancelFunc := withBudget(ctx, ps.Config.SEOTimeout)
+	defer seoCancelFunc()
+	seoChan, getSeoCancelFunc := ps.PageRepositoryAsync.GetSeoFieldsForPage(seoCtx, pageId, fields.SEO)
+	defer getSeoCancelFunc()
+	queries := &pageQueries{pageId: pageId, seoCtx: seoCtx, seoChan: seoChan}
+	if fields.Products == nil {
+		return ps.awaitPage(ctx, queries)
+	}
+	productsCtx, productsCancelFunc := withBudget(ctx, ps.Config.ProductsTimeout)
+	defer productsCancelFunc()
+	var productsChan <-chan repository.ResultProducts
+	var getProductsCancelFunc context.CancelFunc
+	if limit := ps.Config.EmbeddedProductsLimit; limit > 0 {
+		productsChan, getProductsCancelFunc = ps.getProducts(productsCtx, pageId, limit)
+	} else {
+		productsChan, getProductsCancelFunc = ps.PageRepositoryAsync.GetProductFieldsForPage(productsCtx, pageId, fields.Products)
+	}
+	defer getProductsCancelFunc()
+	queries.productsCtx, queries.productsChan = productsCtx, productsChan
+	queries.productsLimit = ps.Config.EmbeddedProductsLimit
+	return ps.awaitPage(ctx, queries)
+}
+
+// getAssembledPage gets the whole page with a single query, with at most productsLimit products.
+func getAssembledPage(ctx context.Context, pageAssembler repository.PageAssemblerAsync, pageId int, productsLimit int) (*model.Page, error) {
+	queriedLimit := productsLimit
+	if queriedLimit > 0 {
+		queriedLimit++
+	}
+	pageChan, cancelFunc := pageAssembler.GetPage(ctx, pageId, queriedLimit)
+	defer cancelFunc()
+	select {
+	case pageResult := <-pageChan:
+		if pageResult.Page != nil {
+			pageResult.Page.CapProducts(productsLimit)
+		}
+		return pageResult.Page, pageResult.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GetPages gets pages with one seos and one products query for all of them, each page with at most
+// EmbeddedProductsLimit products. Returned map contains only pages that exist.
+func (ps *PageServiceImpl) GetPages(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+	pages, err := ps.getPages(ctx, pageIds)
+	for _, pageId := range pageIds {
+		countGetPage(pages[pageId], err)
+	}
+	return pages, err
+}
+
+func (ps *PageServiceImpl) getPages(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+	ps.Logger.Debug("getting pages", "page_ids", pageIds)
+	seosChan, getSeosCancelFunc := ps.PageRepositoryAsync.GetSeosForPages(ctx, pageIds)
+	defer getSeosCancelFunc()
+	productsLimit := ps.Config.EmbeddedProductsLimit
+	if productsLimit > 0 {
+		productsLimit++
+	}
+	productsChan, getProductsCancelFunc := ps.PageRepositoryAsync.GetProductsForPages(ctx, pageIds, productsLimit)
+	defer getProductsCancelFunc()
+
+	var seos []model.SEO
+	var products []model.Product
+	seosReceived, productsReceived := false, false
+	for !seosReceived || !productsReceived {
+		select {
+		case seosResult := <-seosChan:
+			if seosResult.Err != nil {
+				return nil, seosResult.Err
+			}
+			seos = seosResult.SEOs
+			seosReceived = true
 		case productsResult := <-productsChan:
 			if productsResult.Err != nil {
 				return nil, productsResult.Err
 			}
-			if productsResult.Products != nil {
-				page.Products = productsResult.Products
-			}
+			products = productsResult.Products
 			productsReceived = true
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
-	return &page, nil
+
+	pages := make(map[int]*model.Page, len(seos))
+	for _, seo := range seos {
+		pages[seo.PageId] = &model.Page{SEO: seo, Products: []model.Product{}}
+	}
+	for _, product := range products {
+		if page, ok := pages[product.PageId]; ok {
+			page.Products = append(page.Products, product)
+		}
+	}
+	for _, page := range pages {
+		page.CapProducts(ps.Config.EmbeddedProductsLimit)
+	}
+	return pages, nil
+}
+
+func (ps *PageServiceImpl) CreatePage(ctx context.Context, page *model.Page) (*model.Page, error) {
+	ps.Logger.Debug("creating page", "page_id", page.SEO.PageId)
+	if err := page.Validate(); err != nil {
+		return nil, err
+	}
+	page.Normalize()
+	if err := awaitWrite(ps.PageRepositoryAsync.CreatePage(ctx, page)); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (ps *PageServiceImpl) ReplacePage(ctx context.Context, page *model.Page) (*model.Page, error) {
+	ps.Logger.Debug("replacing page", "page_id", page.SEO.PageId)
+	if err := page.Validate(); err != nil {
+		return nil, err
+	}
+	page.Normalize()
+	if err := awaitWrite(ps.PageRepositoryAsync.ReplacePage(ctx, page)); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// PatchPage reads the page with all its products, as products missing from the read page would be removed.
+func (ps *PageServiceImpl) PatchPage(ctx context.Context, pageId int, patch model.PagePatch) (*model.Page, error) {
+	ps.Logger.Debug("patching page", "page_id", pageId)
+	page, err := ps.getPage(ctx, pageId, 0)
+	countGetPage(page, err)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		return nil, model.ErrPageNotFound
+	}
+	// Page without its products would replace them with none.
+	if page.Partial {
+		return nil, fmt.Errorf("%w: products of the page could not be read", model.ErrBackendUnavailable)
+	}
+	page.ApplyPatch(patch)
+	return ps.ReplacePage(ctx, page)
+}
+
+func (ps *PageServiceImpl) DeletePage(ctx context.Context, pageId int) error {
+	ps.Logger.Debug("deleting page", "page_id", pageId)
+	return awaitWrite(ps.PageRepositoryAsync.DeletePage(ctx, pageId))
+}
+
+func awaitWrite(errChan <-chan error, cancelFunc context.CancelFunc) error {
+	defer cancelFunc()
+	return <-errChan
 }
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"github.com/remikj/pages-ms/src/logging"
 	"github.com/remikj/pages-ms/src/model"
 	"github.com/remikj/pages-ms/src/repository"
 	"github.com/stretchr/testify/assert"
@@ -107,14 +108,31 @@ func TestPageServiceImpl_GetPage(t *testing.T) {
 			pageId:     0,
 			epectedErr: sampleProductsError,
 		},
+		{
+			name: "should get page with single query when repository assembles pages",
+			repository: pageAssemblerAsyncMock{
+				GetPageFunc: createGetAssembledPageFunc(&sampleModelPage, nil),
+			},
+			pageId:       0,
+			expectedPage: &sampleModelPage,
+		},
+		{
+			name: "should return error when repository fails to assemble page",
+			repository: pageAssemblerAsyncMock{
+				GetPageFunc: createGetAssembledPageFunc(nil, sampleSeoError),
+			},
+			pageId:     0,
+			epectedErr: sampleSeoError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ps := &PageServiceImpl{
+				Logger:              logging.Discard(),
 				PageRepositoryAsync: tt.repository,
 			}
 
-			resultPage, err := ps.GetPage(tt.pageId)
+			resultPage, err := ps.GetPage(context.Background(), tt.pageId)
 
 			if tt.epectedErr != nil {
 				assert.Error(t, err)
@@ -128,8 +146,225 @@ func TestPageServiceImpl_GetPage(t *testing.T) {
 	}
 }
 
+func TestPageServiceImpl_GetPage_shouldCountOutcomes(t *testing.T) {
+	foundBefore, notFoundBefore := getPageTotal.Value("found"), getPageTotal.Value("not_found")
+	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoForPageFunc:      createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 0),
+			GetProductsForPageFunc: createGetProductsForPageFunc(sampleModelPage.Products, nil, 0),
+		},
+	}
+	psNotFound := &PageServiceImpl{
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoForPageFunc:      createGetSeoForPageFunc(nil, nil, 0),
+			GetProductsForPageFunc: createGetProductsForPageFunc(nil, nil, 0),
+		},
+	}
+
+	_, _ = ps.GetPage(context.Background(), 0)
+	_, _ = psNotFound.GetPage(context.Background(), 0)
+
+	assert.Equal(t, foundBefore+1, getPageTotal.Value("found"))
+	assert.Equal(t, notFoundBefore+1, getPageTotal.Value("not_found"))
+}
+
+func TestPageServiceImpl_GetPage_shouldDegrade(t *testing.T) {
+	partialPage := &model.Page{SEO: sampleModelPage.SEO, Products: []model.Product{}, Partial: true}
+	tests := []struct {
+		name           string
+		config         Configuration
+		seoFunc        func(pageId int) (<-chan repository.ResultSEO, context.CancelFunc)
+		productsFunc   func(pageId int) (<-chan repository.ResultProducts, context.CancelFunc)
+		expectedPage   *model.Page
+		expectedErr    error
+		expectedReason string
+	}{
+		{
+			name:           "should return page without products, when products fail and can be omitted",
+			config:         Configuration{Degradation: DegradationOmitProducts},
+			seoFunc:        createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 10*time.Millisecond),
+			productsFunc:   createGetProductsForPageFunc(nil, sampleProductsError, 0),
+			expectedPage:   partialPage,
+			expectedReason: "error",
+		},
+		{
+			name:           "should return page without products, when products exceed their budget",
+			config:         Configuration{ProductsTimeout: 10 * time.Millisecond, Degradation: DegradationOmitProducts},
+			seoFunc:        createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 0),
+			productsFunc:   createGetProductsForPageFunc(sampleModelPage.Products, nil, time.Second),
+			expectedPage:   partialPage,
+			expectedReason: "timeout",
+		},
+		{
+			name:         "should return whole page, when products are read within their budget",
+			config:       Configuration{ProductsTimeout: time.Second, Degradation: DegradationOmitProducts},
+			seoFunc:      createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 0),
+			productsFunc: createGetProductsForPageFunc(sampleModelPage.Products, nil, 0),
+			expectedPage: &sampleModelPage,
+		},
+		{
+			name:         "should return nil, when page does not exist and products fail",
+			config:       Configuration{Degradation: DegradationOmitProducts},
+			seoFunc:      createGetSeoForPageFunc(nil, nil, 10*time.Millisecond),
+			productsFunc: createGetProductsForPageFunc(nil, sampleProductsError, 0),
+		},
+		{
+			name:         "should fail, when products exceed their budget and can not be omitted",
+			config:       Configuration{ProductsTimeout: 10 * time.Millisecond, Degradation: DegradationNone},
+			seoFunc:      createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 0),
+			productsFunc: createGetProductsForPageFunc(sampleModelPage.Products, nil, time.Second),
+			expectedErr:  context.DeadlineExceeded,
+		},
+		{
+			name:         "should fail, when seo exceeds its budget",
+			config:       Configuration{SEOTimeout: 10 * time.Millisecond, Degradation: DegradationOmitProducts},
+			seoFunc:      createGetSeoForPageFunc(&sampleModelPage.SEO, nil, time.Second),
+			productsFunc: createGetProductsForPageFunc(sampleModelPage.Products, nil, 0),
+			expectedErr:  context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			degradedBefore := degradedPageTotal.Value("products", tt.expectedReason)
+			ps := &PageServiceImpl{
+				Config: tt.config,
+				Logger: logging.Discard(),
+				PageRepositoryAsync: pageRepositoryAsyncMock{
+					GetSeoForPageFunc:      tt.seoFunc,
+					GetProductsForPageFunc: tt.productsFunc,
+				},
+			}
+
+			testStart := time.Now()
+			page, err := ps.GetPage(context.Background(), 0)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expectedPage, page)
+			assert.Less(t, time.Since(testStart), 500*time.Millisecond)
+			if tt.expectedReason != "" {
+				assert.Equal(t, degradedBefore+1, degradedPageTotal.Value("products", tt.expectedReason))
+			}
+		})
+	}
+}
+
+func TestConfigurationFromEnv_shouldReturnErr_whenDegradationUnknown(t *testing.T) {
+	t.Setenv("PAGE_DEGRADATION", "omit_seo")
+
+	_, err := ConfigurationFromEnv()
+
+	assert.EqualError(t, err, `unknown PAGE_DEGRADATION "omit_seo", expected none or omit_products`)
+}
+
+func TestConfigurationFromEnv_shouldReturnErr_whenEmbeddedProductsLimitNegative(t *testing.T) {
+	t.Setenv("PAGE_EMBEDDED_PRODUCTS_LIMIT", "-1")
+
+	_, err := ConfigurationFromEnv()
+
+	assert.EqualError(t, err, "PAGE_EMBEDDED_PRODUCTS_LIMIT can not be negative, got -1")
+}
+
+func TestPageServiceImpl_GetPage_shouldCapProducts_whenOverEmbeddedProductsLimit(t *testing.T) {
+	var queried *model.ProductQuery
+	ps := &PageServiceImpl{
+		Config: Configuration{EmbeddedProductsLimit: 1},
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoForPageFunc: createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 0),
+			QueryProductsForPageFunc: func(pageId int, query *model.ProductQuery) (<-chan repository.ResultProducts, context.CancelFunc) {
+				queried = query
+				return createGetProductsForPageFunc(sampleModelPage.Products, nil, 0)(pageId)
+			},
+		},
+	}
+
+	page, err := ps.GetPage(context.Background(), 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.ProductQuery{Limit: 2, Sort: model.SortById}, queried)
+	assert.Equal(t, sampleModelPage.Products[:1], page.Products)
+	assert.Equal(t, model.CursorAfter(model.SortById, &sampleModelPage.Products[0]), page.ProductsNext)
+}
+
+func TestPageServiceImpl_GetPage_shouldNotSetProductsNext_whenWithinEmbeddedProductsLimit(t *testing.T) {
+	ps := &PageServiceImpl{
+		Config: Configuration{EmbeddedProductsLimit: 2},
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoForPageFunc: createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 0),
+			QueryProductsForPageFunc: func(pageId int, query *model.ProductQuery) (<-chan repository.ResultProducts, context.CancelFunc) {
+				return createGetProductsForPageFunc(sampleModelPage.Products, nil, 0)(pageId)
+			},
+		},
+	}
+
+	page, err := ps.GetPage(context.Background(), 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, sampleModelPage.Products, page.Products)
+	assert.Nil(t, page.ProductsNext)
+}
+
+func TestPageServiceImpl_GetPages_shouldCapProductsOfEachPage(t *testing.T) {
+	var queriedLimit int
+	ps := &PageServiceImpl{
+		Config: Configuration{EmbeddedProductsLimit: 1},
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeosForPagesFunc: func(pageIds []int) (<-chan repository.ResultSEOs, context.CancelFunc) {
+				seosChan := make(chan repository.ResultSEOs, 1)
+				seosChan <- repository.ResultSEOs{SEOs: []model.SEO{{PageId: 1}, {PageId: 2}}}
+				return seosChan, func() {}
+			},
+			GetProductsForPagesFunc: func(pageIds []int, limit int) (<-chan repository.ResultProducts, context.CancelFunc) {
+				queriedLimit = limit
+				productsChan := make(chan repository.ResultProducts, 1)
+				productsChan <- repository.ResultProducts{Products: []model.Product{
+					{Id: 0, PageId: 1}, {Id: 1, PageId: 1}, {Id: 0, PageId: 2},
+				}}
+				return productsChan, func() {}
+			},
+		},
+	}
+
+	pages, err := ps.GetPages(context.Background(), []int{1, 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, queriedLimit)
+	assert.Equal(t, []model.Product{{Id: 0, PageId: 1}}, pages[1].Products)
+	assert.Equal(t, model.CursorAfter(model.SortById, &model.Product{Id: 0, PageId: 1}), pages[1].ProductsNext)
+	assert.Equal(t, []model.Product{{Id: 0, PageId: 2}}, pages[2].Products)
+	assert.Nil(t, pages[2].ProductsNext)
+}
+
+func TestPageServiceImpl_PatchPage_shouldReplaceAllProducts_whenOverEmbeddedProductsLimit(t *testing.T) {
+	var replaced *model.Page
+	products := []model.Product{{Id: 1, PageId: 1, Name: "first"}, {Id: 2, PageId: 1, Name: "second"}}
+	ps := &PageServiceImpl{
+		Config: Configuration{EmbeddedProductsLimit: 1},
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoForPageFunc:      createGetSeoForPageFunc(&model.SEO{PageId: 1, Title: "title", Robots: "robots"}, nil, 0),
+			GetProductsForPageFunc: createGetProductsForPageFunc(products, nil, 0),
+			ReplacePageFunc: func(page *model.Page) (<-chan error, context.CancelFunc) {
+				replaced = page
+				return createWritePageFunc(nil)(page)
+			},
+		},
+	}
+	title := "patched title"
+
+	_, err := ps.PatchPage(context.Background(), 1, model.PagePatch{SEO: &model.SEOPatch{Title: &title}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, products, replaced.Products)
+}
+
 func TestPageServiceImpl_GetPage_shouldGetSeoAndProductsAsynchronously(t *testing.T) {
 	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
 		PageRepositoryAsync: pageRepositoryAsyncMock{
 			GetSeoForPageFunc:      createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 100*time.Millisecond),
 			GetProductsForPageFunc: createGetProductsForPageFunc(sampleModelPage.Products, nil, 100*time.Millisecond),
@@ -137,7 +372,7 @@ func TestPageServiceImpl_GetPage_shouldGetSeoAndProductsAsynchronously(t *testin
 	}
 
 	testStart := time.Now()
-	page, err := ps.GetPage(0)
+	page, err := ps.GetPage(context.Background(), 0)
 	testTime := time.Since(testStart)
 
 	assert.NoError(t, err)
@@ -146,18 +381,38 @@ func TestPageServiceImpl_GetPage_shouldGetSeoAndProductsAsynchronously(t *testin
 	assert.Greater(t, testTime, 99*time.Millisecond)
 }
 
+func TestPageServiceImpl_GetPage_shouldReturnContextErr_whenContextDoneBeforeResults(t *testing.T) {
+	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoForPageFunc:      createGetSeoForPageFunc(&sampleModelPage.SEO, nil, time.Second),
+			GetProductsForPageFunc: createGetProductsForPageFunc(sampleModelPage.Products, nil, time.Second),
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	testStart := time.Now()
+	page, err := ps.GetPage(ctx, 0)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, page)
+	assert.Less(t, time.Since(testStart), 500*time.Millisecond)
+}
+
 func TestPageServiceImpl_GetPage_shouldCancelGetSeoImmediately_whenProductsFailFirst(t *testing.T) {
 	seoCancelTimer := &timeStruct{
 		startTime: time.Now(),
 	}
 	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
 		PageRepositoryAsync: pageRepositoryAsyncMock{
 			GetSeoForPageFunc:      createGetSeoForPageFuncWithCancelTime(&sampleModelPage.SEO, nil, 100*time.Millisecond, seoCancelTimer),
 			GetProductsForPageFunc: createGetProductsForPageFunc(nil, sampleProductsError, 10*time.Millisecond),
 		},
 	}
 
-	page, err := ps.GetPage(0)
+	page, err := ps.GetPage(context.Background(), 0)
 
 	assert.Error(t, err)
 	assert.Nil(t, page)
@@ -170,13 +425,14 @@ func TestPageServiceImpl_GetPage_shouldCancelGetProductsImmediately_wheSeoFailsF
 		startTime: time.Now(),
 	}
 	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
 		PageRepositoryAsync: pageRepositoryAsyncMock{
 			GetSeoForPageFunc:      createGetSeoForPageFunc(nil, sampleSeoError, 10*time.Millisecond),
 			GetProductsForPageFunc: createGetProductsForPageFuncWithCancelTime(sampleModelPage.Products, nil, 100*time.Millisecond, seoCancelTimer),
 		},
 	}
 
-	page, err := ps.GetPage(0)
+	page, err := ps.GetPage(context.Background(), 0)
 
 	assert.Error(t, err)
 	assert.Nil(t, page)
@@ -184,6 +440,271 @@ func TestPageServiceImpl_GetPage_shouldCancelGetProductsImmediately_wheSeoFailsF
 	assert.Greater(t, seoCancelTimer.timeToCancel, 9*time.Millisecond)
 }
 
+func TestPageServiceImpl_GetPageFields(t *testing.T) {
+	seo := &model.SEO{Title: sampleModelPage.SEO.Title}
+	products := []model.Product{{Price: 2.50}, {Price: 19.99}}
+	var seoFields, productFields []string
+	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageAssemblerAsyncMock{
+			pageRepositoryAsyncMock: pageRepositoryAsyncMock{
+				GetSeoFieldsForPageFunc: func(pageId int, fields []string) (<-chan repository.ResultSEO, context.CancelFunc) {
+					seoFields = fields
+					return createGetSeoForPageFunc(seo, nil, 0)(pageId)
+				},
+				GetProductFieldsForPageFunc: func(pageId int, fields []string) (<-chan repository.ResultProducts, context.CancelFunc) {
+					productFields = fields
+					return createGetProductsForPageFunc(products, nil, 0)(pageId)
+				},
+			},
+		},
+	}
+
+	page, err := ps.GetPageFields(context.Background(), 0, &model.PageFields{SEO: []string{"Title"}, Products: []string{"Price"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Page{SEO: *seo, Products: products}, page)
+	assert.Equal(t, []string{"Title"}, seoFields)
+	assert.Equal(t, []string{"Price"}, productFields)
+}
+
+func TestPageServiceImpl_GetPageFields_shouldNotQueryProducts_whenNoneOfTheirFieldsSelected(t *testing.T) {
+	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoFieldsForPageFunc: func(pageId int, fields []string) (<-chan repository.ResultSEO, context.CancelFunc) {
+				return createGetSeoForPageFunc(&model.SEO{}, nil, 0)(pageId)
+			},
+		},
+	}
+
+	page, err := ps.GetPageFields(context.Background(), 0, &model.PageFields{SEO: []string{"Title"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Page{Products: []model.Product{}}, page)
+}
+
+func TestPageServiceImpl_GetPages(t *testing.T) {
+	otherProduct := model.Product{Id: 0, PageId: 2, Name: "other product"}
+	tests := []struct {
+		name          string
+		seos          repository.ResultSEOs
+		products      repository.ResultProducts
+		expectedPages map[int]*model.Page
+		expectedErr   error
+	}{
+		{
+			name: "should assemble found pages with their products",
+			seos: repository.ResultSEOs{SEOs: []model.SEO{{PageId: 1, Title: "first"}, {PageId: 2, Title: "second"}}},
+			products: repository.ResultProducts{Products: []model.Product{
+				{Id: 0, PageId: 1, Name: "first product"}, otherProduct, {Id: 1, PageId: 1, Name: "second product"},
+			}},
+			expectedPages: map[int]*model.Page{
+				1: {
+					SEO:      model.SEO{PageId: 1, Title: "first"},
+					Products: []model.Product{{Id: 0, PageId: 1, Name: "first product"}, {Id: 1, PageId: 1, Name: "second product"}},
+				},
+				2: {SEO: model.SEO{PageId: 2, Title: "second"}, Products: []model.Product{otherProduct}},
+			},
+		},
+		{
+			name:          "should return empty map when no seos found",
+			seos:          repository.ResultSEOs{},
+			products:      repository.ResultProducts{Products: []model.Product{otherProduct}},
+			expectedPages: map[int]*model.Page{},
+		},
+		{
+			name:        "should return error when error while getting seos",
+			seos:        repository.ResultSEOs{Err: sampleSeoError},
+			products:    repository.ResultProducts{},
+			expectedErr: sampleSeoError,
+		},
+		{
+			name:        "should return error when error while getting products",
+			seos:        repository.ResultSEOs{SEOs: []model.SEO{{PageId: 1}}},
+			products:    repository.ResultProducts{Err: sampleProductsError},
+			expectedErr: sampleProductsError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &PageServiceImpl{
+				Logger: logging.Discard(),
+				PageRepositoryAsync: pageRepositoryAsyncMock{
+					GetSeosForPagesFunc: func(pageIds []int) (<-chan repository.ResultSEOs, context.CancelFunc) {
+						seosChan := make(chan repository.ResultSEOs, 1)
+						seosChan <- tt.seos
+						return seosChan, func() {}
+					},
+					GetProductsForPagesFunc: func(pageIds []int, limit int) (<-chan repository.ResultProducts, context.CancelFunc) {
+						productsChan := make(chan repository.ResultProducts, 1)
+						productsChan <- tt.products
+						return productsChan, func() {}
+					},
+				},
+			}
+
+			pages, err := ps.GetPages(context.Background(), []int{1, 2, 3})
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedPages, pages)
+		})
+	}
+}
+
+func TestPageServiceImpl_CreatePage(t *testing.T) {
+	tests := []struct {
+		name         string
+		repository   repository.PageRepositoryAsync
+		page         *model.Page
+		expectedPage *model.Page
+		expectedErr  error
+	}{
+		{
+			name: "should create page with products assigned to page",
+			repository: pageRepositoryAsyncMock{
+				CreatePageFunc: createWritePageFunc(nil),
+			},
+			page: &model.Page{
+				SEO:      model.SEO{PageId: 1, Title: "title"},
+				Products: []model.Product{{Id: 1, Name: "name"}},
+			},
+			expectedPage: &model.Page{
+				SEO:      model.SEO{PageId: 1, Title: "title"},
+				Products: []model.Product{{Id: 1, PageId: 1, Name: "name"}},
+			},
+		},
+		{
+			name:        "should return ErrInvalidPage, when page is not valid",
+			repository:  pageRepositoryAsyncMock{},
+			page:        &model.Page{SEO: model.SEO{PageId: 1}},
+			expectedErr: model.ErrInvalidPage,
+		},
+		{
+			name: "should return error, when repository fails",
+			repository: pageRepositoryAsyncMock{
+				CreatePageFunc: createWritePageFunc(model.ErrPageAlreadyExists),
+			},
+			page:        &model.Page{SEO: model.SEO{PageId: 1, Title: "title"}},
+			expectedErr: model.ErrPageAlreadyExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &PageServiceImpl{
+				Logger:              logging.Discard(),
+				PageRepositoryAsync: tt.repository,
+			}
+
+			resultPage, err := ps.CreatePage(context.Background(), tt.page)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedPage, resultPage)
+			}
+		})
+	}
+}
+
+func TestPageServiceImpl_PatchPage_shouldNotReplacePage_whenProductsOmitted(t *testing.T) {
+	replaced := false
+	ps := &PageServiceImpl{
+		Config: Configuration{Degradation: DegradationOmitProducts},
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoForPageFunc:      createGetSeoForPageFunc(&sampleModelPage.SEO, nil, 0),
+			GetProductsForPageFunc: createGetProductsForPageFunc(nil, sampleProductsError, 0),
+			ReplacePageFunc: func(page *model.Page) (<-chan error, context.CancelFunc) {
+				replaced = true
+				return createWritePageFunc(nil)(page)
+			},
+		},
+	}
+	title := "patched title"
+
+	page, err := ps.PatchPage(context.Background(), 1, model.PagePatch{SEO: &model.SEOPatch{Title: &title}})
+
+	assert.Nil(t, page)
+	assert.ErrorIs(t, err, model.ErrBackendUnavailable)
+	assert.False(t, replaced, "should not replace products of the page with none")
+}
+
+func TestPageServiceImpl_PatchPage(t *testing.T) {
+	title := "patched title"
+	tests := []struct {
+		name         string
+		repository   repository.PageRepositoryAsync
+		patch        model.PagePatch
+		expectedPage *model.Page
+		expectedErr  error
+	}{
+		{
+			name: "should replace page with patch applied",
+			repository: pageRepositoryAsyncMock{
+				GetSeoForPageFunc:      createGetSeoForPageFunc(&model.SEO{PageId: 1, Title: "title", Robots: "robots"}, nil, 0),
+				GetProductsForPageFunc: createGetProductsForPageFunc(nil, nil, 0),
+				ReplacePageFunc:        createWritePageFunc(nil),
+			},
+			patch: model.PagePatch{SEO: &model.SEOPatch{Title: &title}},
+			expectedPage: &model.Page{
+				SEO:      model.SEO{PageId: 1, Title: "patched title", Robots: "robots"},
+				Products: []model.Product{},
+			},
+		},
+		{
+			name: "should return ErrPageNotFound, when page does not exist",
+			repository: pageRepositoryAsyncMock{
+				GetSeoForPageFunc:      createGetSeoForPageFunc(nil, nil, 0),
+				GetProductsForPageFunc: createGetProductsForPageFunc(nil, nil, 0),
+			},
+			patch:       model.PagePatch{SEO: &model.SEOPatch{Title: &title}},
+			expectedErr: model.ErrPageNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &PageServiceImpl{
+				Logger:              logging.Discard(),
+				PageRepositoryAsync: tt.repository,
+			}
+
+			resultPage, err := ps.PatchPage(context.Background(), 1, tt.patch)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedPage, resultPage)
+			}
+		})
+	}
+}
+
+func TestPageServiceImpl_DeletePage(t *testing.T) {
+	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			DeletePageFunc: func(pageId int) (<-chan error, context.CancelFunc) {
+				return createWritePageFunc(model.ErrPageNotFound)(nil)
+			},
+		},
+	}
+
+	err := ps.DeletePage(context.Background(), 1)
+
+	assert.ErrorIs(t, err, model.ErrPageNotFound)
+}
+
+func createWritePageFunc(err error) func(page *model.Page) (<-chan error, context.CancelFunc) {
+	return func(page *model.Page) (<-chan error, context.CancelFunc) {
+		errChan := make(chan error, 1)
+		errChan <- err
+		return errChan, func() {}
+	}
+}
+
 func createGetSeoForPageFunc(seo *model.SEO, err error, sleepTime time.Duration) func(pageId int) (<-chan repository.ResultSEO, context.CancelFunc) {
 	return createGetSeoForPageFuncWithCancelFunc(seo, err, sleepTime, func() {})
 }
@@ -237,15 +758,103 @@ type timeStruct struct {
 	timeToCancel time.Duration
 }
 
+func createGetAssembledPageFunc(page *model.Page, err error) func(pageId int) (<-chan repository.ResultPage, context.CancelFunc) {
+	return func(pageId int) (<-chan repository.ResultPage, context.CancelFunc) {
+		pageChan := make(chan repository.ResultPage, 1)
+		pageChan <- repository.ResultPage{Page: page, Err: err}
+		return pageChan, func() {}
+	}
+}
+
+// pageAssemblerAsyncMock gets whole pages with GetPage, other methods than overridden ones are not implemented.
+type pageAssemblerAsyncMock struct {
+	pageRepositoryAsyncMock
+	GetPageFunc func(pageId int) (<-chan repository.ResultPage, context.CancelFunc)
+}
+
+func (p pageAssemblerAsyncMock) GetPage(ctx context.Context, pageId int, _ int) (<-chan repository.ResultPage, context.CancelFunc) {
+	return p.GetPageFunc(pageId)
+}
+
 type pageRepositoryAsyncMock struct {
-	GetSeoForPageFunc      func(pageId int) (<-chan repository.ResultSEO, context.CancelFunc)
-	GetProductsForPageFunc func(pageId int) (<-chan repository.ResultProducts, context.CancelFunc)
+	GetSeoForPageFunc           func(pageId int) (<-chan repository.ResultSEO, context.CancelFunc)
+	GetProductsForPageFunc      func(pageId int) (<-chan repository.ResultProducts, context.CancelFunc)
+	GetSeoFieldsForPageFunc     func(pageId int, fields []string) (<-chan repository.ResultSEO, context.CancelFunc)
+	GetProductFieldsForPageFunc func(pageId int, fields []string) (<-chan repository.ResultProducts, context.CancelFunc)
+	GetSeosForPagesFunc         func(pageIds []int) (<-chan repository.ResultSEOs, context.CancelFunc)
+	GetProductsForPagesFunc     func(pageIds []int, limit int) (<-chan repository.ResultProducts, context.CancelFunc)
+	CreatePageFunc              func(page *model.Page) (<-chan error, context.CancelFunc)
+	ReplacePageFunc             func(page *model.Page) (<-chan error, context.CancelFunc)
+	DeletePageFunc              func(pageId int) (<-chan error, context.CancelFunc)
+	GetProductForPageFunc       func(pageId int, productId int) (<-chan repository.ResultProduct, context.CancelFunc)
+	QueryProductsForPageFunc    func(pageId int, query *model.ProductQuery) (<-chan repository.ResultProducts, context.CancelFunc)
+	CountProductsForPageFunc    func(pageId int, query *model.ProductQuery) (<-chan repository.ResultCount, context.CancelFunc)
+	StreamProductsForPageFunc   func(pageId int) (<-chan repository.ResultProduct, context.CancelFunc)
+	CreateProductFunc           func(product *model.Product) (<-chan error, context.CancelFunc)
+	ReplaceProductFunc          func(product *model.Product) (<-chan error, context.CancelFunc)
+	DeleteProductFunc           func(pageId int, productId int) (<-chan error, context.CancelFunc)
 }
 
-func (p pageRepositoryAsyncMock) GetSeoForPage(pageId int) (<-chan repository.ResultSEO, context.CancelFunc) {
+func (p pageRepositoryAsyncMock) GetSeoForPage(ctx context.Context, pageId int) (<-chan repository.ResultSEO, context.CancelFunc) {
 	return p.GetSeoForPageFunc(pageId)
 }
 
-func (p pageRepositoryAsyncMock) GetProductsForPage(pageId int) (<-chan repository.ResultProducts, context.CancelFunc) {
+func (p pageRepositoryAsyncMock) GetProductsForPage(ctx context.Context, pageId int) (<-chan repository.ResultProducts, context.CancelFunc) {
 	return p.GetProductsForPageFunc(pageId)
 }
+
+func (p pageRepositoryAsyncMock) GetSeoFieldsForPage(ctx context.Context, pageId int, fields []string) (<-chan repository.ResultSEO, context.CancelFunc) {
+	return p.GetSeoFieldsForPageFunc(pageId, fields)
+}
+
+func (p pageRepositoryAsyncMock) GetProductFieldsForPage(ctx context.Context, pageId int, fields []string) (<-chan repository.ResultProducts, context.CancelFunc) {
+	return p.GetProductFieldsForPageFunc(pageId, fields)
+}
+
+func (p pageRepositoryAsyncMock) GetSeosForPages(ctx context.Context, pageIds []int) (<-chan repository.ResultSEOs, context.CancelFunc) {
+	return p.GetSeosForPagesFunc(pageIds)
+}
+
+func (p pageRepositoryAsyncMock) GetProductsForPages(ctx context.Context, pageIds []int, limit int) (<-chan repository.ResultProducts, context.CancelFunc) {
+	return p.GetProductsForPagesFunc(pageIds, limit)
+}
+
+func (p pageRepositoryAsyncMock) CreatePage(ctx context.Context, page *model.Page) (<-chan error, context.CancelFunc) {
+	return p.CreatePageFunc(page)
+}
+
+func (p pageRepositoryAsyncMock) ReplacePage(ctx context.Context, page *model.Page) (<-chan error, context.CancelFunc) {
+	return p.ReplacePageFunc(page)
+}
+
+func (p pageRepositoryAsyncMock) DeletePage(ctx context.Context, pageId int) (<-chan error, context.CancelFunc) {
+	return p.DeletePageFunc(pageId)
+}
+
+func (p pageRepositoryAsyncMock) GetProductForPage(ctx context.Context, pageId int, productId int) (<-chan repository.ResultProduct, context.CancelFunc) {
+	return p.GetProductForPageFunc(pageId, productId)
+}
+
+func (p pageRepositoryAsyncMock) QueryProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (<-chan repository.ResultProducts, context.CancelFunc) {
+	return p.QueryProductsForPageFunc(pageId, query)
+}
+
+func (p pageRepositoryAsyncMock) StreamProductsForPage(ctx context.Context, pageId int) (<-chan repository.ResultProduct, context.CancelFunc) {
+	return p.StreamProductsForPageFunc(pageId)
+}
+
+func (p pageRepositoryAsyncMock) CountProductsForPage(ctx context.Context, pageId int, query *model.ProductQuery) (<-chan repository.ResultCount, context.CancelFunc) {
+	return p.CountProductsForPageFunc(pageId, query)
+}
+
+func (p pageRepositoryAsyncMock) CreateProduct(ctx context.Context, product *model.Product) (<-chan error, context.CancelFunc) {
+	return p.CreateProductFunc(product)
+}
+
+func (p pageRepositoryAsyncMock) ReplaceProduct(ctx context.Context, product *model.Product) (<-chan error, context.CancelFunc) {
+	return p.ReplaceProductFunc(product)
+}
+
+func (p pageRepositoryAsyncMock) DeleteProduct(ctx context.Context, pageId int, productId int) (<-chan error, context.CancelFunc) {
+	return p.DeleteProductFunc(pageId, productId)
+}
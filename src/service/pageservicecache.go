@@ -0,0 +1,387 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/remikj/pages-ms/src/metrics"
+	"github.com/remikj/pages-ms/src/model"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type CacheConfiguration struct {
+	TTL         time.Duration `envconfig:"CACHE_TTL" default:"30s"`
+	NegativeTTL time.Duration `envconfig:"CACHE_NEGATIVE_TTL" default:"5s"`
+	MaxEntries  int           `envconfig:"CACHE_MAX_ENTRIES" default:"1000"`
+	// LoadTimeout bounds read of a page shared by concurrent misses, as it is not bound by deadline of any caller.
+	LoadTimeout time.Duration `envconfig:"REQUEST_TIMEOUT" default:"10s"`
+}
+
+// UncachedPageGetter is implemented by PageService, whose GetPage may return page older than the stored one.
+type UncachedPageGetter interface {
+	GetPageUncached(ctx context.Context, pageId int) (*model.Page, error)
+}
+
+type CacheStats struct {
+	Hits         uint64
+	NegativeHits uint64
+	Misses       uint64
+	Evictions    uint64
+	Entries      int
+}
+
+// PageServiceCache caches results of GetPage, including not found results, for configured TTL.
+// At most MaxEntries pages are kept, least recently used ones are evicted first.
+// Concurrent misses for the same page are collapsed into one call to the underlying PageService, which runs
+// until the last of its callers goes away, within LoadTimeout.
+// All other methods are delegated, write methods invalidate cached page.
+// GetPageFields is not cached, as pages with selected fields are read with different queries.
+// Partial pages are not cached either, so products are read again by the next call.
+type PageServiceCache struct {
+	PageService
+	config *CacheConfiguration
+	now    func() time.Time
+
+	mutex         sync.Mutex
+	entries       map[int]*list.Element
+	lru           *list.List
+	invalidations uint64
+	flights       map[int]*pageFlight
+
+	hits         uint64
+	negativeHits uint64
+	misses       uint64
+	evictions    uint64
+}
+
+type cacheEntry struct {
+	pageId    int
+	page      *model.Page
+	expiresAt time.Time
+}
+
+// pageFlight is a read of a page shared by concurrent misses. page and err are set before done is closed.
+// It is cancelled once all of its waiters are gone.
+type pageFlight struct {
+	done    chan struct{}
+	page    *model.Page
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// NewPageServiceCacheFromEnv wraps pageService with cache, or returns pageService when CACHE_TTL is 0.
+func NewPageServiceCacheFromEnv(pageService PageService) (PageService, error) {
+	config, err := CacheConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if config.TTL <= 0 || config.MaxEntries <= 0 {
+		return pageService, nil
+	}
+	cache := NewPageServiceCache(config, pageService)
+	cache.registerMetrics()
+	return cache, nil
+}
+
+func CacheConfigurationFromEnv() (*CacheConfiguration, error) {
+	config := &CacheConfiguration{}
+	err := envconfig.Process("", config)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func NewPageServiceCache(config *CacheConfiguration, pageService PageService) *PageServiceCache {
+	return &PageServiceCache{
+		PageService: pageService,
+		config:      config,
+		now:         time.Now,
+		entries:     make(map[int]*list.Element),
+		lru:         list.New(),
+		flights:     make(map[int]*pageFlight),
+	}
+}
+
+func (c *PageServiceCache) GetPage(ctx context.Context, pageId int) (*model.Page, error) {
+	if page, found := c.get(pageId); found {
+		return clonePage(page), nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	flight := c.joinFlight(ctx, pageId)
+	defer c.leaveFlight(pageId, flight)
+	select {
+	case <-flight.done:
+		if flight.err != nil {
+			return nil, flight.err
+		}
+		return clonePage(flight.page), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// joinFlight returns read of the page shared with other callers, starting it when there is none.
+// The read keeps values of ctx, but not its cancellation or deadline, as other callers may wait longer.
+func (c *PageServiceCache) joinFlight(ctx context.Context, pageId int) *pageFlight {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	flight, ok := c.flights[pageId]
+	if !ok {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		if c.config.LoadTimeout > 0 {
+			flightCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), c.config.LoadTimeout)
+		}
+		flight = &pageFlight{done: make(chan struct{}), cancel: cancel}
+		c.flights[pageId] = flight
+		go c.loadPage(flightCtx, pageId, flight, c.invalidations)
+	}
+	flight.waiters++
+	return flight
+}
+
+// leaveFlight cancels the read once its last waiter is gone. A cancelled read is not joined by later callers.
+func (c *PageServiceCache) leaveFlight(pageId int, flight *pageFlight) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	flight.waiters--
+	if flight.waiters > 0 {
+		return
+	}
+	flight.cancel()
+	if c.flights[pageId] == flight {
+		delete(c.flights, pageId)
+	}
+}
+
+func (c *PageServiceCache) loadPage(ctx context.Context, pageId int, flight *pageFlight, invalidations uint64) {
+	defer close(flight.done)
+	page, err := c.PageService.GetPage(ctx, pageId)
+	if err == nil && (page == nil || !page.Partial) {
+		c.put(pageId, page, invalidations)
+	}
+	flight.page, flight.err = page, err
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.flights[pageId] == flight {
+		delete(c.flights, pageId)
+	}
+}
+
+// GetPageUncached gets page from the underlying PageService, bypassing cached page, which could be older
+// than writes made by other instances. The page read replaces the cached one.
+func (c *PageServiceCache) GetPageUncached(ctx context.Context, pageId int) (*model.Page, error) {
+	invalidations := c.currentInvalidations()
+	page, err := c.PageService.GetPage(ctx, pageId)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil || !page.Partial {
+		c.put(pageId, page, invalidations)
+	}
+	return clonePage(page), nil
+}
+
+// GetPages returns cached pages and gets the rest with a single call to the underlying PageService.
+func (c *PageServiceCache) GetPages(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+	pages := make(map[int]*model.Page, len(pageIds))
+	var missingPageIds []int
+	for _, pageId := range pageIds {
+		page, found := c.get(pageId)
+		if !found {
+			missingPageIds = append(missingPageIds, pageId)
+			continue
+		}
+		if page != nil {
+			pages[pageId] = clonePage(page)
+		}
+	}
+	if len(missingPageIds) == 0 {
+		return pages, nil
+	}
+	atomic.AddUint64(&c.misses, uint64(len(missingPageIds)))
+
+	invalidations := c.currentInvalidations()
+	missingPages, err := c.PageService.GetPages(ctx, missingPageIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, pageId := range missingPageIds {
+		page := missingPages[pageId]
+		c.put(pageId, page, invalidations)
+		if page != nil {
+			pages[pageId] = clonePage(page)
+		}
+	}
+	return pages, nil
+}
+
+func (c *PageServiceCache) GetProducts(ctx context.Context, pageId int) (*model.ProductList, error) {
+	page, err := c.GetPage(ctx, pageId)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		return nil, model.ErrPageNotFound
+	}
+	if page.Partial {
+		return nil, fmt.Errorf("%w: products of the page could not be read", model.ErrBackendUnavailable)
+	}
+	return &model.ProductList{Products: page.Products, Next: page.ProductsNext}, nil
+}
+
+func (c *PageServiceCache) CreatePage(ctx context.Context, page *model.Page) (*model.Page, error) {
+	defer c.Invalidate(page.SEO.PageId)
+	return c.PageService.CreatePage(ctx, page)
+}
+
+func (c *PageServiceCache) ReplacePage(ctx context.Context, page *model.Page) (*model.Page, error) {
+	defer c.Invalidate(page.SEO.PageId)
+	return c.PageService.ReplacePage(ctx, page)
+}
+
+func (c *PageServiceCache) PatchPage(ctx context.Context, pageId int, patch model.PagePatch) (*model.Page, error) {
+	defer c.Invalidate(pageId)
+	return c.PageService.PatchPage(ctx, pageId, patch)
+}
+
+func (c *PageServiceCache) DeletePage(ctx context.Context, pageId int) error {
+	defer c.Invalidate(pageId)
+	return c.PageService.DeletePage(ctx, pageId)
+}
+
+func (c *PageServiceCache) CreateProduct(ctx context.Context, pageId int, product *model.Product) (*model.Product, error) {
+	defer c.Invalidate(pageId)
+	return c.PageService.CreateProduct(ctx, pageId, product)
+}
+
+func (c *PageServiceCache) ReplaceProduct(ctx context.Context, pageId int, product *model.Product) (*model.Product, error) {
+	defer c.Invalidate(pageId)
+	return c.PageService.ReplaceProduct(ctx, pageId, product)
+}
+
+func (c *PageServiceCache) PatchProduct(ctx context.Context, pageId int, productId int, patch model.ProductPatch) (*model.Product, error) {
+	defer c.Invalidate(pageId)
+	return c.PageService.PatchProduct(ctx, pageId, productId, patch)
+}
+
+func (c *PageServiceCache) DeleteProduct(ctx context.Context, pageId int, productId int) error {
+	defer c.Invalidate(pageId)
+	return c.PageService.DeleteProduct(ctx, pageId, productId)
+}
+
+// Invalidate removes page from cache. Results of GetPage calls that were in flight
+// during invalidation are not cached, as they could be read before the write.
+func (c *PageServiceCache) Invalidate(pageId int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidations++
+	if element, ok := c.entries[pageId]; ok {
+		c.removeElement(element)
+	}
+	delete(c.flights, pageId)
+}
+
+func (c *PageServiceCache) Stats() CacheStats {
+	c.mutex.Lock()
+	entries := c.lru.Len()
+	c.mutex.Unlock()
+	return CacheStats{
+		Hits:         atomic.LoadUint64(&c.hits),
+		NegativeHits: atomic.LoadUint64(&c.negativeHits),
+		Misses:       atomic.LoadUint64(&c.misses),
+		Evictions:    atomic.LoadUint64(&c.evictions),
+		Entries:      entries,
+	}
+}
+
+func (c *PageServiceCache) registerMetrics() {
+	metrics.NewCounterFunc("page_cache_hits_total", "Total number of found pages returned from cache.", func() float64 {
+		return float64(c.Stats().Hits)
+	})
+	metrics.NewCounterFunc("page_cache_negative_hits_total", "Total number of not found pages returned from cache.", func() float64 {
+		return float64(c.Stats().NegativeHits)
+	})
+	metrics.NewCounterFunc("page_cache_misses_total", "Total number of pages not found in cache.", func() float64 {
+		return float64(c.Stats().Misses)
+	})
+	metrics.NewCounterFunc("page_cache_evictions_total", "Total number of pages evicted from cache.", func() float64 {
+		return float64(c.Stats().Evictions)
+	})
+	metrics.NewGaugeFunc("page_cache_entries", "Number of pages in cache.", func() float64 {
+		return float64(c.Stats().Entries)
+	})
+}
+
+func (c *PageServiceCache) get(pageId int) (*model.Page, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[pageId]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.removeElement(element)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	if entry.page == nil {
+		atomic.AddUint64(&c.negativeHits, 1)
+	} else {
+		atomic.AddUint64(&c.hits, 1)
+	}
+	return entry.page, true
+}
+
+func (c *PageServiceCache) put(pageId int, page *model.Page, invalidations uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.invalidations != invalidations {
+		return
+	}
+	ttl := c.config.TTL
+	if page == nil {
+		ttl = c.config.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	entry := &cacheEntry{pageId: pageId, page: page, expiresAt: c.now().Add(ttl)}
+	if element, ok := c.entries[pageId]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[pageId] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.config.MaxEntries {
+		c.removeElement(c.lru.Back())
+		atomic.AddUint64(&c.evictions, 1)
+	}
+}
+
+func (c *PageServiceCache) currentInvalidations() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.invalidations
+}
+
+func (c *PageServiceCache) removeElement(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).pageId)
+}
+
+// clonePage copies page, so callers can not modify cached value.
+func clonePage(page *model.Page) *model.Page {
+	if page == nil {
+		return nil
+	}
+	clone := *page
+	clone.Products = append([]model.Product{}, page.Products...)
+	return &clone
+}
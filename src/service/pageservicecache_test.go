@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	sampleCacheConfiguration = &CacheConfiguration{
+		TTL:         time.Minute,
+		NegativeTTL: time.Second,
+		MaxEntries:  2,
+	}
+)
+
+func TestPageServiceCache_GetPage_shouldCallPageServiceOnce_whenPageCached(t *testing.T) {
+	pageService := &countingPageService{page: &sampleModelPage}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+
+	firstPage, firstErr := cache.GetPage(context.Background(), 0)
+	secondPage, secondErr := cache.GetPage(context.Background(), 0)
+
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, &sampleModelPage, firstPage)
+	assert.Equal(t, &sampleModelPage, secondPage)
+	assert.Equal(t, int32(1), pageService.getPageCalls)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Entries: 1}, cache.Stats())
+}
+
+func TestPageServiceCache_GetPage_shouldExpireEntries(t *testing.T) {
+	tests := []struct {
+		name          string
+		page          *model.Page
+		elapsed       time.Duration
+		expectedCalls int32
+	}{
+		{
+			name:          "should return cached page, when TTL not elapsed",
+			page:          &sampleModelPage,
+			elapsed:       59 * time.Second,
+			expectedCalls: 1,
+		},
+		{
+			name:          "should get page again, when TTL elapsed",
+			page:          &sampleModelPage,
+			elapsed:       time.Minute,
+			expectedCalls: 2,
+		},
+		{
+			name:          "should get page again, when negative TTL elapsed for not found page",
+			page:          nil,
+			elapsed:       time.Second,
+			expectedCalls: 2,
+		},
+		{
+			name:          "should return cached not found page, when negative TTL not elapsed",
+			page:          nil,
+			elapsed:       999 * time.Millisecond,
+			expectedCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			pageService := &countingPageService{page: tt.page}
+			cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+			cache.now = func() time.Time { return now }
+
+			_, _ = cache.GetPage(context.Background(), 0)
+			now = now.Add(tt.elapsed)
+			page, err := cache.GetPage(context.Background(), 0)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.page, page)
+			assert.Equal(t, tt.expectedCalls, pageService.getPageCalls)
+		})
+	}
+}
+
+func TestPageServiceCache_GetPage_shouldEvictLeastRecentlyUsedPage_whenMaxEntriesExceeded(t *testing.T) {
+	pageService := &countingPageService{page: &sampleModelPage}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+
+	_, _ = cache.GetPage(context.Background(), 1)
+	_, _ = cache.GetPage(context.Background(), 2)
+	_, _ = cache.GetPage(context.Background(), 1)
+	_, _ = cache.GetPage(context.Background(), 3)
+	_, _ = cache.GetPage(context.Background(), 1)
+	_, _ = cache.GetPage(context.Background(), 2)
+
+	assert.Equal(t, int32(4), pageService.getPageCalls)
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 4, Evictions: 2, Entries: 2}, cache.Stats())
+}
+
+func TestPageServiceCache_GetPage_shouldNotCacheErrors(t *testing.T) {
+	pageService := &countingPageService{err: fmt.Errorf("page service error")}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+
+	_, firstErr := cache.GetPage(context.Background(), 0)
+	_, secondErr := cache.GetPage(context.Background(), 0)
+
+	assert.Error(t, firstErr)
+	assert.Error(t, secondErr)
+	assert.Equal(t, int32(2), pageService.getPageCalls)
+}
+
+func TestPageServiceCache_GetPage_shouldNotCachePartialPages(t *testing.T) {
+	pageService := &countingPageService{page: &model.Page{SEO: sampleModelPage.SEO, Products: []model.Product{}, Partial: true}}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+
+	firstPage, _ := cache.GetPage(context.Background(), 0)
+	_, _ = cache.GetPage(context.Background(), 0)
+	_, productsErr := cache.GetProducts(context.Background(), 0)
+
+	assert.True(t, firstPage.Partial)
+	assert.ErrorIs(t, productsErr, model.ErrBackendUnavailable)
+	assert.Equal(t, int32(3), pageService.getPageCalls)
+}
+
+func TestPageServiceCache_GetPage_shouldCollapseConcurrentMisses(t *testing.T) {
+	pageService := &countingPageService{page: &sampleModelPage, sleepTime: sleepTime50ms}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+
+	waitGroup := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			page, err := cache.GetPage(context.Background(), 0)
+			assert.NoError(t, err)
+			assert.Equal(t, &sampleModelPage, page)
+		}()
+	}
+	waitGroup.Wait()
+
+	assert.Equal(t, int32(1), pageService.getPageCalls)
+}
+
+func TestPageServiceCache_GetPage_shouldNotFailOtherCallers_whenFirstCallerCancelled(t *testing.T) {
+	pageService := &countingPageService{page: &sampleModelPage, sleepTime: sleepTime50ms}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	firstErrChan := make(chan error, 1)
+	go func() {
+		_, err := cache.GetPage(ctx, 0)
+		firstErrChan <- err
+	}()
+	assert.Eventually(t, func() bool { return flightWaiters(cache, 0) == 1 }, time.Second, time.Millisecond)
+	secondPageChan := make(chan *model.Page, 1)
+	go func() {
+		page, _ := cache.GetPage(context.Background(), 0)
+		secondPageChan <- page
+	}()
+	assert.Eventually(t, func() bool { return flightWaiters(cache, 0) == 2 }, time.Second, time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-firstErrChan, context.Canceled)
+	assert.Equal(t, &sampleModelPage, <-secondPageChan)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pageService.getPageCalls))
+}
+
+func TestPageServiceCache_GetPage_shouldNotFailOtherCallers_whenFirstCallerDeadlineExceeded(t *testing.T) {
+	pageService := &countingPageService{page: &sampleModelPage, sleepTime: sleepTime50ms}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	firstErrChan := make(chan error, 1)
+	go func() {
+		_, err := cache.GetPage(ctx, 0)
+		firstErrChan <- err
+	}()
+	assert.Eventually(t, func() bool { return flightWaiters(cache, 0) == 1 }, time.Second, time.Millisecond)
+	secondPage, secondErr := cache.GetPage(context.Background(), 0)
+
+	assert.ErrorIs(t, <-firstErrChan, context.DeadlineExceeded)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, &sampleModelPage, secondPage)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pageService.getPageCalls))
+}
+
+func TestPageServiceCache_GetPage_shouldCancelSharedRead_whenAllCallersGone(t *testing.T) {
+	pageService := &blockingPageService{started: make(chan struct{}), cancelled: make(chan struct{})}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := cache.GetPage(ctx, 0)
+		errChan <- err
+	}()
+	<-pageService.started
+	cancel()
+
+	assert.ErrorIs(t, <-errChan, context.Canceled)
+	select {
+	case <-pageService.cancelled:
+	case <-time.After(time.Second):
+		assert.Fail(t, "shared read was not cancelled")
+	}
+	assert.Equal(t, 0, flightWaiters(cache, 0))
+}
+
+func TestPageServiceCache_GetPage_shouldBoundSharedRead_withLoadTimeout(t *testing.T) {
+	pageService := &blockingPageService{started: make(chan struct{}), cancelled: make(chan struct{})}
+	cache := NewPageServiceCache(&CacheConfiguration{TTL: time.Minute, MaxEntries: 1, LoadTimeout: 10 * time.Millisecond}, pageService)
+
+	_, err := cache.GetPage(context.Background(), 0)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPageServiceCache_GetPages_shouldGetOnlyPagesMissingInCache(t *testing.T) {
+	pageService := &countingPageService{page: &sampleModelPage}
+	cache := NewPageServiceCache(&CacheConfiguration{TTL: time.Minute, NegativeTTL: time.Minute, MaxEntries: 3}, pageService)
+
+	_, _ = cache.GetPage(context.Background(), 1)
+	firstPages, firstErr := cache.GetPages(context.Background(), []int{0, 1, 2})
+	secondPages, secondErr := cache.GetPages(context.Background(), []int{0, 1, 2})
+
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	expectedPages := map[int]*model.Page{1: &sampleModelPage, 2: &sampleModelPage}
+	assert.Equal(t, expectedPages, firstPages)
+	assert.Equal(t, expectedPages, secondPages)
+	assert.Equal(t, [][]int{{0, 2}}, pageService.requestedPageIds)
+	assert.Equal(t, CacheStats{Hits: 3, NegativeHits: 1, Misses: 3, Entries: 3}, cache.Stats())
+}
+
+func TestPageServiceCache_shouldInvalidatePage_whenPageWritten(t *testing.T) {
+	pageService := &countingPageService{page: &sampleModelPage}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+
+	_, _ = cache.GetPage(context.Background(), 0)
+	require.NoError(t, cache.DeleteProduct(context.Background(), 0, 1))
+	_, _ = cache.GetPage(context.Background(), 0)
+
+	assert.Equal(t, int32(2), pageService.getPageCalls)
+	assert.Equal(t, CacheStats{Misses: 2, Entries: 1}, cache.Stats())
+}
+
+func TestPageServiceCache_GetPageUncached_shouldReadPageAndRefreshCache(t *testing.T) {
+	pageService := &countingPageService{page: &sampleModelPage}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+	_, _ = cache.GetPage(context.Background(), 0)
+	changedPage := model.Page{SEO: model.SEO{Title: "changed"}, Products: []model.Product{}}
+	pageService.page = &changedPage
+
+	uncachedPage, err := cache.GetPageUncached(context.Background(), 0)
+	cachedPage, _ := cache.GetPage(context.Background(), 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &changedPage, uncachedPage)
+	assert.Equal(t, &changedPage, cachedPage)
+	assert.Equal(t, int32(2), pageService.getPageCalls)
+}
+
+func TestPageServiceCache_GetPage_shouldReturnCopyOfCachedPage(t *testing.T) {
+	pageService := &countingPageService{page: &model.Page{Products: []model.Product{{Id: 1}}}}
+	cache := NewPageServiceCache(sampleCacheConfiguration, pageService)
+
+	firstPage, _ := cache.GetPage(context.Background(), 0)
+	firstPage.Products[0].Id = 2
+	secondPage, _ := cache.GetPage(context.Background(), 0)
+
+	assert.Equal(t, 1, secondPage.Products[0].Id)
+}
+
+func TestNewPageServiceCacheFromEnv_shouldReturnPageService_whenCacheDisabled(t *testing.T) {
+	t.Setenv("CACHE_TTL", "0s")
+	pageService := &countingPageService{}
+
+	result, err := NewPageServiceCacheFromEnv(pageService)
+
+	assert.NoError(t, err)
+	assert.Same(t, pageService, result)
+}
+
+// countingPageService counts GetPage calls, other methods than overridden ones are not implemented.
+type countingPageService struct {
+	PageService
+	page         *model.Page
+	err          error
+	sleepTime    time.Duration
+	getPageCalls int32
+
+	requestedPageIds [][]int
+}
+
+func (c *countingPageService) GetPage(ctx context.Context, _ int) (*model.Page, error) {
+	atomic.AddInt32(&c.getPageCalls, 1)
+	select {
+	case <-time.After(c.sleepTime):
+		return c.page, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GetPages returns page for ids other than 0, when page is set.
+func (c *countingPageService) GetPages(ctx context.Context, pageIds []int) (map[int]*model.Page, error) {
+	c.requestedPageIds = append(c.requestedPageIds, pageIds)
+	pages := map[int]*model.Page{}
+	for _, pageId := range pageIds {
+		if pageId != 0 && c.page != nil {
+			pages[pageId] = c.page
+		}
+	}
+	return pages, c.err
+}
+
+func (c *countingPageService) DeleteProduct(ctx context.Context, _ int, _ int) error {
+	return nil
+}
+
+// flightWaiters returns number of callers waiting for shared read of the page.
+func flightWaiters(cache *PageServiceCache, pageId int) int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if flight, ok := cache.flights[pageId]; ok {
+		return flight.waiters
+	}
+	return 0
+}
+
+// blockingPageService blocks GetPage until its context is done, closing started and cancelled channels.
+type blockingPageService struct {
+	PageService
+	started   chan struct{}
+	cancelled chan struct{}
+}
+
+func (s *blockingPageService) GetPage(ctx context.Context, pageId int) (*model.Page, error) {
+	close(s.started)
+	<-ctx.Done()
+	close(s.cancelled)
+	return nil, ctx.Err()
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/repository"
+)
+
+func (ps *PageServiceImpl) GetProducts(ctx context.Context, pageId int) (*model.ProductList, error) {
+	page, err := ps.GetPage(ctx, pageId)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		return nil, model.ErrPageNotFound
+	}
+	if page.Partial {
+		return nil, fmt.Errorf("%w: products of the page could not be read", model.ErrBackendUnavailable)
+	}
+	return &model.ProductList{Products: page.Products, Next: page.ProductsNext}, nil
+}
+
+// QueryProducts returns products matching the query, with cursor of the next page when there are more of them.
+// Seo of the page is read at the same time, to tell missing page from page without matching products.
+// Total number of matching products is counted with a separate query, only when count is set.
+func (ps *PageServiceImpl) QueryProducts(ctx context.Context, pageId int, query *model.ProductQuery, count bool) (*model.ProductList, error) {
+	ps.Logger.Debug("querying products", "page_id", pageId, "sort", query.Sort, "limit", query.Limit)
+	if query.Limit < 1 {
+		return nil, fmt.Errorf("%w: limit must be at least 1", model.ErrInvalidProductQuery)
+	}
+	seoChan, getSeoCancelFunc := ps.PageRepositoryAsync.GetSeoFieldsForPage(ctx, pageId, []string{})
+	defer getSeoCancelFunc()
+	// One more product is queried to find out whether there is the next page.
+	limitedQuery := *query
+	limitedQuery.Limit++
+	productsChan, queryProductsCancelFunc := ps.PageRepositoryAsync.QueryProductsForPage(ctx, pageId, &limitedQuery)
+	defer queryProductsCancelFunc()
+	var countChan <-chan repository.ResultCount
+	if count {
+		var countProductsCancelFunc context.CancelFunc
+		countChan, countProductsCancelFunc = ps.PageRepositoryAsync.CountProductsForPage(ctx, pageId, query)
+		defer countProductsCancelFunc()
+	}
+
+	list := &model.ProductList{Products: []model.Product{}}
+	seoReceived, productsReceived, countReceived := false, false, countChan == nil
+	for !seoReceived || !productsReceived || !countReceived {
+		select {
+		case seoResult := <-seoChan:
+			if seoResult.Err != nil {
+				return nil, seoResult.Err
+			}
+			if seoResult.SEO == nil {
+				return nil, model.ErrPageNotFound
+			}
+			seoReceived = true
+		case productsResult := <-productsChan:
+			if productsResult.Err != nil {
+				return nil, productsResult.Err
+			}
+			if productsResult.Products != nil {
+				list.Products = productsResult.Products
+			}
+			productsReceived = true
+		case countResult := <-countChan:
+			if countResult.Err != nil {
+				return nil, countResult.Err
+			}
+			list.Total = &countResult.Count
+			countReceived = true
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if len(list.Products) > query.Limit {
+		list.Products = list.Products[:query.Limit]
+		list.Next = model.CursorAfter(query.Sort, &list.Products[query.Limit-1])
+	}
+	return list, nil
+}
+
+// StreamProducts reads seo of the page before opening the stream of its products, so products are passed
+// to yield only once the page is known to exist. The stream holds its connection until all products are read,
+// seo read at the same time could wait for that connection forever. Reading stops with the first error
+// returned by yield.
+func (ps *PageServiceImpl) StreamProducts(ctx context.Context, pageId int, yield func(product *model.Product) error) error {
+	ps.Logger.Debug("streaming products", "page_id", pageId)
+	seoChan, getSeoCancelFunc := ps.PageRepositoryAsync.GetSeoFieldsForPage(ctx, pageId, []string{})
+	defer getSeoCancelFunc()
+	select {
+	case seoResult := <-seoChan:
+		if seoResult.Err != nil {
+			return seoResult.Err
+		}
+		if seoResult.SEO == nil {
+			return model.ErrPageNotFound
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	productChan, streamCancelFunc := ps.PageRepositoryAsync.StreamProductsForPage(ctx, pageId)
+	defer streamCancelFunc()
+	for {
+		select {
+		case productResult, ok := <-productChan:
+			if !ok {
+				return nil
+			}
+			if productResult.Err != nil {
+				return productResult.Err
+			}
+			if err := yield(productResult.Product); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (ps *PageServiceImpl) GetProduct(ctx context.Context, pageId int, productId int) (*model.Product, error) {
+	ps.Logger.Debug("getting product", "page_id", pageId, "product_id", productId)
+	productChan, cancelFunc := ps.PageRepositoryAsync.GetProductForPage(ctx, pageId, productId)
+	defer cancelFunc()
+	var productResult repository.ResultProduct
+	select {
+	case productResult = <-productChan:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if productResult.Err != nil {
+		return nil, productResult.Err
+	}
+	if productResult.Product == nil {
+		return nil, model.ErrProductNotFound
+	}
+	return productResult.Product, nil
+}
+
+func (ps *PageServiceImpl) CreateProduct(ctx context.Context, pageId int, product *model.Product) (*model.Product, error) {
+	ps.Logger.Debug("creating product", "page_id", pageId, "product_id", product.Id)
+	if err := product.Validate(pageId); err != nil {
+		return nil, err
+	}
+	product.PageId = pageId
+	if err := awaitWrite(ps.PageRepositoryAsync.CreateProduct(ctx, product)); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (ps *PageServiceImpl) ReplaceProduct(ctx context.Context, pageId int, product *model.Product) (*model.Product, error) {
+	ps.Logger.Debug("replacing product", "page_id", pageId, "product_id", product.Id)
+	if err := product.Validate(pageId); err != nil {
+		return nil, err
+	}
+	product.PageId = pageId
+	if err := awaitWrite(ps.PageRepositoryAsync.ReplaceProduct(ctx, product)); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (ps *PageServiceImpl) PatchProduct(ctx context.Context, pageId int, productId int, patch model.ProductPatch) (*model.Product, error) {
+	product, err := ps.GetProduct(ctx, pageId, productId)
+	if err != nil {
+		return nil, err
+	}
+	product.ApplyPatch(patch)
+	return ps.ReplaceProduct(ctx, pageId, product)
+}
+
+func (ps *PageServiceImpl) DeleteProduct(ctx context.Context, pageId int, productId int) error {
+	ps.Logger.Debug("deleting product", "page_id", pageId, "product_id", productId)
+	return awaitWrite(ps.PageRepositoryAsync.DeleteProduct(ctx, pageId, productId))
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/remikj/pages-ms/src/logging"
+	"github.com/remikj/pages-ms/src/model"
+	"github.com/remikj/pages-ms/src/repository"
+	"github.com/remikj/pages-ms/src/repository/sqlimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPageServiceImpl_GetProduct(t *testing.T) {
+	tests := []struct {
+		name            string
+		result          repository.ResultProduct
+		expectedProduct *model.Product
+		expectedErr     error
+	}{
+		{
+			name:            "should return product, when product in repository",
+			result:          repository.ResultProduct{Product: &sampleModelPage.Products[0]},
+			expectedProduct: &sampleModelPage.Products[0],
+		},
+		{
+			name:        "should return ErrProductNotFound, when product not in repository",
+			result:      repository.ResultProduct{},
+			expectedErr: model.ErrProductNotFound,
+		},
+		{
+			name:        "should return error, when repository fails",
+			result:      repository.ResultProduct{Err: sampleProductsError},
+			expectedErr: sampleProductsError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &PageServiceImpl{
+				Logger: logging.Discard(),
+				PageRepositoryAsync: pageRepositoryAsyncMock{
+					GetProductForPageFunc: createGetProductForPageFunc(tt.result),
+				},
+			}
+
+			product, err := ps.GetProduct(context.Background(), 0, 0)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedProduct, product)
+		})
+	}
+}
+
+func TestPageServiceImpl_QueryProducts(t *testing.T) {
+	tests := []struct {
+		name         string
+		seo          *model.SEO
+		products     []model.Product
+		count        bool
+		expectedList *model.ProductList
+		expectedErr  error
+	}{
+		{
+			name:     "should return products with next cursor, when there are more products than limit",
+			seo:      &sampleModelPage.SEO,
+			products: sampleModelPage.Products,
+			expectedList: &model.ProductList{
+				Products: sampleModelPage.Products[:1],
+				Next:     &model.ProductCursor{Sort: model.SortByPrice, Id: 0, Price: 2.50},
+			},
+		},
+		{
+			name:         "should return products without next cursor and with total, when count requested",
+			seo:          &sampleModelPage.SEO,
+			products:     sampleModelPage.Products[1:],
+			count:        true,
+			expectedList: &model.ProductList{Products: sampleModelPage.Products[1:], Total: intPtr(7)},
+		},
+		{
+			name:         "should return empty products, when none matches",
+			seo:          &sampleModelPage.SEO,
+			expectedList: &model.ProductList{Products: []model.Product{}},
+		},
+		{
+			name:        "should return ErrPageNotFound, when seo not in repository",
+			products:    []model.Product{},
+			count:       true,
+			expectedErr: model.ErrPageNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := &model.ProductQuery{Limit: 1, Sort: model.SortByPrice}
+			var queriedLimit int
+			ps := &PageServiceImpl{
+				Logger: logging.Discard(),
+				PageRepositoryAsync: pageRepositoryAsyncMock{
+					GetSeoFieldsForPageFunc: func(pageId int, fields []string) (<-chan repository.ResultSEO, context.CancelFunc) {
+						return createGetSeoForPageFunc(tt.seo, nil, 0)(pageId)
+					},
+					QueryProductsForPageFunc: func(pageId int, q *model.ProductQuery) (<-chan repository.ResultProducts, context.CancelFunc) {
+						queriedLimit = q.Limit
+						return createGetProductsForPageFunc(tt.products, nil, 0)(pageId)
+					},
+					CountProductsForPageFunc: func(pageId int, q *model.ProductQuery) (<-chan repository.ResultCount, context.CancelFunc) {
+						countChan := make(chan repository.ResultCount, 1)
+						countChan <- repository.ResultCount{Count: 7}
+						return countChan, func() {}
+					},
+				},
+			}
+
+			list, err := ps.QueryProducts(context.Background(), 0, query, tt.count)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedList, list)
+			assert.Equal(t, 2, queriedLimit)
+			assert.Equal(t, 1, query.Limit)
+		})
+	}
+}
+
+func TestPageServiceImpl_QueryProducts_shouldReturnErr_whenLimitBelowOne(t *testing.T) {
+	ps := &PageServiceImpl{Logger: logging.Discard(), PageRepositoryAsync: pageRepositoryAsyncMock{}}
+
+	list, err := ps.QueryProducts(context.Background(), 0, &model.ProductQuery{Limit: 0, Sort: model.SortByPrice}, false)
+
+	assert.Nil(t, list)
+	assert.ErrorIs(t, err, model.ErrInvalidProductQuery)
+}
+
+func TestPageServiceImpl_StreamProducts(t *testing.T) {
+	tests := []struct {
+		name             string
+		seo              *model.SEO
+		results          []repository.ResultProduct
+		yieldErr         error
+		expectedProducts []model.Product
+		expectedErr      error
+	}{
+		{
+			name: "should yield all products, when page exists",
+			seo:  &sampleModelPage.SEO,
+			results: []repository.ResultProduct{
+				{Product: &sampleModelPage.Products[0]},
+				{Product: &sampleModelPage.Products[1]},
+			},
+			expectedProducts: sampleModelPage.Products,
+		},
+		{
+			name:        "should return ErrPageNotFound before yielding products, when seo not in repository",
+			results:     []repository.ResultProduct{{Product: &sampleModelPage.Products[0]}},
+			expectedErr: model.ErrPageNotFound,
+		},
+		{
+			name: "should return err of the stream after yielded products",
+			seo:  &sampleModelPage.SEO,
+			results: []repository.ResultProduct{
+				{Product: &sampleModelPage.Products[0]},
+				{Err: model.ErrBackendUnavailable},
+			},
+			expectedProducts: sampleModelPage.Products[:1],
+			expectedErr:      model.ErrBackendUnavailable,
+		},
+		{
+			name: "should stop, when yield fails",
+			seo:  &sampleModelPage.SEO,
+			results: []repository.ResultProduct{
+				{Product: &sampleModelPage.Products[0]},
+				{Product: &sampleModelPage.Products[1]},
+			},
+			yieldErr:         context.Canceled,
+			expectedProducts: sampleModelPage.Products[:1],
+			expectedErr:      context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &PageServiceImpl{
+				Logger: logging.Discard(),
+				PageRepositoryAsync: pageRepositoryAsyncMock{
+					GetSeoFieldsForPageFunc: func(pageId int, fields []string) (<-chan repository.ResultSEO, context.CancelFunc) {
+						return createGetSeoForPageFunc(tt.seo, nil, 0)(pageId)
+					},
+					StreamProductsForPageFunc: func(pageId int) (<-chan repository.ResultProduct, context.CancelFunc) {
+						productChan := make(chan repository.ResultProduct, len(tt.results))
+						for _, result := range tt.results {
+							productChan <- result
+						}
+						close(productChan)
+						return productChan, func() {}
+					},
+				},
+			}
+
+			var products []model.Product
+			err := ps.StreamProducts(context.Background(), 0, func(product *model.Product) error {
+				products = append(products, *product)
+				return tt.yieldErr
+			})
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedProducts, products)
+		})
+	}
+}
+
+func TestPageServiceImpl_StreamProducts_shouldStreamAllProducts_whenSQLHasSingleConnection(t *testing.T) {
+	ps := newSingleConnectionSQLPageService(t, 200)
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFunc()
+
+	streamed := 0
+	err := ps.StreamProducts(ctx, 1, func(product *model.Product) error {
+		streamed++
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, streamed)
+}
+
+func TestPageServiceImpl_StreamProducts_shouldNotBlockGetPage_whenSQLHasSingleConnection(t *testing.T) {
+	ps := newSingleConnectionSQLPageService(t, 500)
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFunc()
+
+	streamed := 0
+	err := ps.StreamProducts(ctx, 1, func(product *model.Product) error {
+		streamed++
+		if streamed != 1 {
+			return nil
+		}
+		getCtx, cancelGetCtx := context.WithTimeout(ctx, time.Second)
+		defer cancelGetCtx()
+		page, err := ps.GetPage(getCtx, 1)
+		if err == nil && len(page.Products) != 500 {
+			err = fmt.Errorf("got %v products", len(page.Products))
+		}
+		return err
+	})
+
+	assert.NoError(t, err, "should get page while the stream is open")
+	assert.Equal(t, 500, streamed)
+}
+
+// newSingleConnectionSQLPageService returns service of page 1 with given number of products,
+// stored in sql repository limited to a single connection.
+func newSingleConnectionSQLPageService(t *testing.T, productsCount int) *PageServiceImpl {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "pages.db")+"?_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, sqlimpl.Migrate(context.Background(), db, logging.Discard()))
+	pageRepository := sqlimpl.NewPageRepositorySQL(db, logging.Discard())
+	page := &model.Page{SEO: model.SEO{PageId: 1, Title: "title"}}
+	for id := 0; id < productsCount; id++ {
+		page.Products = append(page.Products, model.Product{Id: id, PageId: 1, Name: "product", Price: 1})
+	}
+	require.NoError(t, pageRepository.CreatePage(context.Background(), page))
+	return NewPageService(&Configuration{}, repository.NewPageRepositoryAsync(&repository.AsyncConfiguration{}, pageRepository), logging.Discard())
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func TestPageServiceImpl_GetProducts_shouldReturnErrPageNotFound_whenSeoNotInRepository(t *testing.T) {
+	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetSeoForPageFunc:      createGetSeoForPageFunc(nil, nil, 0),
+			GetProductsForPageFunc: createGetProductsForPageFunc(sampleModelPage.Products, nil, 0),
+		},
+	}
+
+	products, err := ps.GetProducts(context.Background(), 0)
+
+	assert.Nil(t, products)
+	assert.Equal(t, model.ErrPageNotFound, err)
+}
+
+func TestPageServiceImpl_CreateProduct(t *testing.T) {
+	tests := []struct {
+		name            string
+		product         *model.Product
+		writeErr        error
+		expectedProduct *model.Product
+		expectedErr     error
+	}{
+		{
+			name:            "should create product assigned to page",
+			product:         &model.Product{Id: 3, Name: "name"},
+			expectedProduct: &model.Product{Id: 3, PageId: 1, Name: "name"},
+		},
+		{
+			name:        "should return ErrInvalidProduct, when product belongs to other page",
+			product:     &model.Product{Id: 3, PageId: 2, Name: "name"},
+			expectedErr: model.ErrInvalidProduct,
+		},
+		{
+			name:        "should return error, when repository fails",
+			product:     &model.Product{Id: 3, Name: "name"},
+			writeErr:    model.ErrProductAlreadyExists,
+			expectedErr: model.ErrProductAlreadyExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &PageServiceImpl{
+				Logger: logging.Discard(),
+				PageRepositoryAsync: pageRepositoryAsyncMock{
+					CreateProductFunc: func(product *model.Product) (<-chan error, context.CancelFunc) {
+						return createWritePageFunc(tt.writeErr)(nil)
+					},
+				},
+			}
+
+			product, err := ps.CreateProduct(context.Background(), 1, tt.product)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedProduct, product)
+			}
+		})
+	}
+}
+
+func TestPageServiceImpl_PatchProduct(t *testing.T) {
+	price := 5.0
+	var replacedProduct *model.Product
+	ps := &PageServiceImpl{
+		Logger: logging.Discard(),
+		PageRepositoryAsync: pageRepositoryAsyncMock{
+			GetProductForPageFunc: createGetProductForPageFunc(repository.ResultProduct{
+				Product: &model.Product{Id: 3, PageId: 1, Name: "name", Price: 1.0},
+			}),
+			ReplaceProductFunc: func(product *model.Product) (<-chan error, context.CancelFunc) {
+				replacedProduct = product
+				return createWritePageFunc(nil)(nil)
+			},
+		},
+	}
+
+	product, err := ps.PatchProduct(context.Background(), 1, 3, model.ProductPatch{Price: &price})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Product{Id: 3, PageId: 1, Name: "name", Price: 5.0}, product)
+	assert.Equal(t, product, replacedProduct)
+}
+
+func createGetProductForPageFunc(result repository.ResultProduct) func(pageId int, productId int) (<-chan repository.ResultProduct, context.CancelFunc) {
+	return func(pageId int, productId int) (<-chan repository.ResultProduct, context.CancelFunc) {
+		productChan := make(chan repository.ResultProduct, 1)
+		productChan <- result
+		return productChan, func() {}
+	}
+}